@@ -1,15 +1,17 @@
 package main
 
 import (
-	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/d0ze/golang-hft/src/internal"
 	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
 	"github.com/d0ze/golang-hft/src/pkg/exchange"
 	"github.com/d0ze/golang-hft/src/pkg/goro"
+	"github.com/d0ze/golang-hft/src/pkg/history"
 	"github.com/d0ze/golang-hft/src/pkg/strategy"
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 )
 
@@ -23,9 +25,22 @@ func main() {
 	}
 
 	// init broker
-	exchange.InitClient()
+	var wg sync.WaitGroup
+	var broker exchange.IExchange
+	krakenLimits := exchange.NewKrakenLimits(exchange.IKrakenTier(internal.Config.KrakenApiTier))
+	httpTimeout := time.Duration(internal.Config.HttpTimeout) * time.Second
+	switch internal.Config.Exchange {
+	case "kraken-futures":
+		broker = exchange.NewKrakenFuturesCli(internal.Config.KrakenFuturesApiKey, internal.Config.KrakenFuturesSecret, httpTimeout)
+	case "binance":
+		broker = exchange.NewBinanceCli(internal.Config.BinanceApiKey, internal.Config.BinanceSecret, httpTimeout)
+	default:
+		broker = exchange.NewKrakenCli(internal.Config.KrakenApiKey, internal.Config.KrakenSecret, krakenLimits, httpTimeout)
+		goro.MonitorRateLimits(krakenLimits, time.Minute, nil, &wg)
+	}
+	logrus.Infof("[MAIN] selected exchange %s", internal.Config.Exchange)
 	markets := internal.Config.GetMarkets()
-	data, err := exchange.KrakenCli.GetMarketsData(markets)
+	data, err := broker.GetMarketsData(markets)
 	if err != nil {
 		logrus.Warnf("[MAIN] couldnt retrieve market data (reason: %v), exiting", err)
 		return
@@ -35,37 +50,187 @@ func main() {
 	logrus.Infof("[MAIN] retrieved markets data: %s", entities.Markets.String())
 
 	logrus.Infof("[MAIN] selected strategy %s", internal.Config.Strategy)
+	strategy.Leverage = strategy.NewLeveragePolicy(strategy.LeverageConfig{
+		Policy:           strategy.ILeverage(internal.Config.LeveragePolicy),
+		Fixed:            internal.Config.Leverage,
+		Cap:              internal.Config.GetLeverageCap(),
+		MarketCaps:       internal.Config.GetMarketLeverageCaps(),
+		TargetVolatility: internal.Config.LeverageVolatility,
+		Timeframe:        internal.Config.LeverageTimeframe,
+	})
+	logrus.Infof("[MAIN] selected leverage policy %s", internal.Config.LeveragePolicy)
 	stategy := strategies[internal.Config.Strategy]
 
-	var wg sync.WaitGroup
+	// the history is loaded from the exchange before
+	// it's wrapped by the paper exchange
+	var store history.ICandleStore
+	if internal.Config.HistoryDir != "" {
+		store = history.NewFileStore(internal.Config.HistoryDir)
+	}
+	backfill := history.NewBackfill(broker, store)
+	// sub-minute candles are built from the public trades
+	tradesData, _ := broker.(exchange.ITradesData)
+
+	tracked := entities.NewOrders()
+	// in development mode orders are routed to a simulated
+	// account, filled against the live market data
+	var paper exchange.IPaperExchange
+	if internal.Config.DevelopmentMode {
+		logrus.Infof("[MAIN] development mode, paper trading with balance %v", internal.Config.PaperBalance)
+		paper = exchange.NewPaperExchange(broker, decimal.NewFromFloat(internal.Config.PaperBalance), decimal.NewFromFloat(internal.Config.PaperFee))
+		defer paper.Close()
+		broker = paper
+		if err := goro.TrackOrders(paper, tracked, &wg); err != nil {
+			logrus.Fatalf("[MAIN] error %v subscribing to orders updates", err)
+		}
+	}
+	// websocket feeds and the dead man's switch are kraken only,
+	// other exchanges poll the candles through the rest api
+	var feed exchange.IMarketFeed
+	if internal.Config.Exchange == "kraken" {
+		feed = exchange.NewKrakenFeed(exchange.KRAKEN_WS_URL, 10*time.Second, time.Second)
+		defer feed.Close()
+	}
+	if internal.Config.Exchange == "kraken" && paper == nil {
+		ordersFeed := exchange.NewKrakenOrdersFeed(
+			exchange.KRAKEN_WS_AUTH_URL,
+			exchange.KrakenWsToken(internal.Config.KrakenApiKey, internal.Config.KrakenSecret, krakenLimits, httpTimeout),
+			10*time.Second,
+			time.Second)
+		defer ordersFeed.Close()
+
+		if internal.Config.DeadManSwitchTimeout > 0 {
+			goro.DeadManSwitch(broker, time.Duration(internal.Config.DeadManSwitchTimeout)*time.Second, nil, &wg)
+		}
+		if err := goro.TrackOrders(ordersFeed, tracked, &wg); err != nil {
+			logrus.Fatalf("[MAIN] error %v subscribing to orders updates", err)
+		}
+		goro.ReconcileOrders(
+			broker,
+			tracked,
+			time.Duration(internal.Config.OrdersReconcile)*time.Second,
+			time.Duration(internal.Config.OrdersMaxAge)*time.Second,
+			ordersFeed.Connected(),
+			nil,
+			&wg)
+	}
+	// the other exchanges have no orders feed, the
+	// orders in flight are polled until they're closed
+	if internal.Config.Exchange != "kraken" && paper == nil {
+		logrus.Infof("[MAIN] no orders feed for %s, polling the orders in flight", internal.Config.Exchange)
+		goro.ReconcileOrders(
+			broker,
+			tracked,
+			time.Duration(internal.Config.OrdersPoll)*time.Second,
+			time.Duration(internal.Config.OrdersMaxAge)*time.Second,
+			nil,
+			nil,
+			&wg)
+	}
 
 	for _, market := range markets {
 		var ticks chan entities.Candle
-		trend := entities.InitTrend(market)
-		timeframes := strings.Split(internal.Config.OHLCIntervals, "-")
-		logrus.Infof("[MAIN] selected timeframes %v", timeframes)
-		for _, tf := range timeframes {
-			// get ohlc for each timeframe
-			timeframe, _ := strconv.ParseInt(tf, 10, 16)
-			logrus.Infof("[MAIN] retrieving candles for timeframe %dm", timeframe)
-			prev, err := exchange.KrakenCli.GetOHLC(market, int(timeframe))
-			logrus.Infof("[MAIN] received  %d candles", len(prev))
+		var timeframes []int
+		for _, tf := range strings.Split(internal.Config.OHLCIntervals, "-") {
+			timeframe, err := entities.ParseTimeframe(tf)
 			if err != nil {
-				logrus.Fatalf("[MAIN] error %v retrieving latest %dm candles", timeframe, err)
+				logrus.Fatalf("[MAIN] error %v parsing OHLC_INTERVALS", err)
 			}
-			for _, candle := range prev[len(prev)-60 : len(prev)-1] {
-				logrus.Debugf("[MAIN] loading candle %s", candle.String())
-				trend.Update(candle, int(timeframe))
+			timeframes = append(timeframes, int(timeframe))
+		}
+		trend := entities.InitTrend(market, timeframes...)
+		logrus.Infof("[MAIN] selected timeframes %v", internal.Config.OHLCIntervals)
+		var base int
+		var resampled []int
+		var baseTicks chan entities.Candle
+		if internal.Config.OHLCResample {
+			base, resampled = resampledTimeframes(timeframes)
+		}
+		for _, timeframe := range timeframes {
+			var tfTicks chan entities.Candle
+			interval := entities.Timeframe(timeframe)
+			if timeframe < 0 {
+				// not provided by the exchanges, built from now on
+				if tradesData == nil {
+					logrus.Fatalf("[MAIN] %s candles need the trades history, not provided by %s", interval, internal.Config.Exchange)
+				}
+				tfTicks = goro.AggregateTrades(goro.PollTrades(tradesData, market, time.Now(), time.Second, &wg), market, trend, interval.Duration(), nil, &wg)
+			} else {
+				// get ohlc for each timeframe
+				size := strategy.WarmupSize(stategy, timeframe)
+				trend.SetSize(timeframe, size)
+				logrus.Infof("[MAIN] retrieving %d candles for timeframe %s", size, interval)
+				prev, err := backfill.GetCandles(market, timeframe, size)
+				if err != nil {
+					logrus.Fatalf("[MAIN] error %v retrieving latest %s candles", err, interval)
+				}
+				logrus.Infof("[MAIN] received  %d candles", len(prev))
+				for _, candle := range prev {
+					logrus.Debugf("[MAIN] loading candle %s", candle.String())
+					trend.Update(candle, timeframe)
+				}
+				if contains(resampled, timeframe) {
+					// no feed, built from the base candles
+					continue
+				}
+				if feed == nil || internal.Config.OHLCFeed == "rest" {
+					tfTicks = goro.PollOHLC(broker, market, trend, timeframe, &wg)
+				} else {
+					tfTicks, err = goro.StreamOHLC(feed, market, trend, timeframe, &wg)
+					if err != nil {
+						logrus.Fatalf("[MAIN] error %v subscribing to %s candles", err, interval)
+					}
+				}
 			}
-			tfTicks := goro.PollOHLC(market, trend, int(timeframe), &wg)
-			if int(timeframe) == internal.Config.StrategyIntervalCheck {
+			if len(resampled) > 0 && timeframe == base {
+				// read by the resampling, which forwards them
+				baseTicks = tfTicks
+			} else if timeframe == internal.Config.StrategyIntervalCheck {
 				// defines which candle timeframe will tick the strategy check
 				ticks = tfTicks
 			}
 		}
-		orders := goro.Check(stategy, market, trend, ticks, &wg)
-		goro.HandleOrders(orders)
+		if len(resampled) > 0 {
+			// the base candles are sent if they tick the strategy
+			resampledTicks := goro.ResampleOHLC(baseTicks, market, trend, base, resampled, internal.Config.StrategyIntervalCheck, &wg)
+			if base == internal.Config.StrategyIntervalCheck || contains(resampled, internal.Config.StrategyIntervalCheck) {
+				ticks = resampledTicks
+			}
+		}
+		goro.RepairGaps(backfill, market, trend, time.Minute, internal.Config.OHLCFillGaps, nil, &wg)
+		if paper != nil {
+			ticks = goro.SimulatePrices(paper, market, ticks, &wg)
+		}
+		orders := goro.Check(broker, tracked, stategy, market, trend, ticks, &wg)
+		goro.HandleOrders(broker, tracked, orders)
 	}
 	wg.Wait()
 	logrus.Infof("[MAIN] program exiting...")
 }
+
+// the smallest minute timeframe and the timeframes to resample from
+// it, the multiples of it dividing a day (aligned as the exchange ones)
+func resampledTimeframes(timeframes []int) (int, []int) {
+	base := 0
+	for _, timeframe := range timeframes {
+		if timeframe > 0 && (base == 0 || timeframe < base) {
+			base = timeframe
+		}
+	}
+	var resampled []int
+	for _, timeframe := range timeframes {
+		if timeframe > base && base > 0 && timeframe%base == 0 && int(entities.TIMEFRAME_1D)%timeframe == 0 {
+			resampled = append(resampled, timeframe)
+		}
+	}
+	return base, resampled
+}
+
+func contains(timeframes []int, timeframe int) bool {
+	for _, tf := range timeframes {
+		if tf == timeframe {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/Netflix/go-env"
@@ -8,15 +10,39 @@ import (
 )
 
 type config struct {
-	LogLevel              string `env:"LOG_LEVEL,default=info"`
-	DevelopmentMode       bool   `env:"DEVELOPMENT_MODE,default=false"`
-	KrakenApiKey          string `env:"KRAKEN_API_KEY,default="`
-	KrakenSecret          string `env:"KRAKEN_SECRET,default="`
-	OHLCIntervals         string `env:"OHLC_INTERVALS,default=1-60"`
-	OHLCSize              int    `env:"OHLC_SIZE,default=60"`
-	Strategy              string `env:"STRATEGY,default=twap"`
-	StrategyIntervalCheck int    `env:"STRATEGY_INTERVAL_CHECK,default=1"`
-	Markets               string `env:"MARKETS,default=XBTEUR-ETHEUR"`
+	LogLevel              string  `env:"LOG_LEVEL,default=info"`
+	DevelopmentMode       bool    `env:"DEVELOPMENT_MODE,default=false"`
+	PaperBalance          float64 `env:"PAPER_BALANCE,default=10000"`
+	PaperFee              float64 `env:"PAPER_FEE,default=0.0026"`
+	Exchange              string  `env:"EXCHANGE,default=kraken"`
+	KrakenApiKey          string  `env:"KRAKEN_API_KEY,default="`
+	KrakenSecret          string  `env:"KRAKEN_SECRET,default="`
+	KrakenApiTier         string  `env:"KRAKEN_API_TIER,default=starter"`
+	KrakenFuturesApiKey   string  `env:"KRAKEN_FUTURES_API_KEY,default="`
+	KrakenFuturesSecret   string  `env:"KRAKEN_FUTURES_SECRET,default="`
+	BinanceApiKey         string  `env:"BINANCE_API_KEY,default="`
+	BinanceSecret         string  `env:"BINANCE_SECRET,default="`
+	HttpTimeout           int     `env:"HTTP_TIMEOUT,default=10"`
+	OHLCIntervals         string  `env:"OHLC_INTERVALS,default=1-60"`
+	OHLCSize              int     `env:"OHLC_SIZE,default=60"`
+	OHLCFeed              string  `env:"OHLC_FEED,default=websocket"`
+	OHLCFillGaps          bool    `env:"OHLC_FILL_GAPS,default=false"`
+	OHLCResample          bool    `env:"OHLC_RESAMPLE,default=false"`
+	HistoryDir            string  `env:"HISTORY_DIR,default="`
+	Strategy              string  `env:"STRATEGY,default=twap"`
+	StrategyIntervalCheck int     `env:"STRATEGY_INTERVAL_CHECK,default=1"`
+	Markets               string  `env:"MARKETS,default=XBTEUR-ETHEUR"`
+	DeadManSwitchTimeout  int     `env:"DEAD_MAN_SWITCH_TIMEOUT,default=60"`
+	OrdersReconcile       int     `env:"ORDERS_RECONCILE_INTERVAL,default=60"`
+	OrdersMaxAge          int     `env:"ORDERS_MAX_AGE,default=300"`
+	OrdersPoll            int     `env:"ORDERS_POLL_INTERVAL,default=5"`
+	LeveragePolicy        string  `env:"LEVERAGE_POLICY,default=max"`
+	Leverage              int64   `env:"LEVERAGE,default=2"`
+	LeverageCap           int64   `env:"LEVERAGE_CAP,default=0"`
+	LeverageMarketCaps    string  `env:"LEVERAGE_MARKET_CAPS,default="`
+	LeverageStrategyCaps  string  `env:"LEVERAGE_STRATEGY_CAPS,default="`
+	LeverageVolatility    float64 `env:"LEVERAGE_TARGET_VOLATILITY,default=0.01"`
+	LeverageTimeframe     int     `env:"LEVERAGE_VOLATILITY_TIMEFRAME,default=60"`
 }
 
 func (c *config) Parse() {
@@ -41,23 +67,70 @@ func (c *config) GetMarkets() []Market {
 	return markets
 }
 
+// leverage caps of each market, e.g. SOLEUR:2-DOTEUR:1
+func (c *config) GetMarketLeverageCaps() map[Market]int64 {
+	caps := map[Market]int64{}
+	for name, limit := range parseLeverageCaps(c.LeverageMarketCaps) {
+		caps[IMarket(name)] = limit
+	}
+	return caps
+}
+
+// leverage cap of the selected strategy, the lowest between
+// LEVERAGE_CAP and the strategy cap (0 if not capped)
+func (c *config) GetLeverageCap() int64 {
+	limit := c.LeverageCap
+	if strategyCap, ok := parseLeverageCaps(c.LeverageStrategyCaps)[c.Strategy]; ok && (limit == 0 || strategyCap < limit) {
+		limit = strategyCap
+	}
+	return limit
+}
+
+// parses a dash separated list of name:cap
+func parseLeverageCaps(list string) map[string]int64 {
+	caps := map[string]int64{}
+	if list == "" {
+		return caps
+	}
+	for _, entry := range strings.Split(list, "-") {
+		name, value, ok := strings.Cut(entry, ":")
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if !ok || err != nil || limit < 1 {
+			panic("invalid leverage cap")
+		}
+		caps[name] = limit
+	}
+	return caps
+}
+
+// markets are named after the kraken altname of the pair (e.g. XBTEUR, SOLEUR)
+// or the futures contract, their existence is checked against the exchange
+// when the markets data is retrieved
 func IMarket(market string) Market {
-	switch market {
-	case "XBTEUR":
-		return XBTEUR
-	case "XBTUSD":
-		return XBTUSD
-	case "XBTUSDT":
-		return XBTUSDT
-	case "ETHEUR":
-		return ETHEUR
-	case "ETHUSD":
-		return ETHUSD
-	case "LTCEUR":
-		return LTCEUR
-	default:
+	if !marketName.MatchString(market) {
 		panic("unknown market")
 	}
+	return Market(market)
+}
+
+var marketName = regexp.MustCompile(`^[A-Z0-9]{5,}$`)
+
+// futures markets end either with PERP or with
+// the maturity date of the contract (YYMMDD)
+func (m Market) IsFuture() bool {
+	market := string(m)
+	if strings.HasSuffix(market, "PERP") {
+		return true
+	}
+	if len(market) <= 6 {
+		return false
+	}
+	for _, c := range market[len(market)-6:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 var Config *config
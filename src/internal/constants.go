@@ -9,6 +9,7 @@ type PositionSide string
 type Currency string
 type Market string
 type Strategy string
+type MarketStatus string
 
 const (
 	CREATED   OrderStatus = "created"
@@ -48,6 +49,17 @@ const (
 	SHORT PositionStatus = "short"
 )
 
+// trading status of a market, the orders it doesn't
+// accept are refused before being placed
+const (
+	MARKET_ONLINE      MarketStatus = "online"
+	MARKET_CANCEL_ONLY MarketStatus = "cancel_only"
+	MARKET_POST_ONLY   MarketStatus = "post_only"
+	MARKET_LIMIT_ONLY  MarketStatus = "limit_only"
+	MARKET_REDUCE_ONLY MarketStatus = "reduce_only"
+	MARKET_OFFLINE     MarketStatus = "offline"
+)
+
 const (
 	XBT  Currency = "XBT"
 	EUR  Currency = "EUR"
@@ -63,4 +75,8 @@ const (
 	ETHEUR  Market = "ETHEUR"
 	ETHUSD  Market = "ETHUSD"
 	LTCEUR  Market = "LTCEUR"
+	// perpetual futures contracts, fixed maturity contracts
+	// are named after the maturity date (e.g. XBTUSD241227)
+	XBTUSDPERP Market = "XBTUSDPERP"
+	ETHUSDPERP Market = "ETHUSDPERP"
 )
@@ -0,0 +1,106 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// builds the candles of an interval from a stream of trades. as in the
+// exchange ohlc data, intervals without trades are filled with flat
+// candles at the previous close, with no volume
+type CandleAggregator struct {
+	interval time.Duration
+	current  *Candle
+	// price * volume of the trades of the current candle
+	cost decimal.Decimal
+	// last closed candle, the flat candles are built from it
+	last *Candle
+}
+
+func NewCandleAggregator(interval time.Duration) *CandleAggregator {
+	return &CandleAggregator{interval: interval}
+}
+
+// adds the trade to the candle in progress, returning the candles
+// closed by it. trades older than the candle in progress are ignored
+func (a *CandleAggregator) Add(trade Trade) []Candle {
+	start := trade.Timestamp.Truncate(a.interval)
+	if (a.current != nil && start.Before(a.current.Timestamp)) || (a.current == nil && a.last != nil && !start.After(a.last.Timestamp)) {
+		return nil
+	}
+	var closed []Candle
+	if a.current != nil && start.After(a.current.Timestamp) {
+		closed = a.Flush(start)
+	} else if a.current == nil && a.last != nil {
+		closed = a.fill(start)
+	}
+	if a.current == nil {
+		candle := NewCandle(trade.Price, trade.Price, trade.Price, trade.Price, start)
+		a.current = &candle
+		a.cost = decimal.Zero
+	}
+	c := a.current
+	c.High = decimal.Max(c.High, trade.Price)
+	c.Low = decimal.Min(c.Low, trade.Price)
+	c.Close = trade.Price
+	c.Volume = c.Volume.Add(trade.Volume)
+	c.Count++
+	a.cost = a.cost.Add(trade.Price.Mul(trade.Volume))
+	if c.Volume.IsPositive() {
+		c.Vwap = a.cost.Div(c.Volume)
+	}
+	return closed
+}
+
+// closes the candle in progress and the empty intervals
+// ended before the given time, returning them in order
+func (a *CandleAggregator) Flush(now time.Time) []Candle {
+	var closed []Candle
+	if a.current != nil {
+		if a.current.Timestamp.Add(a.interval).After(now) {
+			return nil
+		}
+		closed = append(closed, *a.current)
+		a.last = a.current
+		a.current = nil
+	}
+	if a.last == nil {
+		return closed
+	}
+	return append(closed, a.fill(now.Truncate(a.interval))...)
+}
+
+// flat candles from the last closed one up to the given start, excluded
+func (a *CandleAggregator) fill(until time.Time) []Candle {
+	var closed []Candle
+	for start := a.last.Timestamp.Add(a.interval); start.Before(until); start = start.Add(a.interval) {
+		flat := NewCandle(a.last.Close, a.last.Close, a.last.Close, a.last.Close, start)
+		closed = append(closed, flat)
+		a.last = &flat
+	}
+	return closed
+}
+
+// returns the candle in progress, false if no trade was received
+// since the last candle was closed
+func (a *CandleAggregator) Current() (Candle, bool) {
+	if a.current == nil {
+		return Candle{}, false
+	}
+	return *a.current, true
+}
+
+// builds the candles of recorded trades, sorted by time. the
+// candle of the last trade is returned even if not closed
+func AggregateTrades(trades []Trade, interval time.Duration) []Candle {
+	aggregator := NewCandleAggregator(interval)
+	var candles []Candle
+	for _, trade := range trades {
+		candles = append(candles, aggregator.Add(trade)...)
+	}
+	if current, ok := aggregator.Current(); ok {
+		candles = append(candles, current)
+	}
+	return candles
+}
@@ -1,6 +1,9 @@
 package entities
 
-import "github.com/shopspring/decimal"
+import (
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/shopspring/decimal"
+)
 
 type Balance struct {
 	TradeBalance  decimal.Decimal // combined balance of all equity currencies
@@ -8,4 +11,6 @@ type Balance struct {
 	FreeMargin    decimal.Decimal // available margin for new operations (equity - margin)
 	Equity        decimal.Decimal // trade balance + unrealized P&L
 	MarginLevel   decimal.Decimal // margin level of the account (equity / margin) * 100
+	// available amount of each currency, reported by spot exchanges
+	Currencies map[internal.Currency]decimal.Decimal
 }
@@ -0,0 +1,175 @@
+package entities
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/shopspring/decimal"
+)
+
+// price level of an order book
+type BookLevel struct {
+	Price     decimal.Decimal
+	Volume    decimal.Decimal
+	Timestamp time.Time
+}
+
+// level-2 order book of a market. bids are sorted from the highest
+// and asks from the lowest price, so the best level comes first
+type OrderBook struct {
+	Market internal.Market
+	Bids   []BookLevel
+	Asks   []BookLevel
+	// levels kept on each side, 0 if not limited
+	Depth     int
+	Timestamp time.Time
+}
+
+func NewOrderBook(market internal.Market, depth int) *OrderBook {
+	return &OrderBook{Market: market, Depth: depth}
+}
+
+// replaces the bid level at the same price, a zero volume removes it
+func (b *OrderBook) UpdateBid(level BookLevel) {
+	b.Bids = b.update(b.Bids, level, func(price decimal.Decimal) bool { return price.LessThanOrEqual(level.Price) })
+}
+
+// replaces the ask level at the same price, a zero volume removes it
+func (b *OrderBook) UpdateAsk(level BookLevel) {
+	b.Asks = b.update(b.Asks, level, func(price decimal.Decimal) bool { return price.GreaterThanOrEqual(level.Price) })
+}
+
+// from is true for the levels at or behind the level price
+func (b *OrderBook) update(levels []BookLevel, level BookLevel, from func(price decimal.Decimal) bool) []BookLevel {
+	if level.Timestamp.After(b.Timestamp) {
+		b.Timestamp = level.Timestamp
+	}
+	i := sort.Search(len(levels), func(i int) bool { return from(levels[i].Price) })
+	found := i < len(levels) && levels[i].Price.Equal(level.Price)
+	switch {
+	case level.Volume.IsZero() && found:
+		levels = append(levels[:i], levels[i+1:]...)
+	case level.Volume.IsZero():
+	case found:
+		levels[i] = level
+	default:
+		levels = append(levels, BookLevel{})
+		copy(levels[i+1:], levels[i:])
+		levels[i] = level
+	}
+	// levels falling out of the depth are no longer updated
+	if b.Depth > 0 && len(levels) > b.Depth {
+		levels = levels[:b.Depth]
+	}
+	return levels
+}
+
+func (b *OrderBook) BestBid() (BookLevel, bool) {
+	if len(b.Bids) == 0 {
+		return BookLevel{}, false
+	}
+	return b.Bids[0], true
+}
+
+func (b *OrderBook) BestAsk() (BookLevel, bool) {
+	if len(b.Asks) == 0 {
+		return BookLevel{}, false
+	}
+	return b.Asks[0], true
+}
+
+// difference between the best ask and bid, zero if a side is empty
+func (b *OrderBook) GetSpread() decimal.Decimal {
+	bid, okBid := b.BestBid()
+	ask, okAsk := b.BestAsk()
+	if !okBid || !okAsk {
+		return decimal.Zero
+	}
+	return ask.Price.Sub(bid.Price)
+}
+
+// price between the best ask and bid, zero if a side is empty
+func (b *OrderBook) GetMidPrice() decimal.Decimal {
+	bid, okBid := b.BestBid()
+	ask, okAsk := b.BestAsk()
+	if !okBid || !okAsk {
+		return decimal.Zero
+	}
+	return ask.Price.Add(bid.Price).Div(decimal.NewFromInt(2))
+}
+
+// levels an order of the given side is filled against
+func (b *OrderBook) liquidity(side internal.OrderSide) []BookLevel {
+	if side == internal.BUY {
+		return b.Asks
+	}
+	return b.Bids
+}
+
+// volume available to an order of the given side within
+// the given basis points from the best price
+func (b *OrderBook) GetDepth(side internal.OrderSide, bps int) decimal.Decimal {
+	levels := b.liquidity(side)
+	if len(levels) == 0 {
+		return decimal.Zero
+	}
+	distance := levels[0].Price.Mul(decimal.NewFromInt(int64(bps))).Div(decimal.NewFromInt(10000))
+	limit := levels[0].Price.Add(distance)
+	if side == internal.SELL {
+		limit = levels[0].Price.Sub(distance)
+	}
+	volume := decimal.Zero
+	for _, level := range levels {
+		if (side == internal.BUY && level.Price.GreaterThan(limit)) || (side == internal.SELL && level.Price.LessThan(limit)) {
+			break
+		}
+		volume = volume.Add(level.Volume)
+	}
+	return volume
+}
+
+// average price at which a market order of the given side and
+// volume would be filled, false if the book is not deep enough
+func (b *OrderBook) GetFillPrice(side internal.OrderSide, volume decimal.Decimal) (decimal.Decimal, bool) {
+	if !volume.IsPositive() {
+		return decimal.Zero, false
+	}
+	remaining := volume
+	cost := decimal.Zero
+	for _, level := range b.liquidity(side) {
+		filled := decimal.Min(remaining, level.Volume)
+		cost = cost.Add(filled.Mul(level.Price))
+		remaining = remaining.Sub(filled)
+		if remaining.IsZero() {
+			return cost.Div(volume), true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// relative distance between the fill price of a market order and
+// the best price, false if the book is not deep enough
+func (b *OrderBook) GetSlippage(side internal.OrderSide, volume decimal.Decimal) (decimal.Decimal, bool) {
+	price, ok := b.GetFillPrice(side, volume)
+	if !ok {
+		return decimal.Zero, false
+	}
+	best := b.liquidity(side)[0].Price
+	return price.Sub(best).Div(best).Abs(), true
+}
+
+// returns a copy which is not affected by the updates of the book
+func (b *OrderBook) Copy() *OrderBook {
+	res := *b
+	res.Bids = append([]BookLevel(nil), b.Bids...)
+	res.Asks = append([]BookLevel(nil), b.Asks...)
+	return &res
+}
+
+func (b *OrderBook) String() string {
+	bid, _ := b.BestBid()
+	ask, _ := b.BestAsk()
+	return fmt.Sprintf("(%s) %s bid %s@%s; ask %s@%s", b.Timestamp.String(), b.Market, bid.Volume, bid.Price, ask.Volume, ask.Price)
+}
@@ -8,11 +8,19 @@ import (
 )
 
 type Candle struct {
-	Open      decimal.Decimal
-	High      decimal.Decimal
-	Low       decimal.Decimal
-	Close     decimal.Decimal
+	Open  decimal.Decimal
+	High  decimal.Decimal
+	Low   decimal.Decimal
+	Close decimal.Decimal
+	// traded volume, volume weighted average price and number
+	// of trades, zero when not provided by the data source
+	Volume    decimal.Decimal
+	Vwap      decimal.Decimal
+	Count     int
 	Timestamp time.Time
+	// the candle interval is still in progress, and the
+	// candle will be updated until it's closed
+	Forming bool
 }
 
 func NewCandle(o, h, l, c decimal.Decimal, ts time.Time) Candle {
@@ -25,13 +33,34 @@ func NewCandle(o, h, l, c decimal.Decimal, ts time.Time) Candle {
 	}
 }
 
+// sets the volume, vwap and trade count of the candle
+func (c Candle) WithVolume(volume decimal.Decimal, vwap decimal.Decimal, count int) Candle {
+	c.Volume = volume
+	c.Vwap = vwap
+	c.Count = count
+	return c
+}
+
 func (c *Candle) String() string {
-	return fmt.Sprintf("(%s) open %s; high %s; low %s; close %s",
+	return fmt.Sprintf("(%s) open %s; high %s; low %s; close %s; volume %s",
 		c.Timestamp.String(),
 		c.Open.StringFixed(2),
 		c.High.StringFixed(2),
 		c.Low.StringFixed(2),
-		c.Close.StringFixed(2))
+		c.Close.StringFixed(2),
+		c.Volume.String())
+}
+
+// sets whether the candle is still in progress
+func (c Candle) WithForming(forming bool) Candle {
+	c.Forming = forming
+	return c
+}
+
+// whether the candles have the same interval and values
+func (c *Candle) Equal(other Candle) bool {
+	return c.Timestamp.Equal(other.Timestamp) && c.Open.Equal(other.Open) && c.High.Equal(other.High) &&
+		c.Low.Equal(other.Low) && c.Close.Equal(other.Close) && c.Volume.Equal(other.Volume) && c.Count == other.Count
 }
 
 func (c *Candle) IsUp() bool {
@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/shopspring/decimal"
+)
+
+// futures contract listed by an exchange, either perpetual
+// or expiring at the maturity date
+type Contract struct {
+	Symbol            string
+	Market            internal.Market
+	TradeCurrency     internal.Currency
+	ReferenceCurrency internal.Currency
+	Perpetual         bool
+	Maturity          time.Time       // zero for perpetual contracts
+	ContractSize      decimal.Decimal // amount of the trade currency of a contract
+	Precision         int             // decimals of the number of contracts
+	TickSize          decimal.Decimal
+	MaxLeverage       decimal.Decimal
+	MarkPrice         decimal.Decimal
+	FundingRate       decimal.Decimal // absolute funding rate, zero for fixed maturity contracts
+}
+
+func (c *Contract) String() string {
+	if c.Perpetual {
+		return fmt.Sprintf("%s perpetual, size %s, mark %s, funding %s", c.Symbol, c.ContractSize, c.MarkPrice, c.FundingRate)
+	}
+	return fmt.Sprintf("%s expiring %s, size %s, mark %s", c.Symbol, c.Maturity.Format("2006-01-02"), c.ContractSize, c.MarkPrice)
+}
+
+// converts a volume of the trade currency into a number
+// of contracts, truncated to the contract precision
+func (c *Contract) Contracts(volume decimal.Decimal) decimal.Decimal {
+	if c.ContractSize.IsZero() {
+		return decimal.Zero
+	}
+	return volume.Div(c.ContractSize).Truncate(int32(c.Precision))
+}
+
+// converts a number of contracts into the volume of the trade currency
+func (c *Contract) Volume(contracts decimal.Decimal) decimal.Decimal {
+	return contracts.Mul(c.ContractSize)
+}
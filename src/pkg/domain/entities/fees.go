@@ -0,0 +1,52 @@
+package entities
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// interval after which the margin rollover fee is charged again
+const ROLLOVER_INTERVAL = 4 * time.Hour
+
+// fee rates of a market, as fractions of the order cost
+type Fees struct {
+	Maker decimal.Decimal
+	Taker decimal.Decimal
+	// charged when a margin position is opened
+	MarginOpen decimal.Decimal
+	// charged on the open margin positions every ROLLOVER_INTERVAL
+	MarginRollover decimal.Decimal
+}
+
+// kraken starter tier, used until the fees of the market are known
+var DEFAULT_FEES = Fees{
+	Maker:          decimal.RequireFromString("0.0025"),
+	Taker:          decimal.RequireFromString("0.004"),
+	MarginOpen:     decimal.RequireFromString("0.0002"),
+	MarginRollover: decimal.RequireFromString("0.0002"),
+}
+
+// fee rate of an order given whether it adds liquidity to the book
+func (f Fees) Rate(maker bool) decimal.Decimal {
+	if maker {
+		return f.Maker
+	}
+	return f.Taker
+}
+
+// rollover fees charged on a margin position opened since the given time,
+// the first period being charged with the opening fee
+func (f Fees) Rollover(cost decimal.Decimal, since time.Time) decimal.Decimal {
+	periods := int64(math.Floor(time.Since(since).Hours() / ROLLOVER_INTERVAL.Hours()))
+	if periods <= 0 {
+		return decimal.Zero
+	}
+	return cost.Mul(f.MarginRollover).Mul(decimal.NewFromInt(periods))
+}
+
+func (f Fees) String() string {
+	return fmt.Sprintf("maker %s, taker %s, margin %s, rollover %s", f.Maker, f.Taker, f.MarginOpen, f.MarginRollover)
+}
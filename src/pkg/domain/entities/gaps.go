@@ -0,0 +1,81 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+)
+
+// intervals missing between two candles of a series, from the
+// start of the first missing interval to the next candle, excluded
+type Gap struct {
+	From  time.Time
+	Until time.Time
+}
+
+// number of candles missing in the gap
+func (g Gap) Missing(interval time.Duration) int {
+	return int(g.Until.Sub(g.From) / interval)
+}
+
+func (g Gap) String() string {
+	return fmt.Sprintf("[%s, %s)", g.From, g.Until)
+}
+
+// data quality of the candles of a trend timeframe
+type SeriesQuality struct {
+	Candles int
+	Gaps    []Gap
+	// candles missing in the gaps
+	Missing int
+}
+
+// whether the series has no missing interval
+func (q SeriesQuality) Complete() bool {
+	return len(q.Gaps) == 0
+}
+
+func (q SeriesQuality) String() string {
+	return fmt.Sprintf("%d candles, %d missing in %d gaps", q.Candles, q.Missing, len(q.Gaps))
+}
+
+// returns the gaps between the candles sorted by time
+func FindGaps(candles []Candle, interval time.Duration) []Gap {
+	var gaps []Gap
+	for i := 1; i < len(candles); i++ {
+		next := candles[i-1].Timestamp.Add(interval)
+		if candles[i].Timestamp.After(next) {
+			gaps = append(gaps, Gap{From: next, Until: candles[i].Timestamp})
+		}
+	}
+	return gaps
+}
+
+// returns the intervals missing after the last candle, the candles
+// closed during the last interval being possibly still on their way
+func TrailingGap(candles []Candle, interval time.Duration, now time.Time) (Gap, bool) {
+	if len(candles) == 0 {
+		return Gap{}, false
+	}
+	gap := Gap{
+		From:  candles[len(candles)-1].Timestamp.Add(interval),
+		Until: now.Truncate(interval).Add(-interval),
+	}
+	return gap, gap.From.Before(gap.Until)
+}
+
+// returns the candles with the gaps filled by flat candles at the
+// previous close, with no volume, as for the intervals without trades
+func FillGaps(candles []Candle, interval time.Duration) []Candle {
+	if len(candles) == 0 {
+		return candles
+	}
+	res := []Candle{candles[0]}
+	for _, candle := range candles[1:] {
+		prev := res[len(res)-1]
+		for start := prev.Timestamp.Add(interval); start.Before(candle.Timestamp); start = start.Add(interval) {
+			res = append(res, NewCandle(prev.Close, prev.Close, prev.Close, prev.Close, start))
+		}
+		res = append(res, candle)
+	}
+	return res
+}
@@ -12,14 +12,24 @@ import (
 // it exposes methods to retrieve (for each market defined in the application) the following informations
 //   - trade currency
 //   - reference currency
-//   - decimals (precision)
+//   - decimals (volume precision)
+//   - price decimals and min price increment (tick size)
 //   - min cost of an order
+//   - lot multiplier, available leverages and trading status
+//   - maker, taker and margin fees
 type metadata struct {
 	Decimals          int
+	PriceDecimals     int
 	TradeCurrency     internal.Currency
 	ReferenceCurrency internal.Currency
 	MinCost           decimal.Decimal
 	OrderMin          decimal.Decimal
+	TickSize          decimal.Decimal
+	LotMultiplier     int
+	LeverageBuy       []int
+	LeverageSell      []int
+	Status            internal.MarketStatus
+	Fees              *Fees
 }
 
 type IMarkets interface {
@@ -34,6 +44,30 @@ type IMarkets interface {
 	SetMinCost(market internal.Market, value decimal.Decimal)
 	GetOrderMin(market internal.Market) decimal.Decimal
 	SetOrderMin(market internal.Market, value decimal.Decimal)
+	GetTickSize(market internal.Market) decimal.Decimal
+	SetTickSize(market internal.Market, value decimal.Decimal)
+	GetPriceDecimals(market internal.Market) int
+	SetPriceDecimals(market internal.Market, value int)
+	GetLotMultiplier(market internal.Market) int
+	SetLotMultiplier(market internal.Market, value int)
+	GetLeverages(market internal.Market) (buy []int, sell []int)
+	SetLeverages(market internal.Market, buy []int, sell []int)
+	GetStatus(market internal.Market) internal.MarketStatus
+	SetStatus(market internal.Market, value internal.MarketStatus)
+	// fee rates of the market, DEFAULT_FEES when not known
+	GetFees(market internal.Market) Fees
+	SetFees(market internal.Market, value Fees)
+	// rounds the price to the closest multiple of the tick size
+	// (or to the price decimals if the tick size is not known).
+	// prices of markets not registered are left unchanged
+	RoundPrice(market internal.Market, price decimal.Decimal) decimal.Decimal
+	// truncates the volume to a multiple of the lot (the volume
+	// precision times the lot multiplier), so that the order
+	// never exceeds the available balance. volumes of markets
+	// not registered are left unchanged
+	RoundVolume(market internal.Market, volume decimal.Decimal) decimal.Decimal
+	// checks that the trading status of the market accepts the order
+	CheckStatus(order *Order) error
 	SetMetadata(
 		market internal.Market,
 		decimals int,
@@ -48,20 +82,23 @@ type markets struct {
 	markets map[internal.Market]*metadata
 }
 
+// markets are registered by the exchanges with SetMetadata
 func NewMarkets() IMarkets {
 	return &markets{
-		markets: map[internal.Market]*metadata{
-			internal.XBTEUR:  {},
-			internal.XBTUSD:  {},
-			internal.XBTUSDT: {},
-			internal.ETHEUR:  {},
-			internal.ETHUSD:  {},
-		},
+		markets: map[internal.Market]*metadata{},
 	}
 }
 
+// returns empty metadata for markets not registered
+func (c *markets) get(market internal.Market) *metadata {
+	if v, ok := c.markets[market]; ok {
+		return v
+	}
+	return &metadata{}
+}
+
 func (c *markets) GetDecimals(market internal.Market) int {
-	return c.markets[market].Decimals
+	return c.get(market).Decimals
 }
 
 func (c *markets) SetDecimals(market internal.Market, value int) {
@@ -71,7 +108,7 @@ func (c *markets) SetDecimals(market internal.Market, value int) {
 }
 
 func (c *markets) GetTradeCurrency(market internal.Market) internal.Currency {
-	return c.markets[market].TradeCurrency
+	return c.get(market).TradeCurrency
 }
 
 func (c *markets) SetTradeCurrency(market internal.Market, value internal.Currency) {
@@ -81,7 +118,7 @@ func (c *markets) SetTradeCurrency(market internal.Market, value internal.Curren
 }
 
 func (c *markets) GetReferenceCurrency(market internal.Market) internal.Currency {
-	return c.markets[market].ReferenceCurrency
+	return c.get(market).ReferenceCurrency
 }
 
 func (c *markets) SetReferenceCurrency(market internal.Market, value internal.Currency) {
@@ -95,7 +132,7 @@ func (c *markets) GetMetadata(market internal.Market) *metadata {
 }
 
 func (c *markets) GetMinCost(market internal.Market) decimal.Decimal {
-	return c.markets[market].MinCost
+	return c.get(market).MinCost
 }
 
 func (c *markets) SetMinCost(market internal.Market, value decimal.Decimal) {
@@ -105,7 +142,7 @@ func (c *markets) SetMinCost(market internal.Market, value decimal.Decimal) {
 }
 
 func (c *markets) GetOrderMin(market internal.Market) decimal.Decimal {
-	return c.markets[market].OrderMin
+	return c.get(market).OrderMin
 }
 
 func (c *markets) SetOrderMin(market internal.Market, value decimal.Decimal) {
@@ -114,6 +151,121 @@ func (c *markets) SetOrderMin(market internal.Market, value decimal.Decimal) {
 	}
 }
 
+func (c *markets) GetTickSize(market internal.Market) decimal.Decimal {
+	return c.get(market).TickSize
+}
+
+func (c *markets) SetTickSize(market internal.Market, value decimal.Decimal) {
+	if v, ok := c.markets[market]; ok {
+		v.TickSize = value
+	}
+}
+
+func (c *markets) GetPriceDecimals(market internal.Market) int {
+	return c.get(market).PriceDecimals
+}
+
+func (c *markets) SetPriceDecimals(market internal.Market, value int) {
+	if v, ok := c.markets[market]; ok {
+		v.PriceDecimals = value
+	}
+}
+
+// the volume of a lot, one when not set
+func (c *markets) GetLotMultiplier(market internal.Market) int {
+	if multiplier := c.get(market).LotMultiplier; multiplier > 0 {
+		return multiplier
+	}
+	return 1
+}
+
+func (c *markets) SetLotMultiplier(market internal.Market, value int) {
+	if v, ok := c.markets[market]; ok {
+		v.LotMultiplier = value
+	}
+}
+
+func (c *markets) GetLeverages(market internal.Market) ([]int, []int) {
+	md := c.get(market)
+	return md.LeverageBuy, md.LeverageSell
+}
+
+func (c *markets) SetLeverages(market internal.Market, buy []int, sell []int) {
+	if v, ok := c.markets[market]; ok {
+		v.LeverageBuy = buy
+		v.LeverageSell = sell
+	}
+}
+
+// markets without a reported status are considered online
+func (c *markets) GetStatus(market internal.Market) internal.MarketStatus {
+	if status := c.get(market).Status; status != "" {
+		return status
+	}
+	return internal.MARKET_ONLINE
+}
+
+func (c *markets) SetStatus(market internal.Market, value internal.MarketStatus) {
+	if v, ok := c.markets[market]; ok {
+		v.Status = value
+	}
+}
+
+func (c *markets) GetFees(market internal.Market) Fees {
+	if fees := c.get(market).Fees; fees != nil {
+		return *fees
+	}
+	return DEFAULT_FEES
+}
+
+func (c *markets) SetFees(market internal.Market, value Fees) {
+	if v, ok := c.markets[market]; ok {
+		v.Fees = &value
+	}
+}
+
+func (c *markets) RoundPrice(market internal.Market, price decimal.Decimal) decimal.Decimal {
+	md, ok := c.markets[market]
+	if !ok {
+		return price
+	}
+	if md.TickSize.IsPositive() {
+		return price.Div(md.TickSize).Round(0).Mul(md.TickSize)
+	}
+	return price.Round(int32(md.PriceDecimals))
+}
+
+func (c *markets) RoundVolume(market internal.Market, volume decimal.Decimal) decimal.Decimal {
+	md, ok := c.markets[market]
+	if !ok {
+		return volume
+	}
+	lot := decimal.New(int64(c.GetLotMultiplier(market)), -int32(md.Decimals))
+	return volume.Div(lot).Truncate(0).Mul(lot)
+}
+
+// restricted markets only accept some orders: closing orders when
+// reduce only, limit orders when limit only, post only orders when
+// post only. no order is accepted when offline or cancel only
+func (c *markets) CheckStatus(order *Order) error {
+	status := c.GetStatus(order.Market)
+	var accepted bool
+	switch status {
+	case internal.MARKET_ONLINE:
+		accepted = true
+	case internal.MARKET_REDUCE_ONLY:
+		accepted = order.ReduceOnly
+	case internal.MARKET_LIMIT_ONLY:
+		accepted = order.PriceType == internal.LIMIT
+	case internal.MARKET_POST_ONLY:
+		accepted = order.PostOnly
+	}
+	if !accepted {
+		return fmt.Errorf("%s %s order not accepted, market %s", order.PriceType, order.Side, status)
+	}
+	return nil
+}
+
 func (c *markets) SetMetadata(
 	market internal.Market,
 	precision int,
@@ -143,7 +295,7 @@ func (c *markets) SetMetadata(
 func (c *markets) String() string {
 	var res string
 	for market, md := range c.markets {
-		res += fmt.Sprintf("[%s]: precision %d, tc %s, rc %s, mc %s", market, md.Decimals, md.TradeCurrency, md.ReferenceCurrency, md.MinCost)
+		res += fmt.Sprintf("[%s]: precision %d, price precision %d, tick %s, tc %s, rc %s, mc %s, status %s", market, md.Decimals, md.PriceDecimals, md.TickSize, md.TradeCurrency, md.ReferenceCurrency, md.MinCost, c.GetStatus(market))
 	}
 	return res
 }
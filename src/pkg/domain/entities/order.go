@@ -10,7 +10,7 @@ import (
 
 type Order struct {
 	Id            string               `bson:"order_id,omitempty"`
-	RemoteId      int64                `bson:"remote_id,omitempty"`
+	RemoteId      string               `bson:"remote_id,omitempty"`
 	LimitPrice    decimal.Decimal      `bson:"limit_price,omitempty"`
 	MarketPrice   decimal.Decimal      `bson:"market_price,omitempty"`
 	Type          internal.OrderType   `bson:"order_type,omitempty"`
@@ -24,6 +24,39 @@ type Order struct {
 	Ioc           bool                 `bson:"ioc,omitempty"`
 	PostOnly      bool                 `bson:"postonly,omitempty"`
 	Leverage      int64                `bson:"leverage,omitempty"`
+	// execution details, updated by the exchange
+	ExecutedVolume decimal.Decimal `bson:"executed_volume,omitempty"`
+	AveragePrice   decimal.Decimal `bson:"average_price,omitempty"`
+	Fee            decimal.Decimal `bson:"fee,omitempty"`
+	UpdatedAt      time.Time       `bson:"updated_at,omitempty"`
+	ClosedAt       time.Time       `bson:"closed_at,omitempty"`
+}
+
+// status and execution update of an order received from the exchange
+type OrderUpdate struct {
+	Id             string // client order id
+	RemoteId       string // exchange order id
+	Status         internal.OrderStatus
+	ExecutedVolume decimal.Decimal
+	AveragePrice   decimal.Decimal
+	Fee            decimal.Decimal
+	Timestamp      time.Time
+}
+
+// acknowledgement of an order accepted by the exchange
+type OrderAck struct {
+	Id          string   // client order id
+	RemoteIds   []string // exchange ids, the first one identifies the order
+	Description string
+	Status      internal.OrderStatus
+}
+
+// returns the exchange id of the acknowledged order
+func (ack *OrderAck) RemoteId() string {
+	if len(ack.RemoteIds) == 0 {
+		return ""
+	}
+	return ack.RemoteIds[0]
 }
 
 func (Order) TableName() string {
@@ -38,6 +71,30 @@ func (order *Order) IsFinal() bool {
 	return order.Status != internal.CREATED && order.Status != internal.OPEN
 }
 
+// applies the exchange update to the order, zero values
+// of the update are ignored
+func (order *Order) Apply(update OrderUpdate) {
+	if update.RemoteId != "" {
+		order.RemoteId = update.RemoteId
+	}
+	if update.Status != "" {
+		order.Status = update.Status
+	}
+	if !update.ExecutedVolume.IsZero() {
+		order.ExecutedVolume = update.ExecutedVolume
+	}
+	if !update.AveragePrice.IsZero() {
+		order.AveragePrice = update.AveragePrice
+	}
+	if !update.Fee.IsZero() {
+		order.Fee = update.Fee
+	}
+	order.UpdatedAt = update.Timestamp
+	if order.IsFinal() && order.ClosedAt.IsZero() {
+		order.ClosedAt = update.Timestamp
+	}
+}
+
 func (order *Order) IsSpot() bool {
 	return order.Type == internal.SPOT
 }
@@ -54,6 +111,31 @@ func (order *Order) GetMarketCost() decimal.Decimal {
 	return order.MarketPrice.Mul(order.InitialVolume)
 }
 
+// orders resting in the book pay the maker fee, market
+// and immediate or cancel orders the taker fee
+func (order *Order) IsMaker() bool {
+	return order.PostOnly || (order.PriceType == internal.LIMIT && !order.Ioc)
+}
+
+// fee of the order according to the fees of the market,
+// including the opening fee of margin positions
+func (order *Order) GetEstimatedFee() decimal.Decimal {
+	fees := Markets.GetFees(order.Market)
+	rate := fees.Rate(order.IsMaker())
+	if order.IsMargin() && !order.ReduceOnly {
+		rate = rate.Add(fees.MarginOpen)
+	}
+	return order.GetMarketCost().Mul(rate)
+}
+
+// amount spent by buy orders and received by sell orders, fees included
+func (order *Order) GetCostWithFees() decimal.Decimal {
+	if order.Side == internal.BUY {
+		return order.GetMarketCost().Add(order.GetEstimatedFee())
+	}
+	return order.GetMarketCost().Sub(order.GetEstimatedFee())
+}
+
 func (order *Order) GetTradeCurrency() internal.Currency {
 	return Markets.GetTradeCurrency(order.Market)
 }
@@ -0,0 +1,103 @@
+package entities
+
+import (
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+)
+
+// keeps track of the orders submitted to the exchange until
+// they reach a final state, so that a new order is not opened
+// while a previous one is still in flight
+type IOrders interface {
+	// starts tracking the order, its creation time
+	// is set to the current time if missing
+	Add(order *Order)
+	// returns the tracked order with the given client id
+	Get(id string) *Order
+	// applies the update to the matching order, matched by client id
+	// or by exchange id. orders reaching a final state are not tracked anymore.
+	// returns nil if the order is unknown
+	Update(update OrderUpdate) *Order
+	// returns the tracked orders of the market
+	InFlight(market internal.Market) []*Order
+	// returns a copy of every tracked order
+	Tracked() []Order
+}
+
+type orders struct {
+	mu       sync.Mutex
+	orders   map[string]*Order
+	remoteId map[string]string
+}
+
+func NewOrders() IOrders {
+	return &orders{
+		orders:   map[string]*Order{},
+		remoteId: map[string]string{},
+	}
+}
+
+func (o *orders) Add(order *Order) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = time.Now()
+	}
+	o.orders[order.Id] = order
+}
+
+func (o *orders) Get(id string) *Order {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.orders[id]
+}
+
+func (o *orders) Update(update OrderUpdate) *Order {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	id := update.Id
+	if id == "" {
+		id = o.remoteId[update.RemoteId]
+	}
+	order, ok := o.orders[id]
+	if !ok {
+		return nil
+	}
+	if update.RemoteId != "" {
+		o.remoteId[update.RemoteId] = id
+	}
+	order.Apply(update)
+	if order.IsFinal() {
+		delete(o.orders, id)
+		for remote, local := range o.remoteId {
+			if local == id {
+				delete(o.remoteId, remote)
+			}
+		}
+	}
+	return order
+}
+
+func (o *orders) InFlight(market internal.Market) []*Order {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var res []*Order
+	for _, order := range o.orders {
+		if order.Market == market {
+			res = append(res, order)
+		}
+	}
+	return res
+}
+
+func (o *orders) Tracked() []Order {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	res := make([]Order, 0, len(o.orders))
+	for _, order := range o.orders {
+		res = append(res, *order)
+	}
+	return res
+}
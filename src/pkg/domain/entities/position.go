@@ -20,6 +20,67 @@ type Position struct {
 	CreatedAt  time.Time
 	Cost       decimal.Decimal
 	Leverage   int
+	// fees paid so far (opening and rollover), estimated when zero
+	Fee decimal.Decimal
+}
+
+func (p *Position) GetCost() decimal.Decimal {
+	if !p.Cost.IsZero() {
+		return p.Cost
+	}
+	return p.OpenPrice.Mul(p.Size)
+}
+
+// fees paid to open and keep the position, estimated from
+// the fees of the market when not reported by the exchange
+func (p *Position) GetFees() decimal.Decimal {
+	if !p.Fee.IsZero() {
+		return p.Fee
+	}
+	fees := Markets.GetFees(p.Market)
+	res := p.GetCost().Mul(fees.Taker)
+	if p.Leverage > 1 {
+		res = res.Add(p.GetCost().Mul(fees.MarginOpen)).Add(fees.Rollover(p.GetCost(), p.CreatedAt))
+	}
+	return res
+}
+
+// fee of a market order closing the position at the given price
+func (p *Position) GetClosingFee(price decimal.Decimal) decimal.Decimal {
+	return price.Mul(p.Size).Mul(Markets.GetFees(p.Market).Taker)
+}
+
+// profit or loss of closing the position at the given price, before fees
+func (p *Position) GetPnL(price decimal.Decimal) decimal.Decimal {
+	pnl := price.Sub(p.OpenPrice).Mul(p.Size)
+	if p.Side == internal.SELL {
+		return pnl.Neg()
+	}
+	return pnl
+}
+
+// profit or loss of closing the position at the given price,
+// net of the fees paid and of the closing fee
+func (p *Position) GetNetPnL(price decimal.Decimal) decimal.Decimal {
+	return p.GetPnL(price).Sub(p.GetFees()).Sub(p.GetClosingFee(price))
+}
+
+// whether closing the position at the given price is profitable after fees
+func (p *Position) IsProfitable(price decimal.Decimal) bool {
+	return p.GetNetPnL(price).IsPositive()
+}
+
+// price at which closing the position covers every fee
+func (p *Position) GetBreakEvenPrice() decimal.Decimal {
+	if p.Size.IsZero() {
+		return p.OpenPrice
+	}
+	closing := Markets.GetFees(p.Market).Taker
+	cost := p.OpenPrice.Mul(p.Size)
+	if p.Side == internal.SELL {
+		return cost.Sub(p.GetFees()).Div(p.Size.Mul(decimal.NewFromInt(1).Add(closing)))
+	}
+	return cost.Add(p.GetFees()).Div(p.Size.Mul(decimal.NewFromInt(1).Sub(closing)))
 }
 
 func (p *Position) String() string {
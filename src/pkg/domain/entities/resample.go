@@ -0,0 +1,115 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// builds the candles of a higher timeframe from the closed candles of
+// a base timeframe (e.g. 1h candles from 1m candles). the intervals
+// are aligned to the UTC boundaries, as the exchange candles, for
+// the intervals dividing a day. only the intervals with every base
+// candle are built, the others being wrong (e.g. the open of a
+// missing first base candle)
+type CandleResampler struct {
+	base     time.Duration
+	interval time.Duration
+	current  *Candle
+	// vwap * volume of the base candles of the current candle
+	cost decimal.Decimal
+	// time of the next base candle of the current candle
+	next time.Time
+	// time of the last base candle added, the candles added again are ignored
+	last time.Time
+}
+
+func NewCandleResampler(base time.Duration, interval time.Duration) *CandleResampler {
+	return &CandleResampler{base: base, interval: interval}
+}
+
+// adds a closed base candle, returning the candle of its interval
+// when it's the last one of the interval. the interval is dropped
+// as soon as one of its base candles is missing
+func (r *CandleResampler) Add(candle Candle) (Candle, bool) {
+	if !r.last.IsZero() && !candle.Timestamp.After(r.last) {
+		return Candle{}, false
+	}
+	r.last = candle.Timestamp
+	start := candle.Timestamp.Truncate(r.interval)
+	if r.current != nil && !candle.Timestamp.Equal(r.next) {
+		r.current = nil
+	}
+	if r.current == nil {
+		if !candle.Timestamp.Equal(start) {
+			return Candle{}, false
+		}
+		next := candle
+		next.Timestamp = start
+		next.Forming = false
+		r.current = &next
+		r.cost = weightedPrice(candle)
+	} else {
+		merged := r.merge(*r.current, r.cost, candle)
+		r.current = &merged
+		r.cost = r.cost.Add(weightedPrice(candle))
+	}
+	r.next = candle.Timestamp.Add(r.base)
+	if r.next.Before(start.Add(r.interval)) {
+		return Candle{}, false
+	}
+	closed := *r.current
+	r.current = nil
+	return closed, true
+}
+
+// returns the candle in progress updated with the candle in progress
+// of the base timeframe, false if its interval is dropped
+func (r *CandleResampler) Forming(candle Candle) (Candle, bool) {
+	start := candle.Timestamp.Truncate(r.interval)
+	if r.current != nil && r.current.Timestamp.Equal(start) && candle.Timestamp.Equal(r.next) {
+		return r.merge(*r.current, r.cost, candle).WithForming(true), true
+	}
+	if !candle.Timestamp.Equal(start) {
+		return Candle{}, false
+	}
+	candle.Timestamp = start
+	return candle.WithForming(true), true
+}
+
+func (r *CandleResampler) merge(current Candle, cost decimal.Decimal, candle Candle) Candle {
+	current.High = decimal.Max(current.High, candle.High)
+	current.Low = decimal.Min(current.Low, candle.Low)
+	current.Close = candle.Close
+	current.Volume = current.Volume.Add(candle.Volume)
+	current.Count += candle.Count
+	if current.Volume.IsPositive() {
+		current.Vwap = cost.Add(weightedPrice(candle)).Div(current.Volume)
+	}
+	return current
+}
+
+// vwap * volume of the candle, the close is used
+// when the data source has no vwap
+func weightedPrice(candle Candle) decimal.Decimal {
+	if candle.Vwap.IsZero() {
+		return candle.Close.Mul(candle.Volume)
+	}
+	return candle.Vwap.Mul(candle.Volume)
+}
+
+// resamples the closed base candles sorted by time to the interval,
+// the last candle is returned even if its interval isn't complete
+func Resample(candles []Candle, base time.Duration, interval time.Duration) []Candle {
+	resampler := NewCandleResampler(base, interval)
+	var res []Candle
+	for _, candle := range candles {
+		if closed, ok := resampler.Add(candle); ok {
+			res = append(res, closed)
+		}
+	}
+	if resampler.current != nil {
+		res = append(res, *resampler.current)
+	}
+	return res
+}
@@ -0,0 +1,39 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/shopspring/decimal"
+)
+
+// single public trade executed on a market
+type Trade struct {
+	Market    internal.Market
+	Price     decimal.Decimal
+	Volume    decimal.Decimal
+	Side      internal.OrderSide
+	PriceType internal.PriceType
+	Timestamp time.Time
+}
+
+func (t *Trade) String() string {
+	return fmt.Sprintf("(%s) %s %s %s@%s", t.Timestamp.String(), t.Side, t.Volume, t.Market, t.Price)
+}
+
+// last known top of the book and daily stats of a market
+type Ticker struct {
+	Market    internal.Market
+	Ask       decimal.Decimal
+	AskVolume decimal.Decimal
+	Bid       decimal.Decimal
+	BidVolume decimal.Decimal
+	Last      decimal.Decimal
+	Volume    decimal.Decimal // volume of the last 24 hours
+	Timestamp time.Time
+}
+
+func (t *Ticker) String() string {
+	return fmt.Sprintf("(%s) %s bid %s; ask %s; last %s", t.Timestamp.String(), t.Market, t.Bid, t.Ask, t.Last)
+}
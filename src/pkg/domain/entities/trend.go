@@ -1,7 +1,12 @@
 package entities
 
 import (
+	"fmt"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/d0ze/golang-hft/src/internal"
@@ -9,27 +14,111 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+// candles interval in minutes. sub-minute intervals, built from the
+// trades, are negative numbers of seconds (e.g. -30 for 30s candles)
 type Timeframe int
 
 const (
 	TIMEFRAME_1M  Timeframe = 1
 	TIMEFRAME_5M  Timeframe = 5
 	TIMEFRAME_15M Timeframe = 15
+	TIMEFRAME_30M Timeframe = 30
 	TIMEFRAME_1H  Timeframe = 60
+	TIMEFRAME_4H  Timeframe = 240
+	TIMEFRAME_1D  Timeframe = 1440
+	TIMEFRAME_1W  Timeframe = 10080
+	TIMEFRAME_15D Timeframe = 21600
 )
 
+var defaultTimeframes = []Timeframe{TIMEFRAME_1M, TIMEFRAME_5M, TIMEFRAME_15M, TIMEFRAME_1H}
+
+// timeframe of the candles of the given interval, in whole seconds
+func TimeframeOf(interval time.Duration) Timeframe {
+	if interval%time.Minute == 0 {
+		return Timeframe(interval / time.Minute)
+	}
+	return -Timeframe(interval / time.Second)
+}
+
+// parses a timeframe either in minutes (e.g. 15) or with
+// one of the units s, m, h, d, w (e.g. 30s, 4h, 1d)
+func ParseTimeframe(value string) (Timeframe, error) {
+	units := map[string]time.Duration{
+		"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour,
+	}
+	unit := time.Minute
+	number := strings.TrimSpace(value)
+	if len(number) > 0 {
+		if u, ok := units[strings.ToLower(number[len(number)-1:])]; ok {
+			unit, number = u, number[:len(number)-1]
+		}
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid timeframe %q", value)
+	}
+	return TimeframeOf(time.Duration(n) * unit), nil
+}
+
+func (t Timeframe) Duration() time.Duration {
+	if t < 0 {
+		return time.Duration(-t) * time.Second
+	}
+	return time.Duration(t) * time.Minute
+}
+
+func (t Timeframe) String() string {
+	d := t.Duration()
+	switch {
+	case d%(7*24*time.Hour) == 0:
+		return fmt.Sprintf("%dw", d/(7*24*time.Hour))
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
 type ITrend interface {
-	// adds a new candle price to the current trend
+	// adds a new closed candle to the current trend
 	// if already 12 candles are present, we also remove the
-	// first price and shift the slice
+	// first price and shift the slice. the timeframe is
+	// created on its first candle
 	Update(new Candle, timeframe int)
+	// adds the closed candles to the timeframe, in order of time,
+	// replacing the candles with the same timestamp. used to
+	// insert the candles missing from the series
+	Merge(candles []Candle, timeframe int)
+	// returns the missing intervals of the timeframe candles
+	GetQuality(timeframe int) SeriesQuality
+	// replaces the candle in progress of the timeframe, dropped
+	// once a candle of the same interval or later is closed
+	SetForming(candle Candle, timeframe int)
+	// returns the candle in progress, false if not received
+	// since the last candle was closed
+	GetForming(timeframe int) (Candle, bool)
+	// keeps at least size candles for the timeframe, for the
+	// indicators needing more than OHLC_SIZE candles
+	SetSize(timeframe int, size int)
+	// returns the timeframes tracked by the trend, sorted by interval
+	Timeframes() []int
 	// returns the time-weighted avg price of the last
 	// 12 candles
 	GetTwap(timeframe int) *decimal.Decimal
 	// return the candle at the given position
 	GetCandle(position int, timeframe int) Candle
-	// return all the latest candles
+	// return all the latest candles, empty for the timeframes not tracked.
+	// the candles are not modified by the following updates and must
+	// not be modified by the caller, as they are shared with the trend
 	GetCandles(timeframe int) *[]Candle
+	// returns a copy of the trend as it is now, not changed by the
+	// following updates, so that every indicator is computed on the
+	// same candles
+	Snapshot() ITrend
 	// returrns the trend market
 	GetMarket() internal.Market
 	GetSMA(period int, timeframe int) *decimal.Decimal
@@ -38,26 +127,141 @@ type ITrend interface {
 	GetMACD(fastPeriod int, slowPeriod int, signalPeriod int, timeframe int) (*decimal.Decimal, *decimal.Decimal)
 }
 
+// the trend is updated and read by different goroutines. the candles
+// of each timeframe are never modified once stored: every update
+// replaces them, so readers get a consistent series without copies
 type trend struct {
-	timeframes map[Timeframe]*[]Candle
+	mu         sync.RWMutex
+	timeframes map[Timeframe][]Candle
+	forming    map[Timeframe]Candle
+	sizes      map[Timeframe]int
 	market     internal.Market
 }
 
-func InitTrend(market internal.Market) ITrend {
-	return &trend{market: market, timeframes: map[Timeframe]*[]Candle{
-		TIMEFRAME_1M:  {},
-		TIMEFRAME_5M:  {},
-		TIMEFRAME_15M: {},
-		TIMEFRAME_1H:  {},
-	}}
+// creates the trend of the market tracking the given
+// timeframes, 1m, 5m, 15m and 1h if none is given
+func InitTrend(market internal.Market, timeframes ...int) ITrend {
+	t := &trend{market: market, timeframes: map[Timeframe][]Candle{}, forming: map[Timeframe]Candle{}, sizes: map[Timeframe]int{}}
+	if len(timeframes) == 0 {
+		for _, timeframe := range defaultTimeframes {
+			timeframes = append(timeframes, int(timeframe))
+		}
+	}
+	for _, timeframe := range timeframes {
+		t.timeframes[Timeframe(timeframe)] = []Candle{}
+	}
+	return t
 }
 
 func (t *trend) Update(new Candle, timeframe int) {
-	candles := t.GetCandles(timeframe)
-	if len(*candles) >= internal.Config.OHLCSize {
-		*candles = (*candles)[1:]
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	candles := t.timeframes[Timeframe(timeframe)]
+	size := t.size(timeframe)
+	if len(candles) >= size {
+		candles = candles[len(candles)-size+1:]
+	}
+	next := make([]Candle, 0, len(candles)+1)
+	t.timeframes[Timeframe(timeframe)] = append(append(next, candles...), new.WithForming(false))
+	if forming, ok := t.forming[Timeframe(timeframe)]; ok && !forming.Timestamp.After(new.Timestamp) {
+		delete(t.forming, Timeframe(timeframe))
+	}
+}
+
+// candles kept for the timeframe
+func (t *trend) size(timeframe int) int {
+	size := internal.Config.OHLCSize
+	if t.sizes[Timeframe(timeframe)] > size {
+		size = t.sizes[Timeframe(timeframe)]
+	}
+	return size
+}
+
+func (t *trend) Merge(candles []Candle, timeframe int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	byTime := map[int64]Candle{}
+	for _, series := range [][]Candle{t.timeframes[Timeframe(timeframe)], candles} {
+		for _, candle := range series {
+			byTime[candle.Timestamp.UnixNano()] = candle.WithForming(false)
+		}
+	}
+	next := make([]Candle, 0, len(byTime))
+	for _, candle := range byTime {
+		next = append(next, candle)
 	}
-	*candles = append(*candles, new)
+	sort.Slice(next, func(i, j int) bool { return next[i].Timestamp.Before(next[j].Timestamp) })
+	if size := t.size(timeframe); len(next) > size {
+		next = next[len(next)-size:]
+	}
+	t.timeframes[Timeframe(timeframe)] = next
+}
+
+func (t *trend) GetQuality(timeframe int) SeriesQuality {
+	candles := *t.GetCandles(timeframe)
+	interval := Timeframe(timeframe).Duration()
+	quality := SeriesQuality{Candles: len(candles), Gaps: FindGaps(candles, interval)}
+	for _, gap := range quality.Gaps {
+		quality.Missing += gap.Missing(interval)
+	}
+	return quality
+}
+
+func (t *trend) SetForming(candle Candle, timeframe int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.timeframes[Timeframe(timeframe)]; !ok {
+		t.timeframes[Timeframe(timeframe)] = []Candle{}
+	}
+	t.forming[Timeframe(timeframe)] = candle.WithForming(true)
+}
+
+func (t *trend) GetForming(timeframe int) (Candle, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	candle, ok := t.forming[Timeframe(timeframe)]
+	return candle, ok
+}
+
+func (t *trend) SetSize(timeframe int, size int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.timeframes[Timeframe(timeframe)]; !ok {
+		t.timeframes[Timeframe(timeframe)] = []Candle{}
+	}
+	t.sizes[Timeframe(timeframe)] = size
+}
+
+func (t *trend) Snapshot() ITrend {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	res := &trend{
+		market:     t.market,
+		timeframes: make(map[Timeframe][]Candle, len(t.timeframes)),
+		forming:    make(map[Timeframe]Candle, len(t.forming)),
+		sizes:      make(map[Timeframe]int, len(t.sizes)),
+	}
+	for timeframe, candles := range t.timeframes {
+		res.timeframes[timeframe] = candles
+	}
+	for timeframe, candle := range t.forming {
+		res.forming[timeframe] = candle
+	}
+	for timeframe, size := range t.sizes {
+		res.sizes[timeframe] = size
+	}
+	return res
+}
+
+func (t *trend) Timeframes() []int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	res := make([]int, 0, len(t.timeframes))
+	for timeframe := range t.timeframes {
+		res = append(res, int(timeframe))
+	}
+	sort.Slice(res, func(i, j int) bool { return Timeframe(res[i]).Duration() < Timeframe(res[j]).Duration() })
+	return res
 }
 
 func (t *trend) GetTwap(timeframe int) *decimal.Decimal {
@@ -65,7 +269,7 @@ func (t *trend) GetTwap(timeframe int) *decimal.Decimal {
 	if len(*candles) == 0 {
 		return nil
 	}
-	precision := Markets.GetDecimals(t.market)
+	precision := Markets.GetPriceDecimals(t.market)
 	var sumWeightedPrice decimal.Decimal
 	var totalTimeWeight int
 
@@ -85,17 +289,20 @@ func (t *trend) GetTwap(timeframe int) *decimal.Decimal {
 }
 
 func (t *trend) GetSMA(period int, timeframe int) *decimal.Decimal {
-	candles := t.GetCandles(timeframe)
-	if len(*candles) < period {
+	return t.sma(*t.GetCandles(timeframe), period)
+}
+
+func (t *trend) sma(candles []Candle, period int) *decimal.Decimal {
+	if len(candles) < period {
 		return nil
 	}
 
 	sum := decimal.Zero
-	for _, candle := range (*candles)[len(*candles)-period:] {
+	for _, candle := range candles[len(candles)-period:] {
 		sum = sum.Add(candle.Close)
 	}
 	sma := sum.Div(decimal.NewFromInt(int64(period)))
-	r := utils.MarketPrecision(sma, Markets.GetDecimals(t.market))
+	r := utils.MarketPrecision(sma, Markets.GetPriceDecimals(t.market))
 	return &r
 }
 
@@ -131,14 +338,14 @@ func (t *trend) GetRSI(period int, timeframe int) *decimal.Decimal {
 }
 
 func (t *trend) GetBB(period int, stdDev float64, timeframe int) (*decimal.Decimal, *decimal.Decimal, *decimal.Decimal) {
-	sma := t.GetSMA(period, timeframe)
+	candles := *t.GetCandles(timeframe)
+	sma := t.sma(candles, period)
 	if sma == nil {
 		return nil, nil, nil
 	}
 
-	candles := t.GetCandles(timeframe)
 	var sumSqDiff decimal.Decimal
-	for _, candle := range *candles {
+	for _, candle := range candles {
 		diff := candle.Close.Sub(*sma)
 		sumSqDiff = sumSqDiff.Add(diff.Mul(diff))
 	}
@@ -147,7 +354,7 @@ func (t *trend) GetBB(period int, stdDev float64, timeframe int) (*decimal.Decim
 
 	upperBand := sma.Add(stdDeviation.Mul(decimal.NewFromFloat(stdDev)))
 	lowerBand := sma.Sub(stdDeviation.Mul(decimal.NewFromFloat(stdDev)))
-	precision := Markets.GetDecimals(t.market)
+	precision := Markets.GetPriceDecimals(t.market)
 	r1, r2, r3 := utils.MarketPrecision(upperBand, precision), utils.MarketPrecision(lowerBand, precision), utils.MarketPrecision(*sma, precision)
 	return &r1, &r2, &r3
 }
@@ -157,7 +364,7 @@ func (t *trend) GetMACD(fastPeriod int, slowPeriod int, signalPeriod int, timefr
 	if len(candles) < slowPeriod {
 		return nil, nil
 	}
-	precision := Markets.GetDecimals(t.market)
+	precision := Markets.GetPriceDecimals(t.market)
 
 	emaFast := calculateEMA(candles, fastPeriod)
 	emaSlow := calculateEMA(candles, slowPeriod)
@@ -187,15 +394,18 @@ func calculateSignalLine(macd decimal.Decimal, period int) decimal.Decimal {
 }
 
 func (t *trend) GetCandle(position int, timeframe int) Candle {
-	if len(*t.GetCandles(timeframe)) >= position {
-		candles := *t.GetCandles(timeframe)
+	candles := *t.GetCandles(timeframe)
+	if position >= 0 && position < len(candles) {
 		return candles[position]
 	}
 	return Candle{}
 }
 
 func (t *trend) GetCandles(timeframe int) *[]Candle {
-	return t.timeframes[Timeframe(timeframe)]
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	candles := t.timeframes[Timeframe(timeframe)]
+	return &candles
 }
 
 func (t *trend) GetMarket() internal.Market {
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+)
+
+func trade(ts time.Time, price int64, volume string) entities.Trade {
+	return entities.Trade{Market: internal.XBTEUR, Price: decimal.NewFromInt(price), Volume: decimal.RequireFromString(volume), Timestamp: ts}
+}
+
+func TestCandleAggregator(t *testing.T) {
+	start := time.Unix(1700000040, 0)
+	aggregator := entities.NewCandleAggregator(time.Minute)
+	for _, tr := range []entities.Trade{
+		trade(start.Add(time.Second), 100, "1"),
+		trade(start.Add(10*time.Second), 104, "1"),
+		trade(start.Add(20*time.Second), 98, "2"),
+	} {
+		if closed := aggregator.Add(tr); len(closed) != 0 {
+			t.Errorf("unexpected closed candles %v", closed)
+		}
+	}
+	current, ok := aggregator.Current()
+	// (100 + 104 + 98 * 2) / 4
+	if !ok || !current.Open.Equal(decimal.NewFromInt(100)) || !current.High.Equal(decimal.NewFromInt(104)) || !current.Low.Equal(decimal.NewFromInt(98)) ||
+		!current.Close.Equal(decimal.NewFromInt(98)) || !current.Vwap.Equal(decimal.NewFromInt(100)) || current.Count != 3 || !current.Timestamp.Equal(start) {
+		t.Errorf("unexpected candle in progress %s", current.String())
+	}
+
+	// a trade three intervals later closes the candle and two empty intervals
+	closed := aggregator.Add(trade(start.Add(3*time.Minute+time.Second), 101, "0.5"))
+	if len(closed) != 3 || !closed[0].Volume.Equal(decimal.NewFromInt(4)) {
+		t.Fatalf("unexpected closed candles %v", closed)
+	}
+	if !closed[2].Timestamp.Equal(start.Add(2*time.Minute)) || !closed[2].Open.Equal(decimal.NewFromInt(98)) || !closed[2].Volume.IsZero() || closed[2].Count != 0 {
+		t.Errorf("unexpected flat candle %s", closed[2].String())
+	}
+	if closed := aggregator.Add(trade(start.Add(time.Minute), 90, "1")); closed != nil {
+		t.Errorf("expected trades of closed candles to be ignored")
+	}
+
+	// no candle closed before the interval ends
+	if closed := aggregator.Flush(start.Add(3*time.Minute + 59*time.Second)); len(closed) != 0 {
+		t.Errorf("unexpected closed candles %v", closed)
+	}
+	closed = aggregator.Flush(start.Add(5*time.Minute + time.Second))
+	if len(closed) != 2 || !closed[0].Close.Equal(decimal.NewFromInt(101)) || !closed[1].Timestamp.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("unexpected flushed candles %v", closed)
+	}
+	if _, ok := aggregator.Current(); ok {
+		t.Errorf("expected no candle in progress")
+	}
+	if closed := aggregator.Add(trade(start.Add(6*time.Minute), 102, "1")); len(closed) != 1 || !closed[0].Timestamp.Equal(start.Add(5*time.Minute)) {
+		t.Errorf("expected the empty interval before the trade to be filled, got %v", closed)
+	}
+}
+
+func TestAggregateRecordedTrades(t *testing.T) {
+	start := time.Unix(1700000040, 0)
+	candles := entities.AggregateTrades([]entities.Trade{
+		trade(start, 100, "1"),
+		trade(start.Add(4*time.Second), 101, "1"),
+		trade(start.Add(5*time.Second), 102, "1"),
+		trade(start.Add(12*time.Second), 103, "1"),
+	}, 5*time.Second)
+	if len(candles) != 3 || candles[0].Count != 2 || !candles[1].Close.Equal(decimal.NewFromInt(102)) || !candles[2].Timestamp.Equal(start.Add(10*time.Second)) {
+		t.Errorf("unexpected candles %v", candles)
+	}
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+)
+
+func level(price string, volume string) entities.BookLevel {
+	return entities.BookLevel{Price: decimal.RequireFromString(price), Volume: decimal.RequireFromString(volume)}
+}
+
+func testBook() *entities.OrderBook {
+	book := entities.NewOrderBook(internal.XBTEUR, 3)
+	book.UpdateBid(level("99", "1"))
+	book.UpdateBid(level("100", "2"))
+	book.UpdateBid(level("98", "3"))
+	book.UpdateAsk(level("102", "2"))
+	book.UpdateAsk(level("101", "1"))
+	book.UpdateAsk(level("103", "3"))
+	return book
+}
+
+func TestOrderBookUpdates(t *testing.T) {
+	book := testBook()
+	if bid, _ := book.BestBid(); !bid.Price.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("unexpected best bid %s", bid.Price)
+	}
+	if ask, _ := book.BestAsk(); !ask.Price.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("unexpected best ask %s", ask.Price)
+	}
+	if !book.GetSpread().Equal(decimal.NewFromInt(1)) || !book.GetMidPrice().Equal(decimal.RequireFromString("100.5")) {
+		t.Errorf("unexpected spread %s", book.GetSpread())
+	}
+
+	// replace, remove and insert beyond the depth
+	book.UpdateBid(level("100", "5"))
+	book.UpdateAsk(level("101", "0"))
+	book.UpdateBid(level("97", "1"))
+	if len(book.Bids) != 3 || !book.Bids[0].Volume.Equal(decimal.NewFromInt(5)) || !book.Bids[2].Price.Equal(decimal.NewFromInt(98)) {
+		t.Errorf("unexpected bids %v", book.Bids)
+	}
+	if len(book.Asks) != 2 || !book.Asks[0].Price.Equal(decimal.NewFromInt(102)) {
+		t.Errorf("unexpected asks %v", book.Asks)
+	}
+	// removing a missing level is a no-op
+	book.UpdateAsk(level("110", "0"))
+	if len(book.Asks) != 2 {
+		t.Errorf("unexpected asks %v", book.Asks)
+	}
+
+	empty := entities.NewOrderBook(internal.XBTEUR, 0)
+	if _, ok := empty.BestBid(); ok || !empty.GetSpread().IsZero() {
+		t.Errorf("expected an empty book")
+	}
+}
+
+func TestOrderBookLiquidity(t *testing.T) {
+	book := testBook()
+	// 100 bps from 101 is 102.01
+	if depth := book.GetDepth(internal.BUY, 100); !depth.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("unexpected ask depth %s", depth)
+	}
+	if depth := book.GetDepth(internal.SELL, 200); !depth.Equal(decimal.NewFromInt(6)) {
+		t.Errorf("unexpected bid depth %s", depth)
+	}
+
+	// 1 @ 101 and 1 @ 102
+	price, ok := book.GetFillPrice(internal.BUY, decimal.NewFromInt(2))
+	if !ok || !price.Equal(decimal.RequireFromString("101.5")) {
+		t.Errorf("unexpected fill price %s", price)
+	}
+	slippage, ok := book.GetSlippage(internal.SELL, decimal.NewFromInt(4))
+	// (2 * 100 + 1 * 99 + 1 * 98) / 4 = 99.25
+	if !ok || !slippage.Equal(decimal.RequireFromString("0.0075")) {
+		t.Errorf("unexpected slippage %s", slippage)
+	}
+	if _, ok := book.GetFillPrice(internal.BUY, decimal.NewFromInt(7)); ok {
+		t.Errorf("expected the book not to be deep enough")
+	}
+}
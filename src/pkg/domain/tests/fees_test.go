@@ -0,0 +1,64 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+)
+
+func feesMarkets() {
+	markets := entities.NewMarkets()
+	markets.SetMetadata(internal.XBTEUR, 8, internal.XBT, internal.EUR, decimal.Zero, decimal.Zero)
+	markets.SetFees(internal.XBTEUR, entities.Fees{
+		Maker:          decimal.RequireFromString("0.001"),
+		Taker:          decimal.RequireFromString("0.002"),
+		MarginOpen:     decimal.RequireFromString("0.0002"),
+		MarginRollover: decimal.RequireFromString("0.0002"),
+	})
+	entities.Markets = markets
+}
+
+func TestOrderFees(t *testing.T) {
+	feesMarkets()
+	market := &entities.Order{Market: internal.XBTEUR, Side: internal.BUY, Type: internal.MARGIN, PriceType: internal.MARKET, MarketPrice: decimal.NewFromInt(100), InitialVolume: decimal.NewFromInt(10)}
+	// taker and margin opening fee on a cost of 1000
+	if fee := market.GetEstimatedFee(); !fee.Equal(decimal.RequireFromString("2.2")) {
+		t.Errorf("unexpected fee %s", fee)
+	}
+	if cost := market.GetCostWithFees(); !cost.Equal(decimal.RequireFromString("1002.2")) {
+		t.Errorf("unexpected cost %s", cost)
+	}
+	limit := &entities.Order{Market: internal.XBTEUR, Side: internal.SELL, Type: internal.SPOT, PriceType: internal.LIMIT, MarketPrice: decimal.NewFromInt(100), InitialVolume: decimal.NewFromInt(10)}
+	if !limit.IsMaker() || !limit.GetCostWithFees().Equal(decimal.NewFromInt(999)) {
+		t.Errorf("unexpected proceeds %s", limit.GetCostWithFees())
+	}
+	if markets := entities.NewMarkets(); !markets.GetFees(internal.XBTEUR).Taker.Equal(entities.DEFAULT_FEES.Taker) {
+		t.Errorf("expected the default fees for unknown markets")
+	}
+}
+
+func TestPositionFees(t *testing.T) {
+	feesMarkets()
+	long := &entities.Position{Market: internal.XBTEUR, Side: internal.BUY, Size: decimal.NewFromInt(10), OpenPrice: decimal.NewFromInt(100), Leverage: 2, CreatedAt: time.Now().Add(-9 * time.Hour)}
+	// taker 2, margin opening 0.2 and two rollovers 0.4
+	if fees := long.GetFees(); !fees.Equal(decimal.RequireFromString("2.6")) {
+		t.Errorf("unexpected fees %s", fees)
+	}
+	// a gross profit of 3 is a loss after the closing fee
+	if long.IsProfitable(decimal.RequireFromString("100.3")) || !long.IsProfitable(decimal.NewFromInt(101)) {
+		t.Errorf("unexpected profitability")
+	}
+	breakEven := long.GetBreakEvenPrice()
+	if pnl := long.GetNetPnL(breakEven); pnl.Abs().GreaterThan(decimal.RequireFromString("0.000001")) {
+		t.Errorf("expected no pnl at the break even price %s, got %s", breakEven, pnl)
+	}
+
+	short := &entities.Position{Market: internal.XBTEUR, Side: internal.SELL, Size: decimal.NewFromInt(10), OpenPrice: decimal.NewFromInt(100), Fee: decimal.NewFromInt(1)}
+	breakEven = short.GetBreakEvenPrice()
+	if !breakEven.LessThan(decimal.NewFromInt(100)) || short.GetNetPnL(breakEven).Abs().GreaterThan(decimal.RequireFromString("0.000001")) {
+		t.Errorf("unexpected short break even price %s", breakEven)
+	}
+}
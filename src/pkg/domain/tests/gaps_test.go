@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+)
+
+func gapCandles(start time.Time, minutes ...int) []entities.Candle {
+	var candles []entities.Candle
+	for _, minute := range minutes {
+		price := decimal.NewFromInt(int64(100 + minute))
+		candles = append(candles, entities.NewCandle(price, price, price, price, start.Add(time.Duration(minute)*time.Minute)))
+	}
+	return candles
+}
+
+func TestFindAndFillGaps(t *testing.T) {
+	start := time.Unix(1700000040, 0)
+	candles := gapCandles(start, 0, 1, 4, 5, 7)
+	gaps := entities.FindGaps(candles, time.Minute)
+	if len(gaps) != 2 || !gaps[0].From.Equal(start.Add(2*time.Minute)) || !gaps[0].Until.Equal(start.Add(4*time.Minute)) || gaps[1].Missing(time.Minute) != 1 {
+		t.Fatalf("unexpected gaps %v", gaps)
+	}
+	filled := entities.FillGaps(candles, time.Minute)
+	if len(filled) != 8 || len(entities.FindGaps(filled, time.Minute)) != 0 {
+		t.Fatalf("unexpected filled candles %v", filled)
+	}
+	if flat := filled[2]; !flat.Open.Equal(candles[1].Close) || !flat.Close.Equal(candles[1].Close) || !flat.Volume.IsZero() {
+		t.Errorf("unexpected flat candle %v", flat)
+	}
+	if len(entities.FindGaps(nil, time.Minute)) != 0 || len(entities.FillGaps(nil, time.Minute)) != 0 {
+		t.Errorf("unexpected gaps of an empty series")
+	}
+}
+
+func TestTrailingGap(t *testing.T) {
+	start := time.Unix(1700000040, 0)
+	candles := gapCandles(start, 0, 1)
+	// the candle of the minute 2 may still be on its way
+	if _, ok := entities.TrailingGap(candles, time.Minute, start.Add(3*time.Minute+30*time.Second)); ok {
+		t.Errorf("unexpected trailing gap")
+	}
+	gap, ok := entities.TrailingGap(candles, time.Minute, start.Add(5*time.Minute+30*time.Second))
+	if !ok || !gap.From.Equal(start.Add(2*time.Minute)) || gap.Missing(time.Minute) != 2 {
+		t.Errorf("unexpected trailing gap %v", gap)
+	}
+	if _, ok := entities.TrailingGap(nil, time.Minute, start); ok {
+		t.Errorf("unexpected trailing gap of an empty series")
+	}
+}
+
+func TestTrendQualityAndMerge(t *testing.T) {
+	internal.InitConfig()
+	start := time.Unix(1700000040, 0)
+	trend := entities.InitTrend(internal.XBTEUR, 1)
+	for _, candle := range gapCandles(start, 0, 1, 4, 5) {
+		trend.Update(candle, 1)
+	}
+	quality := trend.GetQuality(1)
+	if quality.Complete() || quality.Candles != 4 || quality.Missing != 2 || len(quality.Gaps) != 1 {
+		t.Fatalf("unexpected quality %v", quality)
+	}
+	trend.Merge(gapCandles(start, 2, 3, 5), 1)
+	if quality := trend.GetQuality(1); !quality.Complete() || quality.Candles != 6 {
+		t.Errorf("unexpected quality after the merge %v", quality)
+	}
+	candles := *trend.GetCandles(1)
+	for i, candle := range candles {
+		if !candle.Timestamp.Equal(start.Add(time.Duration(i) * time.Minute)) {
+			t.Errorf("unexpected candle %d %v", i, candle)
+		}
+	}
+
+	var minutes []int
+	for minute := 10; minute < 70; minute++ {
+		minutes = append(minutes, minute)
+	}
+	trend.Merge(gapCandles(start, minutes...), 1)
+	if candles := *trend.GetCandles(1); len(candles) != internal.Config.OHLCSize || !candles[len(candles)-1].Timestamp.Equal(start.Add(69*time.Minute)) {
+		t.Errorf("expected the latest %d candles, got %d", internal.Config.OHLCSize, len(candles))
+	}
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+)
+
+func TestMarketsRounding(t *testing.T) {
+	markets := entities.NewMarkets()
+	markets.SetMetadata(internal.XBTEUR, 8, internal.XBT, internal.EUR, decimal.RequireFromString("0.5"), decimal.RequireFromString("0.0001"))
+	markets.SetPriceDecimals(internal.XBTEUR, 1)
+
+	// prices use the pair decimals until the tick size is known
+	if price := markets.RoundPrice(internal.XBTEUR, decimal.RequireFromString("30010.26")); !price.Equal(decimal.RequireFromString("30010.3")) {
+		t.Errorf("unexpected price %s", price)
+	}
+	markets.SetTickSize(internal.XBTEUR, decimal.RequireFromString("0.5"))
+	if price := markets.RoundPrice(internal.XBTEUR, decimal.RequireFromString("30010.26")); !price.Equal(decimal.RequireFromString("30010.5")) {
+		t.Errorf("unexpected price %s", price)
+	}
+	if volume := markets.RoundVolume(internal.XBTEUR, decimal.RequireFromString("0.123456789")); !volume.Equal(decimal.RequireFromString("0.12345678")) {
+		t.Errorf("unexpected volume %s", volume)
+	}
+	if markets.GetStatus(internal.XBTEUR) != internal.MARKET_ONLINE || markets.GetLotMultiplier(internal.XBTEUR) != 1 {
+		t.Errorf("unexpected defaults")
+	}
+	if volume := markets.RoundVolume(internal.ETHEUR, decimal.RequireFromString("0.123")); !volume.Equal(decimal.RequireFromString("0.123")) {
+		t.Errorf("expected markets not registered to be left unchanged, got %s", volume)
+	}
+}
+
+func TestMarketLotsAndStatus(t *testing.T) {
+	markets := entities.NewMarkets()
+	markets.SetMetadata("DOTEUR", 4, "DOT", internal.EUR, decimal.Zero, decimal.Zero)
+	markets.SetLotMultiplier("DOTEUR", 5)
+	if volume := markets.RoundVolume("DOTEUR", decimal.RequireFromString("1.23456")); !volume.Equal(decimal.RequireFromString("1.2345")) {
+		t.Errorf("unexpected volume %s", volume)
+	}
+	if volume := markets.RoundVolume("DOTEUR", decimal.RequireFromString("1.23449")); !volume.Equal(decimal.RequireFromString("1.234")) {
+		t.Errorf("unexpected volume %s", volume)
+	}
+
+	market := &entities.Order{Market: "DOTEUR", PriceType: internal.MARKET, Side: internal.BUY}
+	closing := &entities.Order{Market: "DOTEUR", PriceType: internal.MARKET, Side: internal.SELL, ReduceOnly: true}
+	limit := &entities.Order{Market: "DOTEUR", PriceType: internal.LIMIT, Side: internal.BUY}
+	postOnly := &entities.Order{Market: "DOTEUR", PriceType: internal.LIMIT, Side: internal.BUY, PostOnly: true}
+	for status, accepted := range map[internal.MarketStatus][]bool{
+		internal.MARKET_ONLINE:      {true, true, true, true},
+		internal.MARKET_REDUCE_ONLY: {false, true, false, false},
+		internal.MARKET_LIMIT_ONLY:  {false, false, true, true},
+		internal.MARKET_POST_ONLY:   {false, false, false, true},
+		internal.MARKET_CANCEL_ONLY: {false, false, false, false},
+		internal.MARKET_OFFLINE:     {false, false, false, false},
+	} {
+		markets.SetStatus("DOTEUR", status)
+		for i, order := range []*entities.Order{market, closing, limit, postOnly} {
+			if err := markets.CheckStatus(order); (err == nil) != accepted[i] {
+				t.Errorf("%s: unexpected result %v for order %d", status, err, i)
+			}
+		}
+	}
+}
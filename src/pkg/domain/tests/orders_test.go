@@ -0,0 +1,31 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+)
+
+func TestOrdersTracking(t *testing.T) {
+	tracked := entities.NewOrders()
+	order := &entities.Order{Id: "2c8e1c2d-6b3a-4c55-8f1a-2a6f1b0c9d11", Market: internal.XBTEUR, Status: internal.CREATED}
+	tracked.Add(order)
+
+	tracked.Update(entities.OrderUpdate{Id: order.Id, RemoteId: "OABCDE-12345-XYZAB", Status: internal.OPEN})
+	if len(tracked.InFlight(internal.XBTEUR)) != 1 || len(tracked.InFlight(internal.ETHEUR)) != 0 {
+		t.Errorf("expected the order in flight on XBTEUR only")
+	}
+	// updates carrying only the exchange id are matched as well
+	updated := tracked.Update(entities.OrderUpdate{RemoteId: "OABCDE-12345-XYZAB", Status: internal.FILLED, ExecutedVolume: decimal.NewFromInt(1)})
+	if updated != order || order.Status != internal.FILLED || !order.ExecutedVolume.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("unexpected order %+v", order)
+	}
+	if len(tracked.InFlight(internal.XBTEUR)) != 0 {
+		t.Errorf("expected filled orders to be untracked")
+	}
+	if tracked.Update(entities.OrderUpdate{Id: "unknown", Status: internal.FILLED}) != nil {
+		t.Errorf("expected nil for unknown orders")
+	}
+}
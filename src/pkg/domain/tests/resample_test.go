@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+)
+
+func hourCandles(start time.Time, n int) []entities.Candle {
+	var candles []entities.Candle
+	for i := 0; i < n; i++ {
+		price := decimal.NewFromInt(int64(100 + i))
+		candle := entities.NewCandle(price, price.Add(decimal.NewFromInt(1)), price.Sub(decimal.NewFromInt(1)), price, start.Add(time.Duration(i)*time.Hour))
+		candles = append(candles, candle.WithVolume(decimal.NewFromInt(1), price, 1))
+	}
+	return candles
+}
+
+func TestResampleUTCBoundaries(t *testing.T) {
+	// 22:00 UTC, the day and the 4h intervals start at midnight
+	start := time.Date(2024, 3, 1, 22, 0, 0, 0, time.UTC)
+	candles := hourCandles(start, 28)
+	// the first day is incomplete, the last one is in progress
+	daily := entities.Resample(candles, time.Hour, 24*time.Hour)
+	if len(daily) != 2 || !daily[0].Timestamp.Equal(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected daily candles %v", daily)
+	}
+	day := daily[0]
+	if !day.Open.Equal(decimal.NewFromInt(102)) || !day.Close.Equal(decimal.NewFromInt(125)) || !day.High.Equal(decimal.NewFromInt(126)) ||
+		!day.Low.Equal(decimal.NewFromInt(101)) || !day.Volume.Equal(decimal.NewFromInt(24)) || day.Count != 24 || !day.Vwap.Equal(decimal.RequireFromString("113.5")) {
+		t.Errorf("unexpected daily candle %s", day.String())
+	}
+	fourHours := entities.Resample(candles, time.Hour, 4*time.Hour)
+	for _, candle := range fourHours {
+		if candle.Timestamp.Hour()%4 != 0 {
+			t.Errorf("4h candle not aligned %s", candle.Timestamp)
+		}
+	}
+	if len(fourHours) != 7 || !fourHours[0].Timestamp.Equal(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected 4h candles %d", len(fourHours))
+	}
+}
+
+func TestCandleResampler(t *testing.T) {
+	start := time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC)
+	candles := hourCandles(start, 12)
+	resampler := entities.NewCandleResampler(time.Hour, 4*time.Hour)
+	var closed []entities.Candle
+	for _, candle := range candles[:7] {
+		if candle, ok := resampler.Add(candle); ok {
+			closed = append(closed, candle)
+		}
+	}
+	// the interval started at midnight is skipped, the 04:00
+	// one is closed by its last hour without waiting for 08:00
+	if len(closed) != 1 || !closed[0].Timestamp.Equal(start.Add(3*time.Hour)) || !closed[0].Close.Equal(decimal.NewFromInt(106)) {
+		t.Fatalf("unexpected closed candles %v", closed)
+	}
+	if again, ok := resampler.Add(candles[6]); ok {
+		t.Errorf("candle added twice %v", again)
+	}
+
+	resampler.Add(candles[7])
+	forming, ok := resampler.Forming(candles[8].WithForming(true))
+	if !ok || !forming.Forming || !forming.Timestamp.Equal(start.Add(7*time.Hour)) || !forming.Open.Equal(decimal.NewFromInt(107)) ||
+		!forming.Close.Equal(decimal.NewFromInt(108)) || !forming.Volume.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("unexpected candle in progress %v", forming)
+	}
+
+	// the last hours of the interval are missing
+	if candle, ok := resampler.Add(candles[11]); ok {
+		t.Errorf("expected the incomplete candle to be dropped, got %v", candle)
+	}
+}
+
+func TestCandleResamplerDropsIncompleteIntervals(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	candles := hourCandles(start, 12)
+	resampler := entities.NewCandleResampler(time.Hour, 4*time.Hour)
+	// the first hour of the interval is missing
+	if _, ok := resampler.Forming(candles[1].WithForming(true)); ok {
+		t.Errorf("expected no candle in progress without the first hour")
+	}
+	var closed []entities.Candle
+	for i, candle := range candles {
+		// an hour is missing in the middle of the 04:00 interval
+		if i == 1 || i == 6 {
+			continue
+		}
+		if candle, ok := resampler.Add(candle); ok {
+			closed = append(closed, candle)
+		}
+	}
+	if len(closed) != 1 || !closed[0].Timestamp.Equal(start.Add(8*time.Hour)) || !closed[0].Open.Equal(decimal.NewFromInt(108)) || closed[0].Count != 4 {
+		t.Errorf("expected only the complete 08:00 candle, got %v", closed)
+	}
+}
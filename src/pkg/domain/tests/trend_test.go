@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -22,6 +23,7 @@ func TestSMA(t *testing.T) {
 		decimal.RequireFromString("0.01"),
 		decimal.RequireFromString("0.00000001"),
 	)
+	markets.SetPriceDecimals(internal.XBTEUR, 8)
 	entities.Markets = markets
 	trend := entities.InitTrend(internal.XBTEUR)
 	candles := []entities.Candle{
@@ -59,6 +61,7 @@ func TestRSI(t *testing.T) {
 		decimal.RequireFromString("0.01"),
 		decimal.RequireFromString("0.00000001"),
 	)
+	markets.SetPriceDecimals(internal.XBTEUR, 8)
 	entities.Markets = markets
 	trend := entities.InitTrend(internal.XBTEUR)
 	candles := []entities.Candle{
@@ -94,6 +97,7 @@ func TestBollingerBands(t *testing.T) {
 		decimal.RequireFromString("0.01"),
 		decimal.RequireFromString("0.00000001"),
 	)
+	markets.SetPriceDecimals(internal.XBTEUR, 8)
 	entities.Markets = markets
 	trend := entities.InitTrend(internal.XBTEUR)
 	candles := []entities.Candle{
@@ -126,3 +130,160 @@ func TestBollingerBands(t *testing.T) {
 		t.Errorf("Lower Bollinger Band calculation error. Expected: %s, Got: %s", expectedLower.String(), lower.String())
 	}
 }
+
+func TestTrendSize(t *testing.T) {
+	internal.InitConfig()
+	trend := entities.InitTrend(internal.XBTEUR)
+	trend.SetSize(60, internal.Config.OHLCSize+10)
+	for i := 0; i < internal.Config.OHLCSize+20; i++ {
+		trend.Update(entities.Candle{Close: decimal.NewFromInt(int64(i))}, 60)
+		trend.Update(entities.Candle{Close: decimal.NewFromInt(int64(i))}, 1)
+	}
+	if candles := *trend.GetCandles(60); len(candles) != internal.Config.OHLCSize+10 || !candles[0].Close.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("unexpected 60m candles %d", len(candles))
+	}
+	if candles := *trend.GetCandles(1); len(candles) != internal.Config.OHLCSize {
+		t.Errorf("unexpected 1m candles %d", len(candles))
+	}
+}
+
+func TestTrendTimeframes(t *testing.T) {
+	internal.InitConfig()
+	trend := entities.InitTrend(internal.XBTEUR, 240, 1440)
+	if timeframes := trend.Timeframes(); len(timeframes) != 2 || timeframes[0] != 240 || timeframes[1] != 1440 {
+		t.Errorf("unexpected timeframes %v", timeframes)
+	}
+	if candles := trend.GetCandles(30); candles == nil || len(*candles) != 0 {
+		t.Errorf("expected no 30m candles, got %v", candles)
+	}
+	trend.Update(entities.Candle{Close: decimal.NewFromInt(1)}, 30)
+	trend.Update(entities.Candle{Close: decimal.NewFromInt(1)}, int(entities.TimeframeOf(30*time.Second)))
+	if timeframes := trend.Timeframes(); len(timeframes) != 4 || timeframes[0] != -30 || timeframes[1] != 30 {
+		t.Errorf("unexpected timeframes %v", timeframes)
+	}
+	if len(*trend.GetCandles(30)) != 1 || trend.GetSMA(2, 10080) != nil {
+		t.Errorf("unexpected candles")
+	}
+	if defaults := entities.InitTrend(internal.XBTEUR).Timeframes(); len(defaults) != 4 || defaults[3] != 60 {
+		t.Errorf("unexpected default timeframes %v", defaults)
+	}
+}
+
+func TestParseTimeframe(t *testing.T) {
+	for value, expected := range map[string]entities.Timeframe{
+		"15":  entities.TIMEFRAME_15M,
+		"30m": entities.TIMEFRAME_30M,
+		"4h":  entities.TIMEFRAME_4H,
+		"1d":  entities.TIMEFRAME_1D,
+		"1w":  entities.TIMEFRAME_1W,
+		"15d": entities.TIMEFRAME_15D,
+		"30s": -30,
+		"90s": -90,
+		"2m":  2,
+	} {
+		timeframe, err := entities.ParseTimeframe(value)
+		if err != nil || timeframe != expected {
+			t.Errorf("unexpected timeframe %v (%v) for %s", timeframe, err, value)
+		}
+	}
+	for _, value := range []string{"", "0", "-5", "4x", "h"} {
+		if _, err := entities.ParseTimeframe(value); err == nil {
+			t.Errorf("expected an error for %q", value)
+		}
+	}
+	if entities.TIMEFRAME_4H.String() != "4h" || entities.Timeframe(-30).String() != "30s" || entities.TIMEFRAME_1W.Duration() != 7*24*time.Hour {
+		t.Errorf("unexpected timeframe formatting")
+	}
+}
+
+func TestTrendSnapshot(t *testing.T) {
+	internal.InitConfig()
+	trend := entities.InitTrend(internal.XBTEUR, 1)
+	trend.Update(entities.Candle{Close: decimal.NewFromInt(1)}, 1)
+	snapshot := trend.Snapshot()
+	candles := trend.GetCandles(1)
+	for i := 2; i < internal.Config.OHLCSize+10; i++ {
+		trend.Update(entities.Candle{Close: decimal.NewFromInt(int64(i))}, 1)
+	}
+	trend.Update(entities.Candle{Close: decimal.NewFromInt(1)}, 5)
+	if len(*candles) != 1 || !(*candles)[0].Close.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("returned candles changed by the updates %v", *candles)
+	}
+	if len(*snapshot.GetCandles(1)) != 1 || len(snapshot.Timeframes()) != 1 {
+		t.Errorf("snapshot changed by the updates")
+	}
+	if len(*trend.GetCandles(1)) != internal.Config.OHLCSize {
+		t.Errorf("unexpected candles %d", len(*trend.GetCandles(1)))
+	}
+}
+
+func TestTrendConcurrentUpdates(t *testing.T) {
+	internal.InitConfig()
+	previous := entities.Markets
+	t.Cleanup(func() { entities.Markets = previous })
+	markets := entities.NewMarkets()
+	markets.SetMetadata(internal.XBTEUR, 8, internal.XBT, internal.EUR, decimal.Zero, decimal.Zero)
+	markets.SetPriceDecimals(internal.XBTEUR, 2)
+	entities.Markets = markets
+	trend := entities.InitTrend(internal.XBTEUR, 1, 5, 15)
+	var wg sync.WaitGroup
+	for _, timeframe := range []int{1, 5, 15, 60} {
+		wg.Add(1)
+		go func(timeframe int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				trend.Update(entities.Candle{Close: decimal.NewFromInt(int64(100 + i%7)), Timestamp: time.Now()}, timeframe)
+			}
+		}(timeframe)
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				snapshot := trend.Snapshot()
+				for _, timeframe := range snapshot.Timeframes() {
+					candles := *snapshot.GetCandles(timeframe)
+					for j := 1; j < len(candles); j++ {
+						// candles are appended in order
+						if candles[j].Timestamp.Before(candles[j-1].Timestamp) {
+							t.Errorf("inconsistent %d candles", timeframe)
+						}
+					}
+					snapshot.GetSMA(10, timeframe)
+					snapshot.GetRSI(14, timeframe)
+					snapshot.GetBB(20, 2, timeframe)
+					snapshot.GetMACD(12, 26, 9, timeframe)
+					trend.GetCandle(len(candles)-1, timeframe)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, timeframe := range []int{1, 5, 15, 60} {
+		if len(*trend.GetCandles(timeframe)) != internal.Config.OHLCSize {
+			t.Errorf("unexpected %d candles %d", timeframe, len(*trend.GetCandles(timeframe)))
+		}
+	}
+}
+
+func TestTrendForming(t *testing.T) {
+	internal.InitConfig()
+	trend := entities.InitTrend(internal.XBTEUR, 1)
+	start := time.Unix(1700000000, 0)
+	trend.SetForming(entities.Candle{Close: decimal.NewFromInt(1), Timestamp: start}, 1)
+	trend.SetForming(entities.Candle{Close: decimal.NewFromInt(2), Timestamp: start}, 1)
+	if current, ok := trend.GetForming(1); !ok || !current.Forming || !current.Close.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("unexpected candle in progress %v", current)
+	}
+	if len(*trend.GetCandles(1)) != 0 {
+		t.Errorf("the candle in progress was added to the candles")
+	}
+	trend.Update(entities.Candle{Close: decimal.NewFromInt(3), Timestamp: start, Forming: true}, 1)
+	if _, ok := trend.GetForming(1); ok {
+		t.Errorf("expected the candle in progress dropped once closed")
+	}
+	if candles := *trend.GetCandles(1); len(candles) != 1 || candles[0].Forming {
+		t.Errorf("unexpected closed candles %v", candles)
+	}
+}
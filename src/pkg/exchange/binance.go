@@ -0,0 +1,722 @@
+package exchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/utils"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const BINANCE_API_URL = "https://api.binance.com"
+
+const BINANCE_RECV_WINDOW = 5000
+
+// spot fees of the base tier
+var BINANCE_FEES = entities.Fees{
+	Maker: decimal.RequireFromString("0.001"),
+	Taker: decimal.RequireFromString("0.001"),
+}
+
+type BinanceOrderStatus string
+
+const (
+	BINANCE_STATUS_NEW              BinanceOrderStatus = "NEW"
+	BINANCE_STATUS_PARTIALLY_FILLED BinanceOrderStatus = "PARTIALLY_FILLED"
+	BINANCE_STATUS_FILLED           BinanceOrderStatus = "FILLED"
+	BINANCE_STATUS_CANCELED         BinanceOrderStatus = "CANCELED"
+	BINANCE_STATUS_PENDING_CANCEL   BinanceOrderStatus = "PENDING_CANCEL"
+	BINANCE_STATUS_REJECTED         BinanceOrderStatus = "REJECTED"
+	BINANCE_STATUS_EXPIRED          BinanceOrderStatus = "EXPIRED"
+	BINANCE_STATUS_EXPIRED_IN_MATCH BinanceOrderStatus = "EXPIRED_IN_MATCH"
+)
+
+type binanceError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+type binanceFilter struct {
+	FilterType  string          `json:"filterType"`
+	MinQty      decimal.Decimal `json:"minQty"`
+	StepSize    decimal.Decimal `json:"stepSize"`
+	TickSize    decimal.Decimal `json:"tickSize"`
+	MinNotional decimal.Decimal `json:"minNotional"`
+}
+
+type binanceSymbol struct {
+	Symbol     string          `json:"symbol"`
+	Status     string          `json:"status"`
+	BaseAsset  string          `json:"baseAsset"`
+	QuoteAsset string          `json:"quoteAsset"`
+	Filters    []binanceFilter `json:"filters"`
+}
+
+type binanceExchangeInfo struct {
+	Symbols []binanceSymbol `json:"symbols"`
+}
+
+type binanceAccount struct {
+	Balances []struct {
+		Asset  string          `json:"asset"`
+		Free   decimal.Decimal `json:"free"`
+		Locked decimal.Decimal `json:"locked"`
+	} `json:"balances"`
+}
+
+type binanceOrder struct {
+	Symbol              string          `json:"symbol"`
+	OrderId             int64           `json:"orderId"`
+	ClientOrderId       string          `json:"clientOrderId"`
+	Price               decimal.Decimal `json:"price"`
+	OrigQty             decimal.Decimal `json:"origQty"`
+	ExecutedQty         decimal.Decimal `json:"executedQty"`
+	CummulativeQuoteQty decimal.Decimal `json:"cummulativeQuoteQty"`
+	Status              string          `json:"status"`
+	Type                string          `json:"type"`
+	Side                string          `json:"side"`
+	Time                int64           `json:"time"`
+	TransactTime        int64           `json:"transactTime"`
+	UpdateTime          int64           `json:"updateTime"`
+}
+
+// binance spot implementation of IExchange. spot accounts
+// have no margin, so positions are never reported and the
+// leverage is always 1
+type binanceCli struct {
+	apiKey     string
+	secret     string
+	httpClient *http.Client
+	mu         sync.Mutex
+	// markets retrieved with GetMarketsData, binance requires
+	// the symbol to look up or cancel orders
+	markets  []internal.Market
+	symbols  map[internal.Market]string
+	bySymbol map[string]internal.Market
+	assets   map[string]internal.Currency
+}
+
+// requests not answered within the timeout are abandoned
+func NewBinanceCli(apiKey string, secret string, timeout time.Duration) IExchange {
+	return NewBinanceCliWithClient(apiKey, secret, &http.Client{Timeout: timeout})
+}
+
+// creates the client using the given http client for every request
+func NewBinanceCliWithClient(apiKey string, secret string, httpClient *http.Client) IExchange {
+	return &binanceCli{apiKey: apiKey, secret: secret, httpClient: httpClient}
+}
+
+func (c *binanceCli) GetLeverage(market internal.Market) decimal.Decimal {
+	return decimal.NewFromInt(1)
+}
+
+// returns a list of candles for the given interval and pair
+func (c *binanceCli) GetOHLC(pair internal.Market, interval int) ([]entities.Candle, error) {
+	binanceInterval, err := BinanceInterval(interval)
+	if err != nil {
+		return []entities.Candle{}, err
+	}
+	symbol, err := c.symbol(pair)
+	if err != nil {
+		return []entities.Candle{}, err
+	}
+	var resp [][]interface{}
+	err = c.request(http.MethodGet, "/api/v3/klines", url.Values{
+		"symbol":   {symbol},
+		"interval": {binanceInterval},
+		"limit":    {"1000"},
+	}, false, &resp)
+	if err != nil {
+		return []entities.Candle{}, err
+	}
+	var res []entities.Candle
+	for _, kline := range resp {
+		if len(kline) < 9 {
+			return []entities.Candle{}, fmt.Errorf("unexpected kline length %d", len(kline))
+		}
+		openTime, ok := kline[0].(float64)
+		if !ok {
+			return []entities.Candle{}, fmt.Errorf("unexpected kline open time %v", kline[0])
+		}
+		count, ok := kline[8].(float64)
+		if !ok {
+			return []entities.Candle{}, fmt.Errorf("unexpected kline trades count %v", kline[8])
+		}
+		values, err := wsStrings(append(kline[1:6:6], kline[7]))
+		if err != nil {
+			return []entities.Candle{}, err
+		}
+		prices, err := parseDecimals(values)
+		if err != nil {
+			return []entities.Candle{}, err
+		}
+		// the vwap is the quote volume over the base volume
+		vwap := decimal.Zero
+		if prices[4].IsPositive() {
+			vwap = prices[5].Div(prices[4])
+		}
+		candle := entities.NewCandle(prices[0], prices[1], prices[2], prices[3], time.UnixMilli(int64(openTime)))
+		res = append(res, candle.WithVolume(prices[4], vwap, int(count)))
+	}
+	return res, nil
+}
+
+// maps the exchangeInfo filters into the markets metadata:
+// LOT_SIZE gives the order minimum and the volume precision,
+// PRICE_FILTER the tick size and (MIN_)NOTIONAL the min cost.
+// the markets are named after the base and quote assets,
+// so every symbol is retrieved to find the requested ones
+func (c *binanceCli) GetMarketsData(markets []internal.Market) (entities.IMarkets, error) {
+	var resp binanceExchangeInfo
+	err := c.request(http.MethodGet, "/api/v3/exchangeInfo", url.Values{}, false, &resp)
+	if err != nil {
+		return entities.NewMarkets(), err
+	}
+	infos := map[internal.Market]binanceSymbol{}
+	for _, info := range resp.Symbols {
+		infos[internal.Market(IBinanceAsset(info.BaseAsset)+IBinanceAsset(info.QuoteAsset))] = info
+	}
+	result := entities.NewMarkets()
+	symbols := map[internal.Market]string{}
+	bySymbol := map[string]internal.Market{}
+	assets := map[string]internal.Currency{}
+	for _, market := range markets {
+		info, ok := infos[market]
+		if !ok {
+			return entities.NewMarkets(), fmt.Errorf("unknown binance market %s", market)
+		}
+		symbols[market] = info.Symbol
+		bySymbol[info.Symbol] = market
+		assets[info.BaseAsset] = IBinanceAsset(info.BaseAsset)
+		assets[info.QuoteAsset] = IBinanceAsset(info.QuoteAsset)
+		var decimals int
+		var orderMin, minCost, tickSize decimal.Decimal
+		for _, filter := range info.Filters {
+			switch filter.FilterType {
+			case "LOT_SIZE":
+				orderMin = filter.MinQty
+				decimals = utils.Precision(filter.StepSize)
+			case "PRICE_FILTER":
+				tickSize = filter.TickSize
+			case "MIN_NOTIONAL", "NOTIONAL":
+				minCost = filter.MinNotional
+			}
+		}
+		result.SetMetadata(
+			market,
+			decimals,
+			IBinanceAsset(info.BaseAsset),
+			IBinanceAsset(info.QuoteAsset),
+			minCost,
+			orderMin,
+		)
+		result.SetTickSize(market, tickSize)
+		result.SetPriceDecimals(market, utils.Precision(tickSize))
+		result.SetStatus(market, IBinanceSymbolStatus(info.Status))
+		result.SetFees(market, BINANCE_FEES)
+	}
+	c.mu.Lock()
+	c.markets = markets
+	c.symbols = symbols
+	c.bySymbol = bySymbol
+	c.assets = assets
+	c.mu.Unlock()
+	return result, nil
+}
+
+// returns the binance symbol of a market retrieved with GetMarketsData
+func (c *binanceCli) symbol(market internal.Market) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if symbol, ok := c.symbols[market]; ok {
+		return symbol, nil
+	}
+	return "", fmt.Errorf("unknown binance market %s", market)
+}
+
+// returns the market of a symbol retrieved with GetMarketsData
+func (c *binanceCli) market(symbol string) (internal.Market, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if market, ok := c.bySymbol[symbol]; ok {
+		return market, nil
+	}
+	return "", fmt.Errorf("unknown binance symbol %s", symbol)
+}
+
+// returns the free amount of the currencies of
+// the markets retrieved with GetMarketsData
+func (c *binanceCli) GetBalance() (*entities.Balance, error) {
+	var resp binanceAccount
+	if err := c.request(http.MethodGet, "/api/v3/account", url.Values{}, true, &resp); err != nil {
+		return &entities.Balance{}, err
+	}
+	c.mu.Lock()
+	assets := c.assets
+	c.mu.Unlock()
+	currencies := map[internal.Currency]decimal.Decimal{}
+	for _, balance := range resp.Balances {
+		currency, ok := assets[balance.Asset]
+		if !ok {
+			continue
+		}
+		currencies[currency] = balance.Free
+	}
+	return &entities.Balance{Currencies: currencies}, nil
+}
+
+func (c *binanceCli) GetOpenPositions(market internal.Market) ([]*entities.Position, error) {
+	return []*entities.Position{}, nil
+}
+
+// place order on binance, the order id is sent
+// as client order id
+func (c *binanceCli) PlaceOrder(order *entities.Order) (*entities.OrderAck, error) {
+	symbol, err := c.symbol(order.Market)
+	if err != nil {
+		return nil, err
+	}
+	volume, price := roundOrder(order)
+	params := url.Values{
+		"symbol":           {symbol},
+		"side":             {BinanceSide(order.Side)},
+		"type":             {BinanceType(order.PriceType)},
+		"quantity":         {volume.String()},
+		"newClientOrderId": {order.Id},
+		"newOrderRespType": {"RESULT"},
+	}
+	if order.PriceType == internal.LIMIT {
+		params.Set("price", price.String())
+		params.Set("timeInForce", "GTC")
+		if order.Ioc {
+			params.Set("timeInForce", "IOC")
+		}
+	}
+	var resp binanceOrder
+	if err := c.request(http.MethodPost, "/api/v3/order", params, true, &resp); err != nil {
+		return nil, err
+	}
+	// the order was accepted, an unknown status is reconciled later
+	status, ok := IBinanceStatus(BinanceOrderStatus(resp.Status))
+	if !ok {
+		status = internal.CREATED
+	}
+	return &entities.OrderAck{
+		Id:          order.Id,
+		RemoteIds:   []string{binanceRemoteId(resp.Symbol, resp.OrderId)},
+		Description: fmt.Sprintf("%s %s %s %s", resp.Side, resp.OrigQty, resp.Symbol, resp.Type),
+		Status:      status,
+	}, nil
+}
+
+// gets an order given the client id, looking
+// into every market retrieved with GetMarketsData
+func (c *binanceCli) GetOrder(id string) (*entities.Order, error) {
+	c.mu.Lock()
+	markets := c.markets
+	symbols := c.symbols
+	c.mu.Unlock()
+	for _, market := range markets {
+		var resp binanceOrder
+		err := c.request(http.MethodGet, "/api/v3/order", url.Values{
+			"symbol":            {symbols[market]},
+			"origClientOrderId": {id},
+		}, true, &resp)
+		if isBinanceError(err, -2013) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return fromBinanceOrder(market, resp)
+	}
+	return nil, fmt.Errorf("%w: client id %s", ErrOrderNotFound, id)
+}
+
+// gets an order given the remote id (symbol-orderId)
+func (c *binanceCli) GetRemoteOrder(remoteId string) (*entities.Order, error) {
+	symbol, orderId, err := parseBinanceRemoteId(remoteId)
+	if err != nil {
+		return nil, err
+	}
+	market, err := c.market(symbol)
+	if err != nil {
+		return nil, err
+	}
+	var resp binanceOrder
+	err = c.request(http.MethodGet, "/api/v3/order", url.Values{
+		"symbol":  {symbol},
+		"orderId": {orderId},
+	}, true, &resp)
+	if isBinanceError(err, -2013) {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, remoteId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fromBinanceOrder(market, resp)
+}
+
+func (c *binanceCli) GetOpenOrders(market internal.Market) ([]*entities.Order, error) {
+	symbol, err := c.symbol(market)
+	if err != nil {
+		return nil, err
+	}
+	var resp []binanceOrder
+	err = c.request(http.MethodGet, "/api/v3/openOrders", url.Values{
+		"symbol": {symbol},
+	}, true, &resp)
+	if err != nil {
+		return nil, err
+	}
+	var res []*entities.Order
+	for _, order := range resp {
+		converted, err := fromBinanceOrder(market, order)
+		if err != nil {
+			logrus.Warnf("skipping order %s: %v", binanceRemoteId(order.Symbol, order.OrderId), err)
+			continue
+		}
+		res = append(res, converted)
+	}
+	return res, nil
+}
+
+func (c *binanceCli) GetClosedOrders(market internal.Market, since time.Time) ([]*entities.Order, error) {
+	symbol, err := c.symbol(market)
+	if err != nil {
+		return nil, err
+	}
+	var resp []binanceOrder
+	err = c.request(http.MethodGet, "/api/v3/allOrders", url.Values{
+		"symbol":    {symbol},
+		"startTime": {fmt.Sprintf("%d", since.UnixMilli())},
+	}, true, &resp)
+	if err != nil {
+		return nil, err
+	}
+	var res []*entities.Order
+	for _, order := range resp {
+		converted, err := fromBinanceOrder(market, order)
+		if err != nil {
+			logrus.Warnf("skipping order %s: %v", binanceRemoteId(order.Symbol, order.OrderId), err)
+			continue
+		}
+		if converted.IsFinal() {
+			res = append(res, converted)
+		}
+	}
+	return res, nil
+}
+
+func (c *binanceCli) CancelOrder(remoteId string) error {
+	symbol, orderId, err := parseBinanceRemoteId(remoteId)
+	if err != nil {
+		return err
+	}
+	var resp binanceOrder
+	err = c.request(http.MethodDelete, "/api/v3/order", url.Values{
+		"symbol":  {symbol},
+		"orderId": {orderId},
+	}, true, &resp)
+	if isBinanceError(err, -2011) {
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, remoteId)
+	}
+	return err
+}
+
+// cancels the open orders of every market
+// retrieved with GetMarketsData
+func (c *binanceCli) CancelAll() (int, error) {
+	c.mu.Lock()
+	markets := c.markets
+	symbols := c.symbols
+	c.mu.Unlock()
+	count := 0
+	for _, market := range markets {
+		var resp []binanceOrder
+		err := c.request(http.MethodDelete, "/api/v3/openOrders", url.Values{
+			"symbol": {symbols[market]},
+		}, true, &resp)
+		// -2011 is returned when there is no order to cancel
+		if isBinanceError(err, -2011) {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		count += len(resp)
+	}
+	return count, nil
+}
+
+// binance spot has no dead man's switch
+func (c *binanceCli) CancelAllAfter(timeout time.Duration) (time.Time, error) {
+	return time.Time{}, ErrNotSupported
+}
+
+// sends the request to binance, signing it when required
+// with the hmac sha256 of the query string
+func (c *binanceCli) request(method string, path string, params url.Values, signed bool, response interface{}) error {
+	if signed {
+		params.Set("timestamp", fmt.Sprintf("%d", time.Now().UnixMilli()))
+		params.Set("recvWindow", fmt.Sprintf("%d", BINANCE_RECV_WINDOW))
+	}
+	// the signature must be the last parameter of the signed query string
+	query := params.Encode()
+	if signed {
+		mac := hmac.New(sha256.New, []byte(c.secret))
+		mac.Write([]byte(query))
+		query += "&signature=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	req, err := http.NewRequest(method, BINANCE_API_URL+path+"?"+query, nil)
+	if err != nil {
+		return err
+	}
+	if signed {
+		req.Header.Set("X-MBX-APIKEY", c.apiKey)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return networkError(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return networkError(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var binanceErr binanceError
+		if json.Unmarshal(body, &binanceErr) == nil && binanceErr.Code != 0 {
+			return BinanceError(resp.StatusCode, &binanceErr)
+		}
+		return BinanceError(resp.StatusCode, fmt.Errorf("binance returned status %d: %s", resp.StatusCode, body))
+	}
+	logrus.Tracef("[BINANCE] %s %s: %s", method, path, body)
+	return json.Unmarshal(body, response)
+}
+
+func (e *binanceError) Error() string {
+	return fmt.Sprintf("binance error %d: %s", e.Code, e.Msg)
+}
+
+func isBinanceError(err error, code int) bool {
+	var binanceErr *binanceError
+	return errors.As(err, &binanceErr) && binanceErr.Code == code
+}
+
+// binance error codes not classified by the http status
+var binanceErrorKinds = map[int]ErrorKind{
+	-1000: ERROR_RETRYABLE,    // unknown error
+	-1001: ERROR_RETRYABLE,    // disconnected
+	-1003: ERROR_RATE_LIMITED, // too many requests
+	-1007: ERROR_RETRYABLE,    // timeout waiting for the backend
+	-1016: ERROR_UNAVAILABLE,  // service shutting down
+	-1021: ERROR_RETRYABLE,    // timestamp outside of the recv window
+	-1022: ERROR_AUTH,         // invalid signature
+	-2014: ERROR_AUTH,         // invalid api key format
+	-2015: ERROR_AUTH,         // invalid api key, ip or permissions
+}
+
+// classifies the error returned by binance given the http status
+// and the binance error code, if any
+func BinanceError(status int, err error) error {
+	kind := ERROR_REJECTED
+	code := ""
+	var binanceErr *binanceError
+	if errors.As(err, &binanceErr) {
+		code = fmt.Sprintf("%d", binanceErr.Code)
+		if known, ok := binanceErrorKinds[binanceErr.Code]; ok {
+			kind = known
+		}
+	}
+	switch {
+	case status == http.StatusTooManyRequests, status == http.StatusTeapot:
+		kind = ERROR_RATE_LIMITED
+	case status == http.StatusUnauthorized, status == http.StatusForbidden && code == "":
+		kind = ERROR_AUTH
+	case status >= http.StatusInternalServerError && code == "":
+		// the outcome of 5xx responses is unknown
+		kind = ERROR_RETRYABLE
+	}
+	return &ExchangeError{
+		Kind:    kind,
+		Code:    code,
+		Message: err.Error(),
+		// timestamps outside of the window are refused before processing
+		Unprocessed: kind == ERROR_RATE_LIMITED || code == "-1021",
+		Err:         err,
+	}
+}
+
+// converts a binance order into the application order
+func fromBinanceOrder(market internal.Market, order binanceOrder) (*entities.Order, error) {
+	priceType, ok := IBinanceType(order.Type)
+	if !ok {
+		return nil, fmt.Errorf("unsupported order type %s", order.Type)
+	}
+	side, ok := IBinanceSide(order.Side)
+	if !ok {
+		return nil, fmt.Errorf("unknown side %s", order.Side)
+	}
+	status, ok := IBinanceStatus(BinanceOrderStatus(order.Status))
+	if !ok {
+		return nil, fmt.Errorf("unknown order status %s", order.Status)
+	}
+	res := &entities.Order{
+		Id:             order.ClientOrderId,
+		RemoteId:       binanceRemoteId(order.Symbol, order.OrderId),
+		LimitPrice:     order.Price,
+		Type:           internal.SPOT,
+		PriceType:      priceType,
+		InitialVolume:  order.OrigQty,
+		Side:           side,
+		Status:         status,
+		Market:         market,
+		CreatedAt:      time.UnixMilli(order.Time),
+		ExecutedVolume: order.ExecutedQty,
+		UpdatedAt:      time.UnixMilli(order.UpdateTime),
+	}
+	if !order.ExecutedQty.IsZero() {
+		res.AveragePrice = order.CummulativeQuoteQty.Div(order.ExecutedQty)
+	}
+	if res.IsFinal() {
+		res.ClosedAt = res.UpdatedAt
+	}
+	return res, nil
+}
+
+// binance order ids are unique per symbol only,
+// so the symbol is part of the remote id
+func binanceRemoteId(symbol string, orderId int64) string {
+	return fmt.Sprintf("%s-%d", symbol, orderId)
+}
+
+func parseBinanceRemoteId(remoteId string) (string, string, error) {
+	parts := strings.SplitN(remoteId, "-", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid binance order id %s", remoteId)
+	}
+	if _, err := strconv.ParseInt(parts[1], 10, 64); err != nil {
+		return "", "", fmt.Errorf("invalid binance order id %s", remoteId)
+	}
+	return parts[0], parts[1], nil
+}
+
+// converts the interval in minutes into the kline interval
+func BinanceInterval(interval int) (string, error) {
+	switch interval {
+	case 1, 3, 5, 15, 30:
+		return fmt.Sprintf("%dm", interval), nil
+	case 60, 120, 240, 360, 480, 720:
+		return fmt.Sprintf("%dh", interval/60), nil
+	case 1440, 4320:
+		return fmt.Sprintf("%dd", interval/1440), nil
+	case 10080:
+		return "1w", nil
+	default:
+		return "", fmt.Errorf("unsupported binance interval %dm", interval)
+	}
+}
+
+// markets are named after the kraken altnames,
+// which call bitcoin XBT
+func IBinanceAsset(asset string) internal.Currency {
+	if asset == "BTC" {
+		return internal.XBT
+	}
+	return internal.Currency(asset)
+}
+
+func BinanceSide(side internal.OrderSide) string {
+	switch side {
+	case internal.BUY:
+		return "BUY"
+	case internal.SELL:
+		return "SELL"
+	default:
+		panic("unknown side")
+	}
+}
+
+func IBinanceSide(side string) (internal.OrderSide, bool) {
+	switch side {
+	case "BUY":
+		return internal.BUY, true
+	case "SELL":
+		return internal.SELL, true
+	default:
+		return "", false
+	}
+}
+
+func BinanceType(t internal.PriceType) string {
+	switch t {
+	case internal.MARKET:
+		return "MARKET"
+	case internal.LIMIT:
+		return "LIMIT"
+	default:
+		panic("unknown order type")
+	}
+}
+
+func IBinanceType(t string) (internal.PriceType, bool) {
+	switch t {
+	case "MARKET":
+		return internal.MARKET, true
+	case "LIMIT", "LIMIT_MAKER":
+		return internal.LIMIT, true
+	case "STOP_LOSS":
+		return internal.STOP_LOSS, true
+	case "STOP_LOSS_LIMIT":
+		return internal.STOP_LOSS_LIMIT, true
+	case "TAKE_PROFIT":
+		return internal.TAKE_PROFIT, true
+	case "TAKE_PROFIT_LIMIT":
+		return internal.TAKE_PROFIT_LIMIT, true
+	default:
+		return "", false
+	}
+}
+
+// orders expired by the self trade prevention
+// are reported as EXPIRED_IN_MATCH
+func IBinanceStatus(status BinanceOrderStatus) (internal.OrderStatus, bool) {
+	switch status {
+	case BINANCE_STATUS_NEW, BINANCE_STATUS_PARTIALLY_FILLED, BINANCE_STATUS_PENDING_CANCEL:
+		return internal.OPEN, true
+	case BINANCE_STATUS_FILLED:
+		return internal.FILLED, true
+	case BINANCE_STATUS_CANCELED, BINANCE_STATUS_EXPIRED, BINANCE_STATUS_EXPIRED_IN_MATCH:
+		return internal.CANCELLED, true
+	case BINANCE_STATUS_REJECTED:
+		return internal.ERROR, true
+	default:
+		return "", false
+	}
+}
+
+// maps the exchangeInfo symbol status, symbols in
+// auction or halted are not traded
+func IBinanceSymbolStatus(status string) internal.MarketStatus {
+	switch status {
+	case "", "TRADING":
+		return internal.MARKET_ONLINE
+	default:
+		return internal.MARKET_OFFLINE
+	}
+}
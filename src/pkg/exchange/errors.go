@@ -0,0 +1,141 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ErrorKind string
+
+const (
+	// transient failure (network, timeout, busy exchange), the
+	// request may have been processed anyway
+	ERROR_RETRYABLE ErrorKind = "retryable"
+	// the request was refused before being processed because of rate limits
+	ERROR_RATE_LIMITED ErrorKind = "rate-limited"
+	// the request was processed and refused (e.g. insufficient funds,
+	// invalid arguments, unknown pair), retrying won't help
+	ERROR_REJECTED ErrorKind = "rejected"
+	// invalid credentials or missing permissions
+	ERROR_AUTH ErrorKind = "auth"
+	// the exchange or the market is down or in cancel only mode
+	ERROR_UNAVAILABLE ErrorKind = "unavailable"
+)
+
+// sentinel errors matching the exchange errors of each kind with errors.Is
+var (
+	ErrRetryable   = errors.New("retryable exchange error")
+	ErrRateLimited = errors.New("exchange rate limit exceeded")
+	ErrRejected    = errors.New("request rejected by the exchange")
+	ErrAuth        = errors.New("exchange authentication error")
+	ErrUnavailable = errors.New("exchange unavailable")
+)
+
+var errorKinds = map[ErrorKind]error{
+	ERROR_RETRYABLE:    ErrRetryable,
+	ERROR_RATE_LIMITED: ErrRateLimited,
+	ERROR_REJECTED:     ErrRejected,
+	ERROR_AUTH:         ErrAuth,
+	ERROR_UNAVAILABLE:  ErrUnavailable,
+}
+
+// classified error returned by the exchange clients
+type ExchangeError struct {
+	Kind    ErrorKind
+	Code    string // exchange error code, e.g. EOrder:Insufficient funds
+	Message string
+	// the exchange didn't process the request, so that
+	// even non idempotent calls can be retried
+	Unprocessed bool
+	Err         error
+}
+
+func (e *ExchangeError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s error %s: %s", e.Kind, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s error: %s", e.Kind, e.Message)
+}
+
+func (e *ExchangeError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ExchangeError) Is(target error) bool {
+	return errorKinds[e.Kind] == target
+}
+
+// returns the kind of the error, empty for errors
+// not classified by the exchange clients
+func Kind(err error) ErrorKind {
+	var exchangeErr *ExchangeError
+	if errors.As(err, &exchangeErr) {
+		return exchangeErr.Kind
+	}
+	return ""
+}
+
+// retry policy with exponential backoff. retryable and rate limited
+// errors are retried, but calls which are not idempotent (e.g. order
+// placement) are retried only if the exchange didn't process them
+type RetryPolicy struct {
+	Attempts int
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+var DEFAULT_RETRY_POLICY = RetryPolicy{Attempts: 3, Delay: 500 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+func (p RetryPolicy) Do(name string, idempotent bool, call func() error) error {
+	delay := p.Delay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = call()
+		if err == nil || attempt >= p.Attempts || !p.retryable(err, idempotent) {
+			return err
+		}
+		logrus.Debugf("[RETRY] %s failed (%v), retrying in %s", name, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+		if delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+}
+
+func (p RetryPolicy) retryable(err error, idempotent bool) bool {
+	var exchangeErr *ExchangeError
+	if !errors.As(err, &exchangeErr) {
+		return false
+	}
+	switch exchangeErr.Kind {
+	case ERROR_RATE_LIMITED:
+		return true
+	case ERROR_RETRYABLE:
+		return idempotent || exchangeErr.Unprocessed
+	default:
+		return false
+	}
+}
+
+// classifies the errors of the http clients, the requests which
+// couldn't be sent (e.g. connection refused) were never processed,
+// while after the other failures (e.g. timeouts) they may have been.
+// errors not related to the transport are returned as they are
+func networkError(err error) error {
+	var netErr net.Error
+	if !errors.As(err, &netErr) {
+		return err
+	}
+	var opErr *net.OpError
+	return &ExchangeError{
+		Kind:        ERROR_RETRYABLE,
+		Message:     err.Error(),
+		Unprocessed: errors.As(err, &opErr) && opErr.Op == "dial",
+		Err:         err,
+	}
+}
@@ -0,0 +1,127 @@
+package exchange
+
+import (
+	"errors"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+)
+
+var ErrNotSupported = errors.New("operation not supported by the exchange")
+
+// market data exposed by an exchange: candles and
+// metadata of the traded markets
+type IMarketData interface {
+	// returns a list of candles for the given interval and pair,
+	// the last one being the candle in progress
+	GetOHLC(pair internal.Market, interval int) ([]entities.Candle, error)
+	// returns the metadata (precision, currencies, minimums) of the given markets
+	GetMarketsData(markets []internal.Market) (entities.IMarkets, error)
+	// returns the maximum leverage of the market, zero or one when it
+	// is not tradeable on margin. the leverage of the orders is picked
+	// by the leverage policy within this limit
+	GetLeverage(market internal.Market) decimal.Decimal
+}
+
+// level-2 market data, not exposed by every exchange
+type IOrderBookData interface {
+	// returns the first levels of each side of the order book of the market
+	GetOrderBook(market internal.Market, depth int) (*entities.OrderBook, error)
+}
+
+// public trades history, not exposed by every exchange
+type ITradesData interface {
+	// returns the trades executed on the market after the given time, in
+	// pages. the returned time is the one to request the next page from
+	GetTrades(market internal.Market, since time.Time) ([]entities.Trade, time.Time, error)
+}
+
+// order management on an exchange
+type ITrading interface {
+	// submits the order to the exchange, returning the
+	// acknowledgement carrying the exchange ids
+	PlaceOrder(order *entities.Order) (*entities.OrderAck, error)
+	// gets an order given its client id
+	GetOrder(id string) (*entities.Order, error)
+	// gets an order given the exchange id
+	GetRemoteOrder(remoteId string) (*entities.Order, error)
+	// returns the orders of the market waiting to be filled
+	GetOpenOrders(market internal.Market) ([]*entities.Order, error)
+	// returns the orders of the market closed after the given time
+	GetClosedOrders(market internal.Market, since time.Time) ([]*entities.Order, error)
+	// cancels the order given its exchange id
+	CancelOrder(remoteId string) error
+	// cancels every open order, returning how many were cancelled
+	CancelAll() (int, error)
+	// dead man's switch: every open order is cancelled once the timeout
+	// expires, unless called again before. a zero timeout disarms it.
+	// returns the time at which the orders will be cancelled
+	CancelAllAfter(timeout time.Duration) (time.Time, error)
+}
+
+// account informations on an exchange
+type IAccount interface {
+	GetBalance() (*entities.Balance, error)
+	GetOpenPositions(market internal.Market) ([]*entities.Position, error)
+}
+
+// interface every broker has to implement to be used
+// by the application goroutines and strategies
+type IExchange interface {
+	IMarketData
+	ITrading
+	IAccount
+}
+
+// futures specific market data
+type IFutures interface {
+	// returns every tradeable contract, perpetual and fixed maturity,
+	// with the current mark price and funding rate
+	GetContracts() ([]*entities.Contract, error)
+	// returns the contract traded on the given market
+	GetContract(market internal.Market) (*entities.Contract, error)
+}
+
+// futures exchange, orders placed on it must be of the FUTURE type
+type IFuturesExchange interface {
+	IExchange
+	IFutures
+}
+
+// streaming market data exposed by an exchange. every
+// subscription returns a channel which stays open across
+// reconnections and is closed only when the feed is closed
+type IMarketFeed interface {
+	// streams every update of the candle in progress for the given interval
+	SubscribeOHLC(pair internal.Market, interval int) (chan entities.Candle, error)
+	// streams every public trade executed on the market
+	SubscribeTrades(pair internal.Market) (chan entities.Trade, error)
+	// streams the ticker updates of the market
+	SubscribeTicker(pair internal.Market) (chan entities.Ticker, error)
+	// streams a copy of the order book of the market, limited to the
+	// given depth, after every update. updates are validated against the
+	// exchange checksum and a new snapshot is requested on mismatches
+	SubscribeBook(pair internal.Market, depth int) (chan *entities.OrderBook, error)
+	Close()
+}
+
+// streaming account updates exposed by an exchange
+type IOrdersFeed interface {
+	// streams the status and execution updates of the account orders
+	SubscribeOrders() (chan entities.OrderUpdate, error)
+	// notified after every connection: the updates sent while
+	// disconnected are lost, so the tracked orders must be reconciled
+	Connected() chan struct{}
+	Close()
+}
+
+// rounds the volume to the lot and the limit price to the tick size
+// of the market before the order is sent, once the markets are loaded
+func roundOrder(order *entities.Order) (volume decimal.Decimal, limitPrice decimal.Decimal) {
+	if entities.Markets == nil {
+		return order.InitialVolume, order.LimitPrice
+	}
+	return entities.Markets.RoundVolume(order.Market, order.InitialVolume), entities.Markets.RoundPrice(order.Market, order.LimitPrice)
+}
@@ -1,12 +1,16 @@
 package exchange
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/d0ze/golang-hft/src/internal"
 	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
-	krakenapi "github.com/d0ze/kraken-go-api-client"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 )
@@ -39,235 +43,498 @@ const (
 	STATUS_EXPIRED   KrakenOrderStatus = "expired"
 )
 
-var leverages = map[internal.Market]decimal.Decimal{
-	internal.ETHEUR:  decimal.NewFromInt(5),
-	internal.XBTEUR:  decimal.NewFromInt(5),
-	internal.XBTUSDT: decimal.NewFromInt(5),
-	internal.XBTUSD:  decimal.NewFromInt(5),
-	internal.ETHUSD:  decimal.NewFromInt(5),
-	internal.LTCEUR:  decimal.NewFromInt(3),
+// margin fees of the kraken pairs, not returned by the api
+var (
+	KRAKEN_MARGIN_OPEN_FEE = decimal.RequireFromString("0.0002")
+	KRAKEN_ROLLOVER_FEE    = decimal.RequireFromString("0.0002")
+)
+
+// fee tier of the account, percent fees by pair name
+type krakenTradeVolume struct {
+	Currency  string                   `json:"currency"`
+	Volume    decimal.Decimal          `json:"volume"`
+	Fees      map[string]krakenFeeTier `json:"fees"`
+	FeesMaker map[string]krakenFeeTier `json:"fees_maker"`
+}
+
+type krakenFeeTier struct {
+	Fee decimal.Decimal `json:"fee"`
+}
+
+// margin account balances as returned by TradeBalance
+type krakenTradeBalance struct {
+	EquivalentBalance decimal.Decimal `json:"eb"`
+	TradeBalance      decimal.Decimal `json:"tb"`
+	MarginOP          decimal.Decimal `json:"m"`
+	Net               decimal.Decimal `json:"n"`
+	Cost              decimal.Decimal `json:"c"`
+	Valuation         decimal.Decimal `json:"v"`
+	Equity            decimal.Decimal `json:"e"`
+	FreeMargin        decimal.Decimal `json:"mf"`
+	MarginLevel       decimal.Decimal `json:"ml"` // missing without open positions
+}
+
+// margin position as returned by OpenPositions
+type krakenOpenPosition struct {
+	OrderTxId    string          `json:"ordertxid"`
+	Status       string          `json:"posstatus"`
+	Pair         string          `json:"pair"`
+	Time         decimal.Decimal `json:"time"`
+	Type         string          `json:"type"`
+	OrderType    string          `json:"ordertype"`
+	Cost         decimal.Decimal `json:"cost"`
+	Fee          decimal.Decimal `json:"fee"`
+	Volume       decimal.Decimal `json:"vol"`
+	VolumeClosed decimal.Decimal `json:"vol_closed"`
+	Margin       decimal.Decimal `json:"margin"`
+	Value        decimal.Decimal `json:"value"`
+	Net          decimal.Decimal `json:"net"`
 }
 
-type IKrakenCli interface {
-	GetOHLC(pair internal.Market, interval int) ([]entities.Candle, error)
-	PlaceOrder(order *entities.Order) error
-	GetOrder(id string) (*entities.Order, error)
-	GetBalance() (*entities.Balance, error)
-	GetOpenPositions(market internal.Market) ([]*entities.Position, error)
-	GetMarketsData(markets []internal.Market) (entities.IMarkets, error)
-	GetLeverage(market internal.Market) decimal.Decimal
+type krakenWsToken struct {
+	Token   string `json:"token"`
+	Expires int    `json:"expires"`
 }
 
+type krakenAddOrder struct {
+	Description struct {
+		Order string `json:"order"`
+		Close string `json:"close"`
+	} `json:"descr"`
+	TxIds []string `json:"txid"`
+}
+
+// kraken implementation of IExchange
 type krakenCli struct {
-	cli *krakenapi.KrakenAPI
+	rest   *krakenRest
+	apiKey string
+	limits *KrakenLimits
+}
+
+// the limits are shared by every client of the same account,
+// requests not answered within the timeout are abandoned
+func NewKrakenCli(apiKey string, secret string, limits *KrakenLimits, timeout time.Duration) IExchange {
+	return NewKrakenCliWithClient(apiKey, secret, &http.Client{Timeout: timeout}, limits)
 }
 
-func InitClient() {
-	cli := krakenapi.New(internal.Config.KrakenApiKey, internal.Config.KrakenSecret)
-	KrakenCli = &krakenCli{cli: cli}
+// creates the client using the given http client for every request
+func NewKrakenCliWithClient(apiKey string, secret string, httpClient *http.Client, limits *KrakenLimits) IExchange {
+	return &krakenCli{rest: newKrakenRest(apiKey, secret, httpClient), apiKey: apiKey, limits: limits}
+}
+
+// queries a private method of the kraken api
+func (c *krakenCli) query(method string, params map[string]string, response interface{}) error {
+	values := url.Values{}
+	for key, value := range params {
+		values.Set(key, value)
+	}
+	return c.call(method, false, func() error {
+		return c.rest.private(method, values, response)
+	})
 }
 
+// queries a public method of the kraken api
+func (c *krakenCli) publicQuery(method string, params url.Values, response interface{}) error {
+	return c.call(method, true, func() error {
+		return c.rest.public(method, params, response)
+	})
+}
+
+// calls the kraken api within the rate limits, classifying the
+// errors and retrying them according to KrakenRetryPolicy. the
+// calls which are not idempotent are retried by their caller
+func (c *krakenCli) call(method string, public bool, fn func() error) error {
+	attempt := func() error {
+		if public {
+			c.limits.waitPublic()
+		} else {
+			c.limits.waitPrivate(method)
+		}
+		err := KrakenError(fn())
+		c.limits.checkError(err)
+		return err
+	}
+	if krakenNonIdempotent[method] {
+		return attempt()
+	}
+	return KrakenRetryPolicy.Do(method, true, attempt)
+}
+
+// maximum leverage of the pair, zero for spot only pairs
 func (c *krakenCli) GetLeverage(market internal.Market) decimal.Decimal {
-	return leverages[market]
+	pair, ok := KrakenPairs.Get(market)
+	if !ok {
+		return decimal.Zero
+	}
+	return pair.MaxLeverage
 }
 
 // returns a list of candles for the given interval and pair
 func (c *krakenCli) GetOHLC(pair internal.Market, interval int) ([]entities.Candle, error) {
-	resp, err := c.cli.OHLCWithInterval(Pair(pair), fmt.Sprintf("%d", interval))
-	if err != nil {
+	var resp map[string]json.RawMessage
+	params := url.Values{"pair": {Pair(pair)}, "interval": {strconv.Itoa(interval)}}
+	if err := c.publicQuery("OHLC", params, &resp); err != nil {
 		return []entities.Candle{}, err
 	}
 	var res []entities.Candle
-	for _, ohlc := range resp.OHLC {
-		res = append(res, entities.Candle{
-			Open:      decimal.NewFromFloat(ohlc.Open),
-			High:      decimal.NewFromFloat(ohlc.High),
-			Low:       decimal.NewFromFloat(ohlc.Low),
-			Close:     decimal.NewFromFloat(ohlc.Close),
-			Timestamp: ohlc.Time,
-		})
+	for key, raw := range resp {
+		if key == "last" {
+			continue
+		}
+		// [time, open, high, low, close, vwap, volume, count]
+		var rows [][]json.RawMessage
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return []entities.Candle{}, err
+		}
+		for _, fields := range rows {
+			candle, err := parseKrakenOHLC(fields)
+			if err != nil {
+				return []entities.Candle{}, err
+			}
+			res = append(res, candle)
+		}
 	}
 	return res, nil
 }
 
+func parseKrakenOHLC(fields []json.RawMessage) (entities.Candle, error) {
+	if len(fields) < 8 {
+		return entities.Candle{}, fmt.Errorf("unexpected ohlc length %d", len(fields))
+	}
+	var ts int64
+	var count int
+	if err := json.Unmarshal(fields[0], &ts); err != nil {
+		return entities.Candle{}, err
+	}
+	if err := json.Unmarshal(fields[7], &count); err != nil {
+		return entities.Candle{}, err
+	}
+	values := make([]decimal.Decimal, 6)
+	for i := range values {
+		if err := values[i].UnmarshalJSON(fields[i+1]); err != nil {
+			return entities.Candle{}, err
+		}
+	}
+	candle := entities.NewCandle(values[0], values[1], values[2], values[3], time.Unix(ts, 0))
+	return candle.WithVolume(values[5], values[4], count), nil
+}
+
+// returns up to 1000 public trades executed on the market after the
+// given time, with the time to request the following ones from
+func (c *krakenCli) GetTrades(market internal.Market, since time.Time) ([]entities.Trade, time.Time, error) {
+	params := url.Values{"pair": {Pair(market)}}
+	if !since.IsZero() {
+		params.Set("since", strconv.FormatInt(since.UnixNano(), 10))
+	}
+	var resp map[string]json.RawMessage
+	if err := c.publicQuery("Trades", params, &resp); err != nil {
+		return nil, since, err
+	}
+	next := since
+	var trades []entities.Trade
+	for key, raw := range resp {
+		if key == "last" {
+			var last string
+			if err := json.Unmarshal(raw, &last); err != nil {
+				return nil, since, err
+			}
+			nanos, err := strconv.ParseInt(last, 10, 64)
+			if err != nil {
+				return nil, since, err
+			}
+			next = time.Unix(0, nanos)
+			continue
+		}
+		// [price, volume, time, side, orderType, misc, tradeId]
+		var rows [][]json.RawMessage
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return nil, since, err
+		}
+		for _, fields := range rows {
+			trade, err := parseKrakenTrade(fields, market)
+			if err != nil {
+				return nil, since, err
+			}
+			trades = append(trades, trade)
+		}
+	}
+	return trades, next, nil
+}
+
+func parseKrakenTrade(fields []json.RawMessage, market internal.Market) (entities.Trade, error) {
+	if len(fields) < 5 {
+		return entities.Trade{}, fmt.Errorf("unexpected trade length %d", len(fields))
+	}
+	// the time is a number with decimals, decoded from its text
+	values := make([]decimal.Decimal, 3)
+	for i := range values {
+		if err := values[i].UnmarshalJSON(fields[i]); err != nil {
+			return entities.Trade{}, err
+		}
+	}
+	var sideField, typeField string
+	if err := json.Unmarshal(fields[3], &sideField); err != nil {
+		return entities.Trade{}, err
+	}
+	if err := json.Unmarshal(fields[4], &typeField); err != nil {
+		return entities.Trade{}, err
+	}
+	side := internal.BUY
+	if sideField == "s" {
+		side = internal.SELL
+	}
+	priceType := internal.MARKET
+	if typeField == "l" {
+		priceType = internal.LIMIT
+	}
+	return entities.Trade{
+		Market:    market,
+		Price:     values[0],
+		Volume:    values[1],
+		Side:      side,
+		PriceType: priceType,
+		Timestamp: time.Unix(0, values[2].Shift(9).IntPart()),
+	}, nil
+}
+
 // place order on kraken
-// returns the remote id of the order
-func (c *krakenCli) PlaceOrder(order *entities.Order) error {
-	resp, err := c.cli.AddOrder(
-		Pair(order.Market),
-		string(Side(order.Side)),
-		string(Type(order.PriceType)),
-		order.InitialVolume.String(),
-		map[string]string{
-			"leverage": fmt.Sprintf("%d", order.Leverage),
-			"price":    order.LimitPrice.String(),
-		})
-	logrus.Infof("response: %v", resp)
-	if err != nil {
+// the order id is sent as client order id to match the executions
+// feed updates. the order is sent again only if the exchange never
+// received it (rate limited, connection refused): after ambiguous
+// errors (e.g. timeout) it may be live even if not listed yet, so
+// the error is returned and the order reconciled by the caller
+func (c *krakenCli) PlaceOrder(order *entities.Order) (*entities.OrderAck, error) {
+	var ack *entities.OrderAck
+	err := KrakenRetryPolicy.Do("PlaceOrder", false, func() error {
+		var err error
+		ack, err = c.addOrder(order)
 		return err
+	})
+	return ack, err
+}
+
+func (c *krakenCli) addOrder(order *entities.Order) (*entities.OrderAck, error) {
+	c.limits.waitAddOrder(order.Market)
+	volume, price := roundOrder(order)
+	params := map[string]string{
+		"pair":      Pair(order.Market),
+		"type":      string(Side(order.Side)),
+		"ordertype": string(Type(order.PriceType)),
+		"volume":    volume.String(),
+		"price":     price.String(),
+		"cl_ord_id": order.Id,
 	}
-	return nil
+	// spot orders are sent without leverage
+	if order.Leverage > 1 {
+		params["leverage"] = fmt.Sprintf("%d", order.Leverage)
+	}
+	var resp krakenAddOrder
+	err := c.query("AddOrder", params, &resp)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Tracef("[KRAKEN] AddOrder %s: %v", order.Id, resp)
+	if len(resp.TxIds) == 0 {
+		return nil, fmt.Errorf("no txid returned for order %s", order.Id)
+	}
+	c.limits.recordPlaced(order.Market, resp.TxIds)
+	return &entities.OrderAck{
+		Id:          order.Id,
+		RemoteIds:   resp.TxIds,
+		Description: resp.Description.Order,
+		Status:      internal.OPEN,
+	}, nil
 }
 
-// gets an order from kraken given the
-// client id
-func (c *krakenCli) GetOrder(id string) (*entities.Order, error) {
-	return &entities.Order{}, nil
+// returns a function retrieving a token to
+// authenticate on the private websocket api
+func KrakenWsToken(apiKey string, secret string, limits *KrakenLimits, timeout time.Duration) func() (string, error) {
+	rest := newKrakenRest(apiKey, secret, &http.Client{Timeout: timeout})
+	return func() (string, error) {
+		var resp krakenWsToken
+		err := KrakenRetryPolicy.Do("GetWebSocketsToken", true, func() error {
+			limits.waitPrivate("GetWebSocketsToken")
+			err := KrakenError(rest.private("GetWebSocketsToken", nil, &resp))
+			limits.checkError(err)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+		if resp.Token == "" {
+			return "", fmt.Errorf("no websocket token returned")
+		}
+		return resp.Token, nil
+	}
 }
 
+// loads every kraken pair and asset into KrakenPairs, returning
+// the metadata of the given markets. markets not listed by kraken
+// are reported as errors
 func (c *krakenCli) GetMarketsData(markets []internal.Market) (entities.IMarkets, error) {
-	resp, err := c.cli.AssetPairs()
-	if err != nil {
-		return entities.NewMarkets(), err
-	}
 	result := entities.NewMarkets()
+	var assets map[string]krakenAsset
+	if err := c.publicQuery("Assets", nil, &assets); err != nil {
+		return result, err
+	}
+	var pairs map[string]krakenAssetPair
+	if err := c.publicQuery("AssetPairs", nil, &pairs); err != nil {
+		return result, err
+	}
+	KrakenPairs.Load(pairs, assets)
 	for _, market := range markets {
-		var info krakenapi.AssetPairInfo
-		switch market {
-		case internal.XBTUSD:
-			info = resp.XXBTZUSD
-		// TODO asset pair info response doesnt carry
-		//  xbtusdt, add it in the library
-		case internal.XBTUSDT:
-			info = resp.XXBTZUSD
-		case internal.ETHEUR:
-			info = resp.XETHZEUR
-		case internal.ETHUSD:
-			info = resp.XETHZUSD
-		case internal.XBTEUR:
-			info = resp.XXBTZEUR
-		case internal.LTCEUR:
-			info = resp.XLTCZEUR
-		default:
-			panic("unknown market")
+		pair, ok := KrakenPairs.Get(market)
+		if !ok {
+			return result, fmt.Errorf("unknown kraken market %s", market)
+		}
+		result.SetMetadata(market, pair.LotDecimals, pair.Base, pair.Quote, pair.CostMin, pair.OrderMin)
+		result.SetTickSize(market, pair.TickSize)
+		result.SetPriceDecimals(market, pair.PairDecimals)
+		result.SetLotMultiplier(market, pair.LotMultiplier)
+		result.SetLeverages(market, pair.LeverageBuy, pair.LeverageSell)
+		result.SetStatus(market, pair.Status)
+		result.SetFees(market, pair.Fees)
+	}
+	// the fee tier of the account requires the api keys, the
+	// fees of the lowest tier are kept when it's not available
+	if c.apiKey != "" && len(markets) > 0 {
+		if err := c.setAccountFees(result, markets); err != nil {
+			logrus.Warnf("error %v retrieving the account fees, using the base fees", err)
 		}
-		pair := fmt.Sprintf("%s%s", info.Base, info.Quote)
-		costMin, _ := decimal.NewFromString(info.CostMin)
-		orderMin, _ := decimal.NewFromString(info.OrderMin)
-		result.SetMetadata(
-			IPair(pair),
-			info.LotDecimals,
-			ICurrency(info.Altname[0:3]),
-			ICurrency(info.Altname[3:6]),
-			costMin,
-			orderMin,
-		)
-
 	}
 	return result, nil
 }
 
+// sets the maker and taker fees of the account volume tier
+func (c *krakenCli) setAccountFees(result entities.IMarkets, markets []internal.Market) error {
+	var names []string
+	for _, market := range markets {
+		names = append(names, Pair(market))
+	}
+	var resp krakenTradeVolume
+	if err := c.query("TradeVolume", map[string]string{"pair": strings.Join(names, ",")}, &resp); err != nil {
+		return err
+	}
+	for _, market := range markets {
+		fees := result.GetFees(market)
+		if tier, ok := resp.Fees[Pair(market)]; ok {
+			fees.Taker = tier.Fee.Div(decimal.NewFromInt(100))
+		}
+		if tier, ok := resp.FeesMaker[Pair(market)]; ok {
+			fees.Maker = tier.Fee.Div(decimal.NewFromInt(100))
+		}
+		result.SetFees(market, fees)
+	}
+	return nil
+}
+
 func (c *krakenCli) GetBalance() (*entities.Balance, error) {
-	resp, err := c.cli.TradeBalance(map[string]string{})
-	if err != nil {
+	var resp krakenTradeBalance
+	if err := c.query("TradeBalance", map[string]string{}, &resp); err != nil {
 		return &entities.Balance{}, err
 	}
 	return &entities.Balance{
-		TradeBalance:  decimal.NewFromFloat(resp.TradeBalance),
-		InitialMargin: decimal.NewFromFloat(resp.MarginOP),
-		FreeMargin:    decimal.NewFromFloat(resp.FreeMargin),
-		Equity:        decimal.NewFromFloat(resp.Equity),
-		MarginLevel:   decimal.NewFromFloat(resp.MarginLevel),
+		TradeBalance:  resp.TradeBalance,
+		InitialMargin: resp.MarginOP,
+		FreeMargin:    resp.FreeMargin,
+		Equity:        resp.Equity,
+		MarginLevel:   resp.MarginLevel,
 	}, nil
 }
 
 func (c *krakenCli) GetOpenPositions(market internal.Market) ([]*entities.Position, error) {
-	resp, err := c.cli.OpenPositions(map[string]string{
+	var resp map[string]krakenOpenPosition
+	err := c.query("OpenPositions", map[string]string{
 		"market":  Pair(market),
 		"docalcs": "true",
-	})
+	}, &resp)
 	if err != nil {
 		return []*entities.Position{}, err
 	}
 	var res []*entities.Position
-	for id, position := range *resp {
-		res = append(res, &entities.Position{
-			Id:        id,
-			Size:      decimal.NewFromFloat(position.Volume),
-			Side:      ISide(position.PositionType),
-			Market:    IPair(position.Pair),
-			Realized:  decimal.NewFromFloat(float64(position.Net)),
-			Status:    internal.PositionStatus(position.Status),
-			CreatedAt: time.UnixMilli(int64(position.TradeTime)),
-			Cost:      decimal.NewFromFloat(position.Cost),
-		})
+	for id, position := range resp {
+		converted, err := krakenPosition(id, position)
+		if err != nil {
+			logrus.Warnf("skipping position %s: %v", id, err)
+			continue
+		}
+		res = append(res, converted)
 	}
 	return res, nil
 }
+
+// the open price and the leverage are derived from the
+// cost, the volume and the margin of the position
+func krakenPosition(id string, position krakenOpenPosition) (*entities.Position, error) {
+	side, ok := ISide(position.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown side %s", position.Type)
+	}
+	res := &entities.Position{
+		Id:        id,
+		Size:      position.Volume,
+		Side:      side,
+		Market:    IPair(position.Pair),
+		Realized:  position.Net,
+		Status:    internal.PositionStatus(position.Status),
+		CreatedAt: time.Unix(0, position.Time.Shift(9).IntPart()),
+		Cost:      position.Cost,
+		Fee:       position.Fee,
+	}
+	if res.Size.IsPositive() {
+		res.OpenPrice = res.Cost.Div(res.Size)
+	}
+	if position.Margin.IsPositive() {
+		res.Leverage = int(position.Cost.Div(position.Margin).Round(0).IntPart())
+	}
+	return res, nil
+}
+
+// rest api name of the pair (e.g. XXBTZEUR)
 func Pair(pair internal.Market) string {
-	switch pair {
-	case internal.XBTEUR:
-		return krakenapi.XXBTZEUR
-	case internal.XBTUSD:
-		return krakenapi.XXBTZUSD
-	case internal.XBTUSDT:
-		return krakenapi.XBTUSDT
-	case internal.ETHEUR:
-		return krakenapi.XETHZEUR
-	case internal.ETHUSD:
-		return krakenapi.XETHZUSD
-	case internal.LTCEUR:
-		return krakenapi.XLTCZEUR
-	default:
-		panic("unknown market")
+	if info, ok := KrakenPairs.Get(pair); ok {
+		return info.Name
 	}
+	panic("unknown market")
 }
 
+// returns the market given any of the kraken pair names
 func IPair(pair string) internal.Market {
-	switch pair {
-	case krakenapi.XXBTZEUR:
-		return internal.XBTEUR
-	case krakenapi.XXBTZUSD:
-		return internal.XBTUSD
-	case krakenapi.XBTUSDT:
-		return internal.XBTUSDT
-	case krakenapi.XETHZEUR:
-		return internal.ETHEUR
-	case krakenapi.XETHZUSD:
-		return internal.ETHUSD
-	case krakenapi.XLTCZEUR:
-		return internal.LTCEUR
-	default:
-		panic("unknown market")
+	if info, ok := KrakenPairs.Find(pair); ok {
+		return info.Market
 	}
+	panic("unknown market")
 }
 
-func Currency(currency internal.Currency) string {
-	switch currency {
-	case internal.XBT:
-		return "XBT"
-	case internal.ETH:
-		return "ETH"
-	case internal.EUR:
-		return "EUR"
-	case internal.USD:
-		return "USD"
-	case internal.USDT:
-		return "USDT"
-	case internal.LTC:
-		return "LTC"
-	default:
-		panic("unknown currency")
+// websocket name of the pair
+func WsPair(pair internal.Market) string {
+	if info, ok := KrakenPairs.Get(pair); ok && info.WsName != "" {
+		return info.WsName
 	}
+	panic("unknown market")
 }
 
+func IWsPair(pair string) internal.Market {
+	return IPair(pair)
+}
+
+// kraken accepts the asset altnames
+func Currency(currency internal.Currency) string {
+	return string(currency)
+}
+
+// returns the currency given the kraken asset id (e.g. XXBT) or altname
 func ICurrency(currency string) internal.Currency {
-	switch currency {
-	case "XBT":
-		return internal.XBT
-	case "ETH":
-		return internal.ETH
-	case "EUR":
-		return internal.EUR
-	case "USD":
-		return internal.USD
-	case "USDT":
-		return internal.USDT
-	case "LTC":
-		return internal.LTC
-	default:
-		panic("unknown currency")
+	return KrakenPairs.Currency(currency)
+}
+
+// returns the market given the pair altname (e.g. XBTEUR)
+// reporting false for pairs not listed by kraken
+func IAltPair(pair string) (internal.Market, bool) {
+	if info, ok := KrakenPairs.Find(pair); ok {
+		return info.Market, true
 	}
+	return "", false
 }
 
 func Side(side internal.OrderSide) KrakenOrderSide {
@@ -281,14 +548,53 @@ func Side(side internal.OrderSide) KrakenOrderSide {
 	}
 }
 
-func ISide(side string) internal.OrderSide {
+// the mappers of the exchange values report whether the value
+// is known, since orders placed outside the bot may use types
+// the application doesn't model (e.g. trailing-stop, iceberg)
+func ISide(side string) (internal.OrderSide, bool) {
 	switch side {
 	case string(SIDE_BUY):
-		return internal.BUY
+		return internal.BUY, true
 	case string(SIDE_SELL):
-		return internal.SELL
+		return internal.SELL, true
 	default:
-		panic("unknown side")
+		return "", false
+	}
+}
+
+func IType(t string) (internal.PriceType, bool) {
+	switch t {
+	case string(TYPE_MARKET):
+		return internal.MARKET, true
+	case string(TYPE_LIMIT):
+		return internal.LIMIT, true
+	case string(TYPE_TAKE_PROFIT):
+		return internal.TAKE_PROFIT, true
+	case string(TYPE_TAKE_PROFIT_LIMIT):
+		return internal.TAKE_PROFIT_LIMIT, true
+	case string(TYPE_STOP_LOSS):
+		return internal.STOP_LOSS, true
+	case string(TYPE_STOP_LOSS_LIMIT):
+		return internal.STOP_LOSS_LIMIT, true
+	default:
+		return "", false
+	}
+}
+
+// maps the kraken order status, partially filled orders
+// stay open until they are closed or cancelled
+func IStatus(status KrakenOrderStatus) (internal.OrderStatus, bool) {
+	switch status {
+	case STATUS_PENDING:
+		return internal.CREATED, true
+	case STATUS_OPEN:
+		return internal.OPEN, true
+	case STATUS_CLOSED:
+		return internal.FILLED, true
+	case STATUS_CANCELLED, STATUS_EXPIRED:
+		return internal.CANCELLED, true
+	default:
+		return "", false
 	}
 }
 
@@ -302,5 +608,3 @@ func Type(t internal.PriceType) KrakenPriceType {
 		panic("unknown order type")
 	}
 }
-
-var KrakenCli IKrakenCli
@@ -0,0 +1,228 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// levels of each side covered by the kraken book checksum
+const KRAKEN_CHECKSUM_LEVELS = 10
+
+// order book as returned by the Depth endpoint,
+// levels are [price, volume, timestamp]
+type krakenDepth struct {
+	Asks [][]interface{} `json:"asks"`
+	Bids [][]interface{} `json:"bids"`
+}
+
+// state of a websocket book subscription. the precision of the
+// levels is the one of the snapshot, as required by the checksum
+type krakenBook struct {
+	book           *entities.OrderBook
+	priceDecimals  int32
+	volumeDecimals int32
+	// false until the first snapshot and after a checksum mismatch
+	synced bool
+}
+
+// returns the first levels of the order book of the market
+func (c *krakenCli) GetOrderBook(market internal.Market, depth int) (*entities.OrderBook, error) {
+	var resp map[string]krakenDepth
+	params := url.Values{"pair": {Pair(market)}, "count": {strconv.Itoa(depth)}}
+	if err := c.publicQuery("Depth", params, &resp); err != nil {
+		return nil, err
+	}
+	// the result is keyed by the rest name of the pair
+	for _, data := range resp {
+		book := entities.NewOrderBook(market, depth)
+		for _, fields := range data.Bids {
+			level, err := parseBookLevel(fields)
+			if err != nil {
+				return nil, err
+			}
+			book.UpdateBid(level)
+		}
+		for _, fields := range data.Asks {
+			level, err := parseBookLevel(fields)
+			if err != nil {
+				return nil, err
+			}
+			book.UpdateAsk(level)
+		}
+		return book, nil
+	}
+	return nil, fmt.Errorf("no order book returned for %s", market)
+}
+
+func (f *krakenFeed) SubscribeBook(pair internal.Market, depth int) (chan *entities.OrderBook, error) {
+	result := make(chan *entities.OrderBook)
+	subscription := krakenWsSubscription{Name: "book", Depth: depth}
+	state := &krakenBook{}
+	err := f.subscribe(pair, subscription, fmt.Sprintf("book-%d", depth),
+		func(payload []json.RawMessage) error {
+			changed, err := state.apply(pair, depth, payload[1:len(payload)-2])
+			if err != nil {
+				// the book is unreliable until a new snapshot is received
+				state.synced = false
+				logrus.Warnf("[KRAKEN WS] %v, resubscribing to the %s book", err, pair)
+				return f.resubscribe(pair, subscription)
+			}
+			if !changed {
+				return nil
+			}
+			select {
+			case result <- state.book.Copy():
+			case <-f.ws.done:
+			}
+			return nil
+		}, func() { close(result) })
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// asks kraken for a new snapshot of the subscription
+func (f *krakenFeed) resubscribe(pair internal.Market, subscription krakenWsSubscription) error {
+	if err := f.ws.send(krakenWsSubscribe{Event: "unsubscribe", Pair: []string{WsPair(pair)}, Subscription: subscription}); err != nil {
+		return err
+	}
+	return f.ws.send(krakenWsSubscribe{Event: "subscribe", Pair: []string{WsPair(pair)}, Subscription: subscription})
+}
+
+// applies a snapshot ({as, bs}) or the updates ({a, b, c}) of a
+// book message, updates received while not synced are skipped
+func (s *krakenBook) apply(pair internal.Market, depth int, objects []json.RawMessage) (bool, error) {
+	var checksum string
+	updated := false
+	for _, object := range objects {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(object, &fields); err != nil {
+			return false, err
+		}
+		if asks, ok := fields["as"]; ok {
+			if err := s.snapshot(pair, depth, asks, fields["bs"]); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+		if !s.synced {
+			return false, nil
+		}
+		for key, update := range map[string]func(entities.BookLevel){"a": s.book.UpdateAsk, "b": s.book.UpdateBid} {
+			if raw, ok := fields[key]; ok {
+				if err := s.update(raw, update, false); err != nil {
+					return false, err
+				}
+				updated = true
+			}
+		}
+		if raw, ok := fields["c"]; ok {
+			if err := json.Unmarshal(raw, &checksum); err != nil {
+				return false, err
+			}
+		}
+	}
+	if checksum != "" {
+		if expected := fmt.Sprintf("%d", KrakenBookChecksum(s.book, s.priceDecimals, s.volumeDecimals)); expected != checksum {
+			return false, fmt.Errorf("checksum mismatch (%s, expected %s)", expected, checksum)
+		}
+	}
+	return updated, nil
+}
+
+func (s *krakenBook) snapshot(pair internal.Market, depth int, asks json.RawMessage, bids json.RawMessage) error {
+	s.book = entities.NewOrderBook(pair, depth)
+	if err := s.update(asks, s.book.UpdateAsk, true); err != nil {
+		return err
+	}
+	if err := s.update(bids, s.book.UpdateBid, true); err != nil {
+		return err
+	}
+	s.synced = true
+	return nil
+}
+
+// parses the levels [price, volume, timestamp(, "r")] and applies them
+func (s *krakenBook) update(raw json.RawMessage, update func(entities.BookLevel), snapshot bool) error {
+	var levels [][]interface{}
+	if err := json.Unmarshal(raw, &levels); err != nil {
+		return err
+	}
+	for _, fields := range levels {
+		level, err := parseBookLevel(fields)
+		if err != nil {
+			return err
+		}
+		if snapshot {
+			s.priceDecimals = stringDecimals(fields[0])
+			s.volumeDecimals = stringDecimals(fields[1])
+		}
+		update(level)
+	}
+	return nil
+}
+
+func parseBookLevel(fields []interface{}) (entities.BookLevel, error) {
+	if len(fields) < 3 {
+		return entities.BookLevel{}, fmt.Errorf("unexpected book level length %d", len(fields))
+	}
+	values, err := wsStrings(fields[:2])
+	if err != nil {
+		return entities.BookLevel{}, err
+	}
+	prices, err := parseDecimals(values)
+	if err != nil {
+		return entities.BookLevel{}, err
+	}
+	// the rest api returns the timestamp as a number
+	var ts time.Time
+	switch value := fields[2].(type) {
+	case string:
+		ts, err = parseKrakenTime(value)
+	case float64:
+		ts = time.Unix(int64(value), 0)
+	default:
+		err = fmt.Errorf("unexpected book level timestamp %v", value)
+	}
+	if err != nil {
+		return entities.BookLevel{}, err
+	}
+	return entities.BookLevel{Price: prices[0], Volume: prices[1], Timestamp: ts}, nil
+}
+
+// digits after the decimal point of a formatted number
+func stringDecimals(field interface{}) int32 {
+	value, _ := field.(string)
+	if i := strings.Index(value, "."); i >= 0 {
+		return int32(len(value) - i - 1)
+	}
+	return 0
+}
+
+// crc32 of the first ten asks and bids, each price and volume
+// formatted as sent by kraken without the decimal point and
+// the leading zeros
+func KrakenBookChecksum(book *entities.OrderBook, priceDecimals int32, volumeDecimals int32) uint32 {
+	var sb strings.Builder
+	format := func(value decimal.Decimal, decimals int32) {
+		sb.WriteString(strings.TrimLeft(strings.Replace(value.StringFixed(decimals), ".", "", 1), "0"))
+	}
+	for _, levels := range [][]entities.BookLevel{book.Asks, book.Bids} {
+		for i := 0; i < len(levels) && i < KRAKEN_CHECKSUM_LEVELS; i++ {
+			format(levels[i].Price, priceDecimals)
+			format(levels[i].Volume, volumeDecimals)
+		}
+	}
+	return crc32.ChecksumIEEE([]byte(sb.String()))
+}
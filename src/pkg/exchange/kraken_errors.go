@@ -0,0 +1,94 @@
+package exchange
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// retry policy of the kraken calls
+var KrakenRetryPolicy = DEFAULT_RETRY_POLICY
+
+// calls placing orders twice if retried
+var krakenNonIdempotent = map[string]bool{
+	"AddOrder": true,
+}
+
+var krakenErrorCode = regexp.MustCompile(`E(General|API|Query|Order|Trade|Funding|Service|Session|Auth|Data|Permission):[A-Za-z0-9_ \-]+`)
+
+// kraken error codes not following the category of the error
+var krakenErrorKinds = map[string]ErrorKind{
+	"EAPI:Rate limit exceeded":               ERROR_RATE_LIMITED,
+	"EOrder:Rate limit exceeded":             ERROR_RATE_LIMITED,
+	"EGeneral:Too many requests":             ERROR_RATE_LIMITED,
+	"EAPI:Invalid nonce":                     ERROR_RETRYABLE,
+	"EAPI:Invalid key":                       ERROR_AUTH,
+	"EAPI:Invalid signature":                 ERROR_AUTH,
+	"EAPI:Feature disabled":                  ERROR_AUTH,
+	"EGeneral:Permission denied":             ERROR_AUTH,
+	"EGeneral:Internal error":                ERROR_RETRYABLE,
+	"EGeneral:Temporary lockout":             ERROR_RATE_LIMITED,
+	"EService:Busy":                          ERROR_RETRYABLE,
+	"EService:Deadline elapsed":              ERROR_RETRYABLE,
+	"EService:Unavailable":                   ERROR_UNAVAILABLE,
+	"EService:Market in cancel_only mode":    ERROR_UNAVAILABLE,
+	"EService:Market in post_only mode":      ERROR_UNAVAILABLE,
+	"EService:Market in limit_only mode":     ERROR_UNAVAILABLE,
+	"EOrder:Trading agreement required":      ERROR_AUTH,
+	"EOrder:Unknown order":                   ERROR_REJECTED,
+	"EOrder:Insufficient funds":              ERROR_REJECTED,
+	"EOrder:Insufficient margin":             ERROR_REJECTED,
+	"EGeneral:Invalid arguments":             ERROR_REJECTED,
+	"EQuery:Unknown asset pair":              ERROR_REJECTED,
+	"EOrder:Orders limit exceeded":           ERROR_REJECTED,
+	"EOrder:Positions limit exceeded":        ERROR_REJECTED,
+	"EOrder:Margin allowance exceeded":       ERROR_REJECTED,
+	"EOrder:Cannot open position":            ERROR_REJECTED,
+	"EOrder:Order minimum not met":           ERROR_REJECTED,
+	"EAPI:Bad request":                       ERROR_REJECTED,
+	"EService:Market not available":          ERROR_UNAVAILABLE,
+	"EService:Timeout":                       ERROR_RETRYABLE,
+	"EGeneral:Unknown method":                ERROR_REJECTED,
+	"EOrder:Domain rate limit exceeded":      ERROR_RATE_LIMITED,
+	"EOrder:Scheduled orders limit exceeded": ERROR_REJECTED,
+}
+
+// classifies the error returned by a kraken call, parsing the
+// kraken error codes carried by the message. the transport
+// errors are already classified by the rest client
+func KrakenError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var exchangeErr *ExchangeError
+	if errors.As(err, &exchangeErr) {
+		return err
+	}
+	message := err.Error()
+	if code := krakenErrorCode.FindString(message); code != "" {
+		code = strings.TrimSpace(code)
+		kind, ok := krakenErrorKinds[code]
+		if !ok {
+			kind = krakenCategoryKind(code)
+		}
+		return &ExchangeError{
+			Kind:        kind,
+			Code:        code,
+			Message:     message,
+			Unprocessed: kind == ERROR_RATE_LIMITED || code == "EAPI:Invalid nonce",
+			Err:         err,
+		}
+	}
+	return err
+}
+
+func krakenCategoryKind(code string) ErrorKind {
+	switch {
+	case strings.HasPrefix(code, "EService:"):
+		return ERROR_UNAVAILABLE
+	case strings.HasPrefix(code, "EAuth:"), strings.HasPrefix(code, "EPermission:"):
+		return ERROR_AUTH
+	default:
+		return ERROR_REJECTED
+	}
+}
@@ -0,0 +1,831 @@
+package exchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/utils"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const KRAKEN_FUTURES_API_URL = "https://futures.kraken.com"
+
+// number of candles requested by GetOHLC
+const KRAKEN_FUTURES_OHLC_SIZE = 720
+
+// fees of the base tier, funding is paid through the funding rate
+var KRAKEN_FUTURES_FEES = entities.Fees{
+	Maker: decimal.RequireFromString("0.0002"),
+	Taker: decimal.RequireFromString("0.0005"),
+}
+
+type krakenFuturesResponse struct {
+	Result string `json:"result"`
+	Error  string `json:"error"`
+}
+
+type krakenFuturesInstrument struct {
+	Symbol                      string          `json:"symbol"`
+	Type                        string          `json:"type"`
+	Tag                         string          `json:"tag"`
+	Base                        string          `json:"base"`
+	Quote                       string          `json:"quote"`
+	Tradeable                   bool            `json:"tradeable"`
+	TickSize                    decimal.Decimal `json:"tickSize"`
+	ContractSize                decimal.Decimal `json:"contractSize"`
+	ContractValueTradePrecision int             `json:"contractValueTradePrecision"`
+	LastTradingTime             time.Time       `json:"lastTradingTime"`
+	MarginLevels                []struct {
+		InitialMargin decimal.Decimal `json:"initialMargin"`
+	} `json:"marginLevels"`
+}
+
+type krakenFuturesTicker struct {
+	Symbol      string          `json:"symbol"`
+	MarkPrice   decimal.Decimal `json:"markPrice"`
+	FundingRate decimal.Decimal `json:"fundingRate"`
+}
+
+type krakenFuturesCandle struct {
+	Time  int64           `json:"time"`
+	Open  decimal.Decimal `json:"open"`
+	High  decimal.Decimal `json:"high"`
+	Low   decimal.Decimal `json:"low"`
+	Close decimal.Decimal `json:"close"`
+	// base volume, vwap and trade count are not returned
+	Volume decimal.Decimal `json:"volume"`
+}
+
+type krakenFuturesAccount struct {
+	BalanceValue    decimal.Decimal `json:"balanceValue"`
+	InitialMargin   decimal.Decimal `json:"initialMargin"`
+	AvailableMargin decimal.Decimal `json:"availableMargin"`
+	MarginEquity    decimal.Decimal `json:"marginEquity"`
+	Currencies      map[string]struct {
+		Quantity  decimal.Decimal `json:"quantity"`
+		Available decimal.Decimal `json:"available"`
+	} `json:"currencies"`
+}
+
+type krakenFuturesPosition struct {
+	Side     string          `json:"side"`
+	Symbol   string          `json:"symbol"`
+	Price    decimal.Decimal `json:"price"`
+	FillTime time.Time       `json:"fillTime"`
+	Size     decimal.Decimal `json:"size"`
+}
+
+type krakenFuturesOpenOrder struct {
+	OrderId        string          `json:"order_id"`
+	ClientId       string          `json:"cliOrdId"`
+	Symbol         string          `json:"symbol"`
+	Side           string          `json:"side"`
+	OrderType      string          `json:"orderType"`
+	LimitPrice     decimal.Decimal `json:"limitPrice"`
+	FilledSize     decimal.Decimal `json:"filledSize"`
+	UnfilledSize   decimal.Decimal `json:"unfilledSize"`
+	ReduceOnly     bool            `json:"reduceOnly"`
+	ReceivedTime   time.Time       `json:"receivedTime"`
+	LastUpdateTime time.Time       `json:"lastUpdateTime"`
+}
+
+type krakenFuturesOrderStatus struct {
+	Order struct {
+		OrderId             string          `json:"orderId"`
+		ClientId            string          `json:"cliOrdId"`
+		Symbol              string          `json:"symbol"`
+		Side                string          `json:"side"`
+		Quantity            decimal.Decimal `json:"quantity"`
+		Filled              decimal.Decimal `json:"filled"`
+		LimitPrice          decimal.Decimal `json:"limitPrice"`
+		ReduceOnly          bool            `json:"reduceOnly"`
+		Timestamp           time.Time       `json:"timestamp"`
+		LastUpdateTimestamp time.Time       `json:"lastUpdateTimestamp"`
+	} `json:"order"`
+	Status string `json:"status"`
+}
+
+type krakenFuturesFill struct {
+	OrderId  string          `json:"order_id"`
+	ClientId string          `json:"cliOrdId"`
+	Symbol   string          `json:"symbol"`
+	Side     string          `json:"side"`
+	Size     decimal.Decimal `json:"size"`
+	Price    decimal.Decimal `json:"price"`
+	FillTime time.Time       `json:"fillTime"`
+}
+
+type krakenFuturesSendStatus struct {
+	OrderId     string `json:"order_id"`
+	Status      string `json:"status"`
+	OrderEvents []struct {
+		Type   string          `json:"type"`
+		Amount decimal.Decimal `json:"amount"`
+	} `json:"orderEvents"`
+}
+
+// kraken futures implementation of IFuturesExchange. volumes of the
+// application are expressed in the trade currency and converted
+// into contracts using the contract size of the instrument
+type krakenFuturesCli struct {
+	apiKey     string
+	secret     string
+	httpClient *http.Client
+	mu         sync.Mutex
+	// contracts retrieved from the instruments endpoint,
+	// required to convert volumes into contracts
+	contracts map[internal.Market]*entities.Contract
+}
+
+// requests not answered within the timeout are abandoned
+func NewKrakenFuturesCli(apiKey string, secret string, timeout time.Duration) IFuturesExchange {
+	return NewKrakenFuturesCliWithClient(apiKey, secret, &http.Client{Timeout: timeout})
+}
+
+// creates the client using the given http client for every request
+func NewKrakenFuturesCliWithClient(apiKey string, secret string, httpClient *http.Client) IFuturesExchange {
+	return &krakenFuturesCli{
+		apiKey:     apiKey,
+		secret:     secret,
+		httpClient: httpClient,
+		contracts:  map[internal.Market]*entities.Contract{},
+	}
+}
+
+func (c *krakenFuturesCli) GetContracts() ([]*entities.Contract, error) {
+	var instruments struct {
+		Instruments []krakenFuturesInstrument `json:"instruments"`
+	}
+	if err := c.request(http.MethodGet, "/derivatives/api/v3/instruments", url.Values{}, false, &instruments); err != nil {
+		return nil, err
+	}
+	var tickers struct {
+		Tickers []krakenFuturesTicker `json:"tickers"`
+	}
+	if err := c.request(http.MethodGet, "/derivatives/api/v3/tickers", url.Values{}, false, &tickers); err != nil {
+		return nil, err
+	}
+	prices := map[string]krakenFuturesTicker{}
+	for _, ticker := range tickers.Tickers {
+		prices[ticker.Symbol] = ticker
+	}
+	var res []*entities.Contract
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, instrument := range instruments.Instruments {
+		market, ok := IFuturesSymbol(instrument.Symbol)
+		if !ok || !instrument.Tradeable {
+			continue
+		}
+		contract := &entities.Contract{
+			Symbol:            instrument.Symbol,
+			Market:            market,
+			TradeCurrency:     internal.Currency(instrument.Base),
+			ReferenceCurrency: internal.Currency(instrument.Quote),
+			Perpetual:         instrument.Tag == "perpetual",
+			ContractSize:      instrument.ContractSize,
+			Precision:         instrument.ContractValueTradePrecision,
+			TickSize:          instrument.TickSize,
+			MaxLeverage:       decimal.NewFromInt(1),
+			MarkPrice:         prices[instrument.Symbol].MarkPrice,
+			FundingRate:       prices[instrument.Symbol].FundingRate,
+		}
+		if !contract.Perpetual {
+			contract.Maturity = instrument.LastTradingTime
+		}
+		if len(instrument.MarginLevels) > 0 && instrument.MarginLevels[0].InitialMargin.IsPositive() {
+			contract.MaxLeverage = decimal.NewFromInt(1).Div(instrument.MarginLevels[0].InitialMargin).Floor()
+		}
+		c.contracts[market] = contract
+		res = append(res, contract)
+	}
+	return res, nil
+}
+
+func (c *krakenFuturesCli) GetContract(market internal.Market) (*entities.Contract, error) {
+	contracts, err := c.GetContracts()
+	if err != nil {
+		return nil, err
+	}
+	for _, contract := range contracts {
+		if contract.Market == market {
+			return contract, nil
+		}
+	}
+	return nil, fmt.Errorf("no contract listed for market %s", market)
+}
+
+// returns the known contract of the market, retrieving
+// the instruments if the market was never seen
+func (c *krakenFuturesCli) contract(market internal.Market) (*entities.Contract, error) {
+	c.mu.Lock()
+	contract, ok := c.contracts[market]
+	c.mu.Unlock()
+	if ok {
+		return contract, nil
+	}
+	return c.GetContract(market)
+}
+
+// returns the max leverage of the contract
+func (c *krakenFuturesCli) GetLeverage(market internal.Market) decimal.Decimal {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if contract, ok := c.contracts[market]; ok {
+		return contract.MaxLeverage
+	}
+	return decimal.NewFromInt(1)
+}
+
+// returns a list of candles for the given interval and pair
+func (c *krakenFuturesCli) GetOHLC(pair internal.Market, interval int) ([]entities.Candle, error) {
+	resolution, err := KrakenFuturesResolution(interval)
+	if err != nil {
+		return []entities.Candle{}, err
+	}
+	from := time.Now().Add(-time.Duration(interval*KRAKEN_FUTURES_OHLC_SIZE) * time.Minute)
+	var resp struct {
+		Candles []krakenFuturesCandle `json:"candles"`
+	}
+	err = c.request(http.MethodGet, "/api/charts/v1/trade/"+FuturesSymbol(pair)+"/"+resolution, url.Values{
+		"from": {fmt.Sprintf("%d", from.Unix())},
+	}, false, &resp)
+	if err != nil {
+		return []entities.Candle{}, err
+	}
+	var res []entities.Candle
+	for _, candle := range resp.Candles {
+		res = append(res, entities.NewCandle(candle.Open, candle.High, candle.Low, candle.Close, time.UnixMilli(candle.Time)).WithVolume(candle.Volume, decimal.Zero, 0))
+	}
+	return res, nil
+}
+
+// maps the contracts into the markets metadata, the
+// precision and order minimum refer to the trade currency
+func (c *krakenFuturesCli) GetMarketsData(markets []internal.Market) (entities.IMarkets, error) {
+	result := entities.NewMarkets()
+	for _, market := range markets {
+		contract, err := c.contract(market)
+		if err != nil {
+			return result, err
+		}
+		step := contract.Volume(decimal.New(1, -int32(contract.Precision)))
+		result.SetMetadata(
+			market,
+			contract.Precision,
+			contract.TradeCurrency,
+			contract.ReferenceCurrency,
+			decimal.Zero,
+			step,
+		)
+		result.SetTickSize(market, contract.TickSize)
+		result.SetPriceDecimals(market, utils.Precision(contract.TickSize))
+		result.SetFees(market, KRAKEN_FUTURES_FEES)
+	}
+	return result, nil
+}
+
+func (c *krakenFuturesCli) GetBalance() (*entities.Balance, error) {
+	var resp struct {
+		Accounts struct {
+			Flex krakenFuturesAccount `json:"flex"`
+		} `json:"accounts"`
+	}
+	if err := c.request(http.MethodGet, "/derivatives/api/v3/accounts", url.Values{}, true, &resp); err != nil {
+		return &entities.Balance{}, err
+	}
+	account := resp.Accounts.Flex
+	balance := &entities.Balance{
+		TradeBalance:  account.BalanceValue,
+		InitialMargin: account.InitialMargin,
+		FreeMargin:    account.AvailableMargin,
+		Equity:        account.MarginEquity,
+		Currencies:    map[internal.Currency]decimal.Decimal{},
+	}
+	if account.InitialMargin.IsPositive() {
+		balance.MarginLevel = account.MarginEquity.Div(account.InitialMargin).Mul(decimal.NewFromInt(100))
+	}
+	for currency, amount := range account.Currencies {
+		balance.Currencies[internal.Currency(strings.ToUpper(currency))] = amount.Available
+	}
+	return balance, nil
+}
+
+// kraken futures positions are netted, so there is
+// at most one position for each contract
+func (c *krakenFuturesCli) GetOpenPositions(market internal.Market) ([]*entities.Position, error) {
+	contract, err := c.contract(market)
+	if err != nil {
+		return []*entities.Position{}, err
+	}
+	var resp struct {
+		OpenPositions []krakenFuturesPosition `json:"openPositions"`
+	}
+	if err := c.request(http.MethodGet, "/derivatives/api/v3/openpositions", url.Values{}, true, &resp); err != nil {
+		return []*entities.Position{}, err
+	}
+	var res []*entities.Position
+	for _, position := range resp.OpenPositions {
+		if position.Symbol != contract.Symbol {
+			continue
+		}
+		size := contract.Volume(position.Size)
+		side := internal.BUY
+		if position.Side == "short" {
+			side = internal.SELL
+		}
+		res = append(res, &entities.Position{
+			Id:        position.Symbol,
+			Size:      size,
+			Side:      side,
+			OpenPrice: position.Price,
+			Market:    market,
+			Status:    internal.POPEN,
+			CreatedAt: position.FillTime,
+			Cost:      size.Mul(position.Price),
+		})
+	}
+	return res, nil
+}
+
+// place order on kraken futures, the volume is converted
+// into contracts and the order id sent as client order id
+func (c *krakenFuturesCli) PlaceOrder(order *entities.Order) (*entities.OrderAck, error) {
+	if !order.IsFuture() {
+		return nil, fmt.Errorf("%w: %s order on a futures exchange", ErrNotSupported, order.Type)
+	}
+	orderType, err := FuturesType(order)
+	if err != nil {
+		return nil, err
+	}
+	contract, err := c.contract(order.Market)
+	if err != nil {
+		return nil, err
+	}
+	size := contract.Contracts(order.InitialVolume)
+	if !size.IsPositive() {
+		return nil, fmt.Errorf("volume %s is below the contract size %s", order.InitialVolume, contract.ContractSize)
+	}
+	params := url.Values{
+		"orderType":  {orderType},
+		"symbol":     {contract.Symbol},
+		"side":       {string(Side(order.Side))},
+		"size":       {size.String()},
+		"cliOrdId":   {order.Id},
+		"reduceOnly": {fmt.Sprintf("%t", order.ReduceOnly)},
+	}
+	if order.PriceType == internal.LIMIT {
+		// the size is already rounded to the contract size
+		_, price := roundOrder(order)
+		params.Set("limitPrice", price.String())
+	}
+	var resp struct {
+		SendStatus krakenFuturesSendStatus `json:"sendStatus"`
+	}
+	if err := c.request(http.MethodPost, "/derivatives/api/v3/sendorder", params, true, &resp); err != nil {
+		return nil, err
+	}
+	if resp.SendStatus.Status != "placed" {
+		return nil, &ExchangeError{Kind: ERROR_REJECTED, Code: resp.SendStatus.Status, Message: "order rejected by kraken futures"}
+	}
+	status := internal.OPEN
+	executed := decimal.Zero
+	for _, event := range resp.SendStatus.OrderEvents {
+		if event.Type == "EXECUTION" {
+			executed = executed.Add(event.Amount)
+		}
+	}
+	if executed.GreaterThanOrEqual(size) {
+		status = internal.FILLED
+	}
+	return &entities.OrderAck{
+		Id:          order.Id,
+		RemoteIds:   []string{resp.SendStatus.OrderId},
+		Description: fmt.Sprintf("%s %s %s %s", order.Side, size, contract.Symbol, params.Get("orderType")),
+		Status:      status,
+	}, nil
+}
+
+// gets an order given the client id
+func (c *krakenFuturesCli) GetOrder(id string) (*entities.Order, error) {
+	return c.orderStatus(url.Values{"cliOrdIds": {id}}, id)
+}
+
+// gets an order given the kraken futures order id
+func (c *krakenFuturesCli) GetRemoteOrder(remoteId string) (*entities.Order, error) {
+	return c.orderStatus(url.Values{"orderIds": {remoteId}}, remoteId)
+}
+
+func (c *krakenFuturesCli) orderStatus(params url.Values, id string) (*entities.Order, error) {
+	var resp struct {
+		Orders []krakenFuturesOrderStatus `json:"orders"`
+	}
+	if err := c.request(http.MethodPost, "/derivatives/api/v3/orders/status", params, true, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Orders) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, id)
+	}
+	status := resp.Orders[0]
+	market, ok := IFuturesSymbol(status.Order.Symbol)
+	if !ok {
+		return nil, fmt.Errorf("unknown futures symbol %s", status.Order.Symbol)
+	}
+	contract, err := c.contract(market)
+	if err != nil {
+		return nil, err
+	}
+	side, ok := ISide(status.Order.Side)
+	if !ok {
+		return nil, fmt.Errorf("unknown side %s", status.Order.Side)
+	}
+	orderStatus, ok := IFuturesStatus(status.Status)
+	if !ok {
+		return nil, fmt.Errorf("unknown order status %s", status.Status)
+	}
+	res := &entities.Order{
+		Id:             status.Order.ClientId,
+		RemoteId:       status.Order.OrderId,
+		LimitPrice:     status.Order.LimitPrice,
+		Type:           internal.FUTURE,
+		PriceType:      internal.MARKET,
+		InitialVolume:  contract.Volume(status.Order.Quantity),
+		Side:           side,
+		Status:         orderStatus,
+		Market:         market,
+		CreatedAt:      status.Order.Timestamp,
+		ReduceOnly:     status.Order.ReduceOnly,
+		ExecutedVolume: contract.Volume(status.Order.Filled),
+		UpdatedAt:      status.Order.LastUpdateTimestamp,
+	}
+	if !status.Order.LimitPrice.IsZero() {
+		res.PriceType = internal.LIMIT
+	}
+	if res.IsFinal() {
+		res.ClosedAt = res.UpdatedAt
+	}
+	return res, nil
+}
+
+func (c *krakenFuturesCli) GetOpenOrders(market internal.Market) ([]*entities.Order, error) {
+	contract, err := c.contract(market)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		OpenOrders []krakenFuturesOpenOrder `json:"openOrders"`
+	}
+	if err := c.request(http.MethodGet, "/derivatives/api/v3/openorders", url.Values{}, true, &resp); err != nil {
+		return nil, err
+	}
+	var res []*entities.Order
+	for _, order := range resp.OpenOrders {
+		if order.Symbol != contract.Symbol {
+			continue
+		}
+		priceType, ok := IFuturesType(order.OrderType)
+		side, sideOk := ISide(order.Side)
+		if !ok || !sideOk {
+			logrus.Warnf("skipping %s %s order %s", order.OrderType, order.Side, order.OrderId)
+			continue
+		}
+		res = append(res, &entities.Order{
+			Id:             order.ClientId,
+			RemoteId:       order.OrderId,
+			LimitPrice:     order.LimitPrice,
+			Type:           internal.FUTURE,
+			PriceType:      priceType,
+			InitialVolume:  contract.Volume(order.FilledSize.Add(order.UnfilledSize)),
+			Side:           side,
+			Status:         internal.OPEN,
+			Market:         market,
+			CreatedAt:      order.ReceivedTime,
+			ReduceOnly:     order.ReduceOnly,
+			ExecutedVolume: contract.Volume(order.FilledSize),
+			UpdatedAt:      order.LastUpdateTime,
+		})
+	}
+	return res, nil
+}
+
+// kraken futures doesn't list closed orders, so they are rebuilt
+// from the fills of the market, skipping orders still open
+func (c *krakenFuturesCli) GetClosedOrders(market internal.Market, since time.Time) ([]*entities.Order, error) {
+	contract, err := c.contract(market)
+	if err != nil {
+		return nil, err
+	}
+	open, err := c.GetOpenOrders(market)
+	if err != nil {
+		return nil, err
+	}
+	skip := map[string]bool{}
+	for _, order := range open {
+		skip[order.RemoteId] = true
+	}
+	var resp struct {
+		Fills []krakenFuturesFill `json:"fills"`
+	}
+	if err := c.request(http.MethodGet, "/derivatives/api/v3/fills", url.Values{}, true, &resp); err != nil {
+		return nil, err
+	}
+	orders := map[string]*entities.Order{}
+	var res []*entities.Order
+	for _, fill := range resp.Fills {
+		if fill.Symbol != contract.Symbol || fill.FillTime.Before(since) || skip[fill.OrderId] {
+			continue
+		}
+		order, ok := orders[fill.OrderId]
+		if !ok {
+			side, ok := ISide(fill.Side)
+			if !ok {
+				logrus.Warnf("skipping %s fill of order %s", fill.Side, fill.OrderId)
+				continue
+			}
+			order = &entities.Order{
+				Id:        fill.ClientId,
+				RemoteId:  fill.OrderId,
+				Type:      internal.FUTURE,
+				Side:      side,
+				Status:    internal.FILLED,
+				Market:    market,
+				CreatedAt: fill.FillTime,
+			}
+			orders[fill.OrderId] = order
+			res = append(res, order)
+		}
+		volume := contract.Volume(fill.Size)
+		// cost of the previous fills plus the current one
+		cost := order.AveragePrice.Mul(order.ExecutedVolume).Add(fill.Price.Mul(volume))
+		order.ExecutedVolume = order.ExecutedVolume.Add(volume)
+		order.InitialVolume = order.ExecutedVolume
+		order.AveragePrice = cost.Div(order.ExecutedVolume)
+		if fill.FillTime.Before(order.CreatedAt) {
+			order.CreatedAt = fill.FillTime
+		}
+		if fill.FillTime.After(order.ClosedAt) {
+			order.ClosedAt = fill.FillTime
+			order.UpdatedAt = fill.FillTime
+		}
+	}
+	return res, nil
+}
+
+func (c *krakenFuturesCli) CancelOrder(remoteId string) error {
+	var resp struct {
+		CancelStatus struct {
+			Status string `json:"status"`
+		} `json:"cancelStatus"`
+	}
+	if err := c.request(http.MethodPost, "/derivatives/api/v3/cancelorder", url.Values{"order_id": {remoteId}}, true, &resp); err != nil {
+		return err
+	}
+	switch resp.CancelStatus.Status {
+	case "cancelled":
+		return nil
+	case "notFound":
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, remoteId)
+	default:
+		return fmt.Errorf("order %s not cancelled: %s", remoteId, resp.CancelStatus.Status)
+	}
+}
+
+func (c *krakenFuturesCli) CancelAll() (int, error) {
+	var resp struct {
+		CancelStatus struct {
+			CancelledOrders []struct {
+				OrderId string `json:"order_id"`
+			} `json:"cancelledOrders"`
+		} `json:"cancelStatus"`
+	}
+	if err := c.request(http.MethodPost, "/derivatives/api/v3/cancelallorders", url.Values{}, true, &resp); err != nil {
+		return 0, err
+	}
+	return len(resp.CancelStatus.CancelledOrders), nil
+}
+
+func (c *krakenFuturesCli) CancelAllAfter(timeout time.Duration) (time.Time, error) {
+	var resp struct {
+		Status krakenCancelAfter `json:"status"`
+	}
+	err := c.request(http.MethodPost, "/derivatives/api/v3/cancelallordersafter", url.Values{
+		"timeout": {fmt.Sprintf("%d", int(timeout.Seconds()))},
+	}, true, &resp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if resp.Status.TriggerTime == "0" || resp.Status.TriggerTime == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, resp.Status.TriggerTime)
+}
+
+// classifies the error codes returned by kraken futures
+func KrakenFuturesError(code string) error {
+	kind := ERROR_REJECTED
+	switch {
+	case code == "apiLimitExceeded":
+		kind = ERROR_RATE_LIMITED
+	case code == "authenticationError":
+		kind = ERROR_AUTH
+	case strings.Contains(code, "nonce"):
+		kind = ERROR_RETRYABLE
+	case code == "Unavailable", code == "marketUnavailable":
+		kind = ERROR_UNAVAILABLE
+	}
+	return &ExchangeError{
+		Kind:        kind,
+		Code:        code,
+		Message:     fmt.Sprintf("kraken futures error: %s", code),
+		Unprocessed: kind == ERROR_RATE_LIMITED || kind == ERROR_RETRYABLE,
+	}
+}
+
+// sends the request to kraken futures. private requests are signed
+// with the hmac sha512 of the sha256 of (params + nonce + endpoint path),
+// the endpoint path being the url path without the /derivatives prefix
+func (c *krakenFuturesCli) request(method string, path string, params url.Values, signed bool, response interface{}) error {
+	data := params.Encode()
+	var body io.Reader
+	target := KRAKEN_FUTURES_API_URL + path
+	if method == http.MethodGet {
+		if data != "" {
+			target += "?" + data
+		}
+	} else {
+		body = strings.NewReader(data)
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return err
+	}
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if signed {
+		nonce := fmt.Sprintf("%d", time.Now().UnixNano())
+		authent, err := c.sign(data + nonce + strings.TrimPrefix(path, "/derivatives"))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("APIKey", c.apiKey)
+		req.Header.Set("Nonce", nonce)
+		req.Header.Set("Authent", authent)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return networkError(err)
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return networkError(err)
+	}
+	// html error pages are returned while the exchange is down
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "application/json" {
+		return &ExchangeError{
+			Kind:    ERROR_UNAVAILABLE,
+			Message: fmt.Sprintf("%s %s failed: status %d, Content-Type %s", method, path, resp.StatusCode, mediaType),
+		}
+	}
+	var result krakenFuturesResponse
+	if err := json.Unmarshal(content, &result); err != nil {
+		// cut or garbled, the request may have been processed
+		return &ExchangeError{
+			Kind:    ERROR_RETRYABLE,
+			Message: fmt.Sprintf("%s %s failed: invalid response: %v", method, path, err),
+			Err:     err,
+		}
+	}
+	if result.Result == "error" {
+		return KrakenFuturesError(result.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		kind := ERROR_REJECTED
+		if resp.StatusCode >= http.StatusInternalServerError {
+			kind = ERROR_UNAVAILABLE
+		}
+		return &ExchangeError{Kind: kind, Message: fmt.Sprintf("kraken futures returned status %d: %s", resp.StatusCode, content)}
+	}
+	logrus.Tracef("[KRAKEN FUTURES] %s %s: %s", method, path, content)
+	return json.Unmarshal(content, response)
+}
+
+func (c *krakenFuturesCli) sign(message string) (string, error) {
+	secret, err := base64.StdEncoding.DecodeString(c.secret)
+	if err != nil {
+		return "", fmt.Errorf("invalid kraken futures secret: %w", err)
+	}
+	digest := sha256.Sum256([]byte(message))
+	mac := hmac.New(sha512.New, secret)
+	mac.Write(digest[:])
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// converts the interval in minutes into the charts resolution
+func KrakenFuturesResolution(interval int) (string, error) {
+	switch interval {
+	case 1, 5, 15, 30:
+		return fmt.Sprintf("%dm", interval), nil
+	case 60, 240, 720:
+		return fmt.Sprintf("%dh", interval/60), nil
+	case 1440:
+		return "1d", nil
+	case 10080:
+		return "1w", nil
+	default:
+		return "", fmt.Errorf("unsupported kraken futures interval %dm", interval)
+	}
+}
+
+// maps a futures market into the kraken futures symbol:
+// XBTUSDPERP -> PF_XBTUSD, XBTUSD241227 -> FF_XBTUSD_241227
+func FuturesSymbol(market internal.Market) string {
+	symbol := string(market)
+	switch {
+	case !market.IsFuture():
+		panic("unknown market")
+	case strings.HasSuffix(symbol, "PERP"):
+		return "PF_" + strings.TrimSuffix(symbol, "PERP")
+	default:
+		return "FF_" + symbol[:len(symbol)-6] + "_" + symbol[len(symbol)-6:]
+	}
+}
+
+// maps a kraken futures symbol into the market, only
+// multi-collateral contracts (PF_ and FF_) are supported
+func IFuturesSymbol(symbol string) (internal.Market, bool) {
+	parts := strings.Split(symbol, "_")
+	switch {
+	case len(parts) == 2 && parts[0] == "PF":
+		return internal.Market(parts[1] + "PERP"), true
+	case len(parts) == 3 && parts[0] == "FF":
+		market := internal.Market(parts[1] + parts[2])
+		return market, market.IsFuture()
+	default:
+		return "", false
+	}
+}
+
+// stop and take profit orders are sent to a distinct
+// endpoint of kraken futures and are not supported
+func FuturesType(order *entities.Order) (string, error) {
+	switch order.PriceType {
+	case internal.MARKET:
+		return "mkt", nil
+	case internal.LIMIT:
+		if order.PostOnly {
+			return "post", nil
+		}
+		if order.Ioc {
+			return "ioc", nil
+		}
+		return "lmt", nil
+	default:
+		return "", fmt.Errorf("%w: %s order on kraken futures", ErrNotSupported, order.PriceType)
+	}
+}
+
+func IFuturesType(t string) (internal.PriceType, bool) {
+	switch t {
+	case "mkt":
+		return internal.MARKET, true
+	case "lmt", "post", "ioc":
+		return internal.LIMIT, true
+	case "stop":
+		return internal.STOP_LOSS, true
+	case "take_profit":
+		return internal.TAKE_PROFIT, true
+	default:
+		return "", false
+	}
+}
+
+func IFuturesStatus(status string) (internal.OrderStatus, bool) {
+	switch status {
+	case "ENTERED_BOOK", "TRIGGER_PLACED":
+		return internal.OPEN, true
+	case "FULLY_EXECUTED":
+		return internal.FILLED, true
+	case "CANCELLED":
+		return internal.CANCELLED, true
+	case "REJECTED", "TRIGGER_ACTIVATION_FAILURE":
+		return internal.ERROR, true
+	default:
+		return "", false
+	}
+}
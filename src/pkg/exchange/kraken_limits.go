@@ -0,0 +1,211 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+)
+
+type KrakenTier string
+
+const (
+	KRAKEN_TIER_STARTER      KrakenTier = "starter"
+	KRAKEN_TIER_INTERMEDIATE KrakenTier = "intermediate"
+	KRAKEN_TIER_PRO          KrakenTier = "pro"
+)
+
+type krakenTierLimits struct {
+	apiMax      float64
+	apiDecay    float64
+	ordersMax   float64
+	ordersDecay float64
+}
+
+// rest api counter and matching engine (per pair) limits of each tier
+var krakenTiers = map[KrakenTier]krakenTierLimits{
+	KRAKEN_TIER_STARTER:      {apiMax: 15, apiDecay: 0.33, ordersMax: 60, ordersDecay: 1},
+	KRAKEN_TIER_INTERMEDIATE: {apiMax: 20, apiDecay: 0.5, ordersMax: 125, ordersDecay: 2.34},
+	KRAKEN_TIER_PRO:          {apiMax: 20, apiDecay: 1, ordersMax: 180, ordersDecay: 3.75},
+}
+
+// methods counted by the matching engine limits instead of the api counter
+var krakenOrderMethods = map[string]bool{
+	"AddOrder":    true,
+	"CancelOrder": true,
+}
+
+// methods managing orders, never starved by data polling
+var krakenHighPriority = map[string]bool{
+	"AddOrder":             true,
+	"CancelOrder":          true,
+	"CancelAll":            true,
+	"CancelAllOrdersAfter": true,
+	"OpenOrders":           true,
+	"QueryOrders":          true,
+	"GetWebSocketsToken":   true,
+}
+
+// history calls cost twice as much
+var krakenHistoryMethods = map[string]bool{
+	"Ledgers":       true,
+	"QueryLedgers":  true,
+	"TradesHistory": true,
+	"QueryTrades":   true,
+}
+
+// kraken rate limits shared by every client of the same account:
+//   - the private api counter, decaying according to the tier
+//   - the matching engine counter of each pair, for order placement
+//     and cancellation (cancelling young orders costs more)
+//   - the public endpoints, about one call per second
+type KrakenLimits struct {
+	tier   krakenTierLimits
+	api    IRateLimiter
+	public IRateLimiter
+	mu     sync.Mutex
+	orders map[internal.Market]IRateLimiter
+	placed map[string]krakenPlacedOrder // by txid
+}
+
+type krakenPlacedOrder struct {
+	market internal.Market
+	at     time.Time
+}
+
+func NewKrakenLimits(tier KrakenTier) *KrakenLimits {
+	limits, ok := krakenTiers[tier]
+	if !ok {
+		panic("unknown kraken tier")
+	}
+	return &KrakenLimits{
+		tier:   limits,
+		api:    NewDecayingLimiter(limits.apiMax, limits.apiDecay, limits.apiMax/4),
+		public: NewDecayingLimiter(1, 1, 0),
+		orders: map[internal.Market]IRateLimiter{},
+		placed: map[string]krakenPlacedOrder{},
+	}
+}
+
+// waits for the budget of a private api method
+func (l *KrakenLimits) waitPrivate(method string) {
+	if krakenOrderMethods[method] {
+		return
+	}
+	priority := PRIORITY_LOW
+	if krakenHighPriority[method] {
+		priority = PRIORITY_HIGH
+	}
+	cost := 1.0
+	if krakenHistoryMethods[method] {
+		cost = 2
+	}
+	l.api.Wait(priority, cost)
+}
+
+func (l *KrakenLimits) waitPublic() {
+	l.public.Wait(PRIORITY_LOW, 1)
+}
+
+// waits for the matching engine budget of the pair
+func (l *KrakenLimits) waitAddOrder(market internal.Market) {
+	l.market(market).Wait(PRIORITY_HIGH, 1)
+}
+
+// records the placement time, which defines the cancellation cost.
+// orders older than 5 minutes are free to cancel and forgotten
+func (l *KrakenLimits) recordPlaced(market internal.Market, txids []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for txid, order := range l.placed {
+		if KrakenCancelCost(time.Since(order.at)) == 0 {
+			delete(l.placed, txid)
+		}
+	}
+	for _, txid := range txids {
+		l.placed[txid] = krakenPlacedOrder{market: market, at: time.Now()}
+	}
+}
+
+// waits for the matching engine budget of the cancellation,
+// orders not placed by this process are not accounted
+func (l *KrakenLimits) waitCancel(txid string) {
+	l.mu.Lock()
+	order, ok := l.placed[txid]
+	delete(l.placed, txid)
+	l.mu.Unlock()
+	if !ok {
+		return
+	}
+	if cost := KrakenCancelCost(time.Since(order.at)); cost > 0 {
+		l.market(order.market).Wait(PRIORITY_HIGH, cost)
+	}
+}
+
+// the counter is full once kraken rejects a call for rate limit,
+// so that the next calls back off
+func (l *KrakenLimits) checkError(err error) {
+	if errors.Is(err, ErrRateLimited) {
+		l.api.Consume(l.tier.apiMax)
+	}
+}
+
+func (l *KrakenLimits) market(market internal.Market) IRateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limiter, ok := l.orders[market]
+	if !ok {
+		limiter = NewDecayingLimiter(l.tier.ordersMax, l.tier.ordersDecay, 0)
+		l.orders[market] = limiter
+	}
+	return limiter
+}
+
+// returns the budget usage of every limiter, implementation of IRateMonitor
+func (l *KrakenLimits) Usage() map[string]RateUsage {
+	res := map[string]RateUsage{
+		"api":    l.api.Usage(),
+		"public": l.public.Usage(),
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for market, limiter := range l.orders {
+		res[fmt.Sprintf("orders %s", market)] = limiter.Usage()
+	}
+	return res
+}
+
+// matching engine cost of cancelling an order given its age
+func KrakenCancelCost(age time.Duration) float64 {
+	switch {
+	case age < 5*time.Second:
+		return 8
+	case age < 10*time.Second:
+		return 6
+	case age < 15*time.Second:
+		return 5
+	case age < 45*time.Second:
+		return 4
+	case age < 90*time.Second:
+		return 2
+	case age < 300*time.Second:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func IKrakenTier(tier string) KrakenTier {
+	switch tier {
+	case string(KRAKEN_TIER_STARTER):
+		return KRAKEN_TIER_STARTER
+	case string(KRAKEN_TIER_INTERMEDIATE):
+		return KRAKEN_TIER_INTERMEDIATE
+	case string(KRAKEN_TIER_PRO):
+		return KRAKEN_TIER_PRO
+	default:
+		panic("unknown kraken tier")
+	}
+}
@@ -0,0 +1,225 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+var ErrOrderNotFound = errors.New("order not found")
+
+type krakenOrderDescription struct {
+	Pair      string          `json:"pair"`
+	Type      string          `json:"type"`
+	OrderType string          `json:"ordertype"`
+	Price     decimal.Decimal `json:"price"`
+	Leverage  string          `json:"leverage"`
+}
+
+type krakenOrder struct {
+	ClientId       string                 `json:"cl_ord_id"`
+	Status         string                 `json:"status"`
+	OpenTime       float64                `json:"opentm"`
+	CloseTime      float64                `json:"closetm"`
+	Description    krakenOrderDescription `json:"descr"`
+	Volume         decimal.Decimal        `json:"vol"`
+	VolumeExecuted decimal.Decimal        `json:"vol_exec"`
+	Fee            decimal.Decimal        `json:"fee"`
+	Price          decimal.Decimal        `json:"price"` // average execution price
+	Reason         string                 `json:"reason"`
+}
+
+type krakenOpenOrders struct {
+	Open map[string]krakenOrder `json:"open"`
+}
+
+type krakenClosedOrders struct {
+	Closed map[string]krakenOrder `json:"closed"`
+	Count  int                    `json:"count"`
+}
+
+// gets an order from kraken given the client id,
+// looking into the open orders first
+func (c *krakenCli) GetOrder(id string) (*entities.Order, error) {
+	var open krakenOpenOrders
+	if err := c.query("OpenOrders", map[string]string{"cl_ord_id": id}, &open); err != nil {
+		return nil, err
+	}
+	for txid, order := range open.Open {
+		return toOrder(txid, order)
+	}
+	var closed krakenClosedOrders
+	if err := c.query("ClosedOrders", map[string]string{"cl_ord_id": id}, &closed); err != nil {
+		return nil, err
+	}
+	for txid, order := range closed.Closed {
+		return toOrder(txid, order)
+	}
+	return nil, fmt.Errorf("%w: client id %s", ErrOrderNotFound, id)
+}
+
+// gets an order from kraken given the txid
+func (c *krakenCli) GetRemoteOrder(remoteId string) (*entities.Order, error) {
+	resp := map[string]krakenOrder{}
+	if err := c.query("QueryOrders", map[string]string{"txid": remoteId}, &resp); err != nil {
+		return nil, err
+	}
+	order, ok := resp[remoteId]
+	if !ok {
+		return nil, fmt.Errorf("%w: txid %s", ErrOrderNotFound, remoteId)
+	}
+	return toOrder(remoteId, order)
+}
+
+func (c *krakenCli) GetOpenOrders(market internal.Market) ([]*entities.Order, error) {
+	var resp krakenOpenOrders
+	if err := c.query("OpenOrders", map[string]string{}, &resp); err != nil {
+		return nil, err
+	}
+	return filterOrders(resp.Open, market), nil
+}
+
+// retrieves the closed orders, paginating through
+// the results (kraken returns 50 orders per page).
+// the offset advances by the page size, since orders
+// closed meanwhile shift the pages and may be repeated
+func (c *krakenCli) GetClosedOrders(market internal.Market, since time.Time) ([]*entities.Order, error) {
+	all := map[string]krakenOrder{}
+	ofs := 0
+	for {
+		var resp krakenClosedOrders
+		err := c.query("ClosedOrders", map[string]string{
+			"start": fmt.Sprintf("%d", since.Unix()),
+			"ofs":   fmt.Sprintf("%d", ofs),
+		}, &resp)
+		if err != nil {
+			return nil, err
+		}
+		for txid, order := range resp.Closed {
+			all[txid] = order
+		}
+		ofs += len(resp.Closed)
+		if len(resp.Closed) == 0 || ofs >= resp.Count {
+			break
+		}
+	}
+	return filterOrders(all, market), nil
+}
+
+// orders which cannot be converted (e.g. trailing stops
+// placed from the kraken ui) are skipped
+func filterOrders(orders map[string]krakenOrder, market internal.Market) []*entities.Order {
+	var res []*entities.Order
+	for txid, order := range orders {
+		if pair, ok := IAltPair(order.Description.Pair); !ok || pair != market {
+			continue
+		}
+		converted, err := toOrder(txid, order)
+		if err != nil {
+			logrus.Warnf("skipping order %s: %v", txid, err)
+			continue
+		}
+		res = append(res, converted)
+	}
+	return res
+}
+
+// converts a kraken order into the application order
+func toOrder(txid string, order krakenOrder) (*entities.Order, error) {
+	priceType, ok := IType(order.Description.OrderType)
+	if !ok {
+		return nil, fmt.Errorf("unsupported order type %s", order.Description.OrderType)
+	}
+	side, ok := ISide(order.Description.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown side %s", order.Description.Type)
+	}
+	status, ok := IStatus(KrakenOrderStatus(order.Status))
+	if !ok {
+		return nil, fmt.Errorf("unknown order status %s", order.Status)
+	}
+	market, _ := IAltPair(order.Description.Pair)
+	orderType := internal.SPOT
+	leverage := int64(0)
+	if order.Description.Leverage != "" && order.Description.Leverage != "none" {
+		orderType = internal.MARGIN
+		fmt.Sscanf(strings.Split(order.Description.Leverage, ":")[0], "%d", &leverage)
+	}
+	res := &entities.Order{
+		Id:             order.ClientId,
+		RemoteId:       txid,
+		LimitPrice:     order.Description.Price,
+		Type:           orderType,
+		PriceType:      priceType,
+		InitialVolume:  order.Volume,
+		Side:           side,
+		Status:         status,
+		Market:         market,
+		CreatedAt:      krakenTime(order.OpenTime),
+		Leverage:       leverage,
+		ExecutedVolume: order.VolumeExecuted,
+		AveragePrice:   order.Price,
+		Fee:            order.Fee,
+		UpdatedAt:      krakenTime(order.OpenTime),
+	}
+	if order.CloseTime > 0 {
+		res.ClosedAt = krakenTime(order.CloseTime)
+		res.UpdatedAt = res.ClosedAt
+	}
+	return res, nil
+}
+
+func krakenTime(ts float64) time.Time {
+	return time.UnixMicro(int64(ts * 1e6))
+}
+
+type krakenCancel struct {
+	Count   int  `json:"count"`
+	Pending bool `json:"pending"`
+}
+
+// trigger time is "0" once the switch is disarmed
+type krakenCancelAfter struct {
+	CurrentTime string `json:"currentTime"`
+	TriggerTime string `json:"triggerTime"`
+}
+
+func (c *krakenCli) CancelOrder(remoteId string) error {
+	c.limits.waitCancel(remoteId)
+	var resp krakenCancel
+	if err := c.query("CancelOrder", map[string]string{"txid": remoteId}, &resp); err != nil {
+		return err
+	}
+	if resp.Count == 0 && !resp.Pending {
+		return fmt.Errorf("%w: txid %s", ErrOrderNotFound, remoteId)
+	}
+	return nil
+}
+
+func (c *krakenCli) CancelAll() (int, error) {
+	var resp krakenCancel
+	if err := c.query("CancelAll", map[string]string{}, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+func (c *krakenCli) CancelAllAfter(timeout time.Duration) (time.Time, error) {
+	var resp krakenCancelAfter
+	err := c.query("CancelAllOrdersAfter", map[string]string{
+		"timeout": fmt.Sprintf("%d", int(timeout.Seconds())),
+	}, &resp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if resp.TriggerTime == "0" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, resp.TriggerTime)
+}
@@ -0,0 +1,238 @@
+package exchange
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+)
+
+// metadata of a kraken pair. the market is named after the pair
+// altname (e.g. XBTEUR), while the rest api names the pairs after
+// the asset ids (e.g. XXBTZEUR) and the websocket api after the
+// wsname (e.g. XBT/EUR)
+type KrakenPair struct {
+	Market        internal.Market
+	Name          string
+	WsName        string
+	Base          internal.Currency
+	Quote         internal.Currency
+	PairDecimals  int
+	LotDecimals   int
+	LotMultiplier int
+	TickSize      decimal.Decimal
+	OrderMin      decimal.Decimal
+	CostMin       decimal.Decimal
+	LeverageBuy   []int
+	LeverageSell  []int
+	MaxLeverage   decimal.Decimal // zero for pairs not tradeable on margin
+	Status        internal.MarketStatus
+	Fees          entities.Fees
+}
+
+// asset pair as returned by the AssetPairs endpoint
+type krakenAssetPair struct {
+	Altname       string          `json:"altname"`
+	WsName        string          `json:"wsname"`
+	Base          string          `json:"base"`
+	Quote         string          `json:"quote"`
+	PairDecimals  int             `json:"pair_decimals"`
+	LotDecimals   int             `json:"lot_decimals"`
+	LotMultiplier int             `json:"lot_multiplier"`
+	LeverageBuy   []int           `json:"leverage_buy"`
+	LeverageSell  []int           `json:"leverage_sell"`
+	OrderMin      decimal.Decimal `json:"ordermin"`
+	CostMin       decimal.Decimal `json:"costmin"`
+	TickSize      decimal.Decimal `json:"tick_size"`
+	Status        string          `json:"status"`
+	// [volume, percent fee] tiers
+	Fees      [][]decimal.Decimal `json:"fees"`
+	FeesMaker [][]decimal.Decimal `json:"fees_maker"`
+}
+
+// asset as returned by the Assets endpoint
+type krakenAsset struct {
+	Altname  string `json:"altname"`
+	Decimals int    `json:"decimals"`
+}
+
+// registry of the kraken pairs and assets, translating between the
+// market names of the application and the names used by kraken
+type IKrakenPairs interface {
+	// replaces the registry content with the AssetPairs and Assets results
+	Load(pairs map[string]krakenAssetPair, assets map[string]krakenAsset)
+	Get(market internal.Market) (*KrakenPair, bool)
+	// looks the pair up by rest name, altname or wsname
+	Find(name string) (*KrakenPair, bool)
+	// returns the currency given the kraken asset id or altname
+	Currency(asset string) internal.Currency
+}
+
+type krakenPairs struct {
+	mu      sync.RWMutex
+	markets map[internal.Market]*KrakenPair
+	names   map[string]*KrakenPair
+	assets  map[string]internal.Currency
+}
+
+// pairs known before the registry is loaded from kraken
+var krakenDefaultPairs = []*KrakenPair{
+	{Market: internal.XBTEUR, Name: "XXBTZEUR", WsName: "XBT/EUR", Base: internal.XBT, Quote: internal.EUR, MaxLeverage: decimal.NewFromInt(5)},
+	{Market: internal.XBTUSD, Name: "XXBTZUSD", WsName: "XBT/USD", Base: internal.XBT, Quote: internal.USD, MaxLeverage: decimal.NewFromInt(5)},
+	{Market: internal.XBTUSDT, Name: "XBTUSDT", WsName: "XBT/USDT", Base: internal.XBT, Quote: internal.USDT, MaxLeverage: decimal.NewFromInt(5)},
+	{Market: internal.ETHEUR, Name: "XETHZEUR", WsName: "ETH/EUR", Base: internal.ETH, Quote: internal.EUR, MaxLeverage: decimal.NewFromInt(5)},
+	{Market: internal.ETHUSD, Name: "XETHZUSD", WsName: "ETH/USD", Base: internal.ETH, Quote: internal.USD, MaxLeverage: decimal.NewFromInt(5)},
+	{Market: internal.LTCEUR, Name: "XLTCZEUR", WsName: "LTC/EUR", Base: internal.LTC, Quote: internal.EUR, MaxLeverage: decimal.NewFromInt(3)},
+}
+
+var krakenDefaultAssets = map[string]internal.Currency{
+	"XXBT": internal.XBT,
+	"XETH": internal.ETH,
+	"XLTC": internal.LTC,
+	"ZEUR": internal.EUR,
+	"ZUSD": internal.USD,
+	"USDT": internal.USDT,
+}
+
+// registry shared by the kraken clients and feeds,
+// loaded by GetMarketsData
+var KrakenPairs = NewKrakenPairs()
+
+func NewKrakenPairs() IKrakenPairs {
+	r := &krakenPairs{}
+	r.reset(krakenDefaultAssets)
+	for _, pair := range krakenDefaultPairs {
+		r.add(pair)
+	}
+	return r
+}
+
+func (r *krakenPairs) Load(pairs map[string]krakenAssetPair, assets map[string]krakenAsset) {
+	currencies := map[string]internal.Currency{}
+	for id, asset := range assets {
+		currencies[id] = internal.Currency(asset.Altname)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reset(currencies)
+	for name, info := range pairs {
+		// dark pool pairs (e.g. XBTEUR.d) are not traded
+		if strings.Contains(name, ".") {
+			continue
+		}
+		pair := &KrakenPair{
+			Market:        internal.Market(info.Altname),
+			Name:          name,
+			WsName:        info.WsName,
+			Base:          r.currency(info.Base),
+			Quote:         r.currency(info.Quote),
+			PairDecimals:  info.PairDecimals,
+			LotDecimals:   info.LotDecimals,
+			LotMultiplier: info.LotMultiplier,
+			TickSize:      info.TickSize,
+			OrderMin:      info.OrderMin,
+			CostMin:       info.CostMin,
+			LeverageBuy:   info.LeverageBuy,
+			LeverageSell:  info.LeverageSell,
+			MaxLeverage:   decimal.Zero,
+			Status:        IKrakenPairStatus(info.Status),
+			Fees:          krakenBaseFees(info),
+		}
+		if pair.TickSize.IsZero() {
+			pair.TickSize = decimal.New(1, -int32(info.PairDecimals))
+		}
+		for _, leverage := range info.LeverageBuy {
+			if l := decimal.NewFromInt(int64(leverage)); l.GreaterThan(pair.MaxLeverage) {
+				pair.MaxLeverage = l
+			}
+		}
+		r.add(pair)
+	}
+}
+
+func (r *krakenPairs) Get(market internal.Market) (*KrakenPair, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pair, ok := r.markets[market]
+	return pair, ok
+}
+
+func (r *krakenPairs) Find(name string) (*KrakenPair, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	pair, ok := r.names[name]
+	return pair, ok
+}
+
+func (r *krakenPairs) Currency(asset string) internal.Currency {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.currency(asset)
+}
+
+// assets missing from the registry are named as they are
+func (r *krakenPairs) currency(asset string) internal.Currency {
+	if currency, ok := r.assets[asset]; ok {
+		return currency
+	}
+	return internal.Currency(asset)
+}
+
+func (r *krakenPairs) reset(assets map[string]internal.Currency) {
+	r.markets = map[internal.Market]*KrakenPair{}
+	r.names = map[string]*KrakenPair{}
+	r.assets = map[string]internal.Currency{}
+	for id, currency := range assets {
+		r.assets[id] = currency
+		r.assets[string(currency)] = currency
+	}
+}
+
+func (r *krakenPairs) add(pair *KrakenPair) {
+	r.markets[pair.Market] = pair
+	r.names[string(pair.Market)] = pair
+	r.names[pair.Name] = pair
+	if pair.WsName != "" {
+		r.names[pair.WsName] = pair
+	}
+}
+
+// fees of the lowest volume tier, margin fees are not listed
+func krakenBaseFees(info krakenAssetPair) entities.Fees {
+	fees := entities.DEFAULT_FEES
+	fees.MarginOpen = KRAKEN_MARGIN_OPEN_FEE
+	fees.MarginRollover = KRAKEN_ROLLOVER_FEE
+	if len(info.Fees) > 0 && len(info.Fees[0]) == 2 {
+		fees.Taker = info.Fees[0][1].Div(decimal.NewFromInt(100))
+	}
+	if len(info.FeesMaker) > 0 && len(info.FeesMaker[0]) == 2 {
+		fees.Maker = info.FeesMaker[0][1].Div(decimal.NewFromInt(100))
+	}
+	return fees
+}
+
+func (p *KrakenPair) String() string {
+	return fmt.Sprintf("%s (%s, %s)", p.Market, p.Name, p.WsName)
+}
+
+// maps the AssetPairs status, pairs in
+// statuses not known are not traded
+func IKrakenPairStatus(status string) internal.MarketStatus {
+	switch status {
+	case "", "online":
+		return internal.MARKET_ONLINE
+	case "cancel_only":
+		return internal.MARKET_CANCEL_ONLY
+	case "post_only":
+		return internal.MARKET_POST_ONLY
+	case "limit_only":
+		return internal.MARKET_LIMIT_ONLY
+	case "reduce_only":
+		return internal.MARKET_REDUCE_ONLY
+	default:
+		return internal.MARKET_OFFLINE
+	}
+}
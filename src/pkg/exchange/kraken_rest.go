@@ -0,0 +1,141 @@
+package exchange
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	KRAKEN_API_URL     = "https://api.kraken.com"
+	KRAKEN_API_VERSION = "0"
+)
+
+// nonces of the private requests must increase for every api key, the
+// generator is shared by every client (rest and websocket token) of
+// the process so that two requests never get the same nonce
+type krakenNonceGenerator struct {
+	mu   sync.Mutex
+	last int64
+}
+
+var krakenNonce = &krakenNonceGenerator{}
+
+func (g *krakenNonceGenerator) next() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	nonce := time.Now().UnixNano()
+	if nonce <= g.last {
+		nonce = g.last + 1
+	}
+	g.last = nonce
+	return strconv.FormatInt(nonce, 10)
+}
+
+// kraken rest api client, signing the private requests and decoding
+// the results into the typed responses. numbers are decoded from their
+// text, so the decimal.Decimal fields keep the exact values sent by kraken
+type krakenRest struct {
+	apiKey     string
+	secret     string
+	httpClient *http.Client
+}
+
+func newKrakenRest(apiKey string, secret string, httpClient *http.Client) *krakenRest {
+	return &krakenRest{apiKey: apiKey, secret: secret, httpClient: httpClient}
+}
+
+// queries a public method with the given query string
+func (r *krakenRest) public(method string, params url.Values, response interface{}) error {
+	target := fmt.Sprintf("%s/%s/public/%s", KRAKEN_API_URL, KRAKEN_API_VERSION, method)
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	return r.do(method, req, response)
+}
+
+// queries a private method, signed with the api secret: the signature is
+// the hmac-sha512 of the path and of the sha256 of nonce and post data
+func (r *krakenRest) private(method string, params url.Values, response interface{}) error {
+	values := url.Values{}
+	for key, value := range params {
+		values[key] = value
+	}
+	values.Set("nonce", krakenNonce.next())
+	path := fmt.Sprintf("/%s/private/%s", KRAKEN_API_VERSION, method)
+	signature, err := r.sign(path, values)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, KRAKEN_API_URL+path, strings.NewReader(values.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("API-Key", r.apiKey)
+	req.Header.Set("API-Sign", signature)
+	return r.do(method, req, response)
+}
+
+func (r *krakenRest) sign(path string, values url.Values) (string, error) {
+	secret, err := base64.StdEncoding.DecodeString(r.secret)
+	if err != nil {
+		return "", err
+	}
+	shaSum := sha256.Sum256([]byte(values.Get("nonce") + values.Encode()))
+	mac := hmac.New(sha512.New, secret)
+	mac.Write(append([]byte(path), shaSum[:]...))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// sends the request and decodes the {error, result} envelope. the
+// transport errors and the responses which aren't kraken ones are
+// classified here, the kraken error codes by KrakenError
+func (r *krakenRest) do(method string, req *http.Request, response interface{}) error {
+	req.Header.Set("User-Agent", "golang-hft")
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return networkError(err)
+	}
+	defer resp.Body.Close()
+	// html error pages are returned while the exchange is down
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "application/json" {
+		return &ExchangeError{
+			Kind:    ERROR_UNAVAILABLE,
+			Message: fmt.Sprintf("%s failed: status %d, Content-Type %s", method, resp.StatusCode, mediaType),
+		}
+	}
+	var body struct {
+		Error  []string        `json:"error"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		// cut (e.g. read timeout) or garbled, the request may have been processed
+		return &ExchangeError{
+			Kind:    ERROR_RETRYABLE,
+			Message: fmt.Sprintf("%s failed: invalid response: %v", method, err),
+			Err:     err,
+		}
+	}
+	if len(body.Error) > 0 {
+		return fmt.Errorf("%s failed: %s", method, strings.Join(body.Error, ", "))
+	}
+	if response == nil {
+		return nil
+	}
+	return json.Unmarshal(body.Result, response)
+}
@@ -0,0 +1,359 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const KRAKEN_WS_URL = "wss://ws.kraken.com"
+
+type krakenWsEvent struct {
+	Event        string `json:"event"`
+	Status       string `json:"status"`
+	Pair         string `json:"pair"`
+	ChannelName  string `json:"channelName"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+type krakenWsSubscription struct {
+	Name     string `json:"name"`
+	Interval int    `json:"interval,omitempty"`
+	Depth    int    `json:"depth,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+type krakenWsSubscribe struct {
+	Event        string               `json:"event"`
+	Pair         []string             `json:"pair,omitempty"`
+	Subscription krakenWsSubscription `json:"subscription"`
+}
+
+// kraken public websocket feed, implementation of IMarketFeed.
+// messages are dispatched to the subscriber channel by
+// channel name and pair
+type krakenFeed struct {
+	ws       *wsClient
+	mu       sync.Mutex
+	handlers map[string]func(payload []json.RawMessage) error
+	closers  []func()
+}
+
+func NewKrakenFeed(url string, heartbeatTimeout time.Duration, reconnectDelay time.Duration) IMarketFeed {
+	feed := &krakenFeed{
+		ws:       newWsClient("KRAKEN WS", url, heartbeatTimeout, reconnectDelay),
+		handlers: map[string]func(payload []json.RawMessage) error{},
+	}
+	feed.ws.onMessage = feed.dispatch
+	feed.ws.onClose = feed.closeChannels
+	feed.ws.start()
+	return feed
+}
+
+func (f *krakenFeed) SubscribeOHLC(pair internal.Market, interval int) (chan entities.Candle, error) {
+	result := make(chan entities.Candle)
+	err := f.subscribe(pair, krakenWsSubscription{Name: "ohlc", Interval: interval}, fmt.Sprintf("ohlc-%d", interval),
+		func(payload []json.RawMessage) error {
+			candle, err := parseWsOHLC(payload[1], interval)
+			if err != nil {
+				return err
+			}
+			select {
+			case result <- candle:
+			case <-f.ws.done:
+			}
+			return nil
+		}, func() { close(result) })
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (f *krakenFeed) SubscribeTrades(pair internal.Market) (chan entities.Trade, error) {
+	result := make(chan entities.Trade)
+	err := f.subscribe(pair, krakenWsSubscription{Name: "trade"}, "trade",
+		func(payload []json.RawMessage) error {
+			trades, err := parseWsTrades(payload[1], pair)
+			if err != nil {
+				return err
+			}
+			for _, trade := range trades {
+				select {
+				case result <- trade:
+				case <-f.ws.done:
+					return nil
+				}
+			}
+			return nil
+		}, func() { close(result) })
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (f *krakenFeed) SubscribeTicker(pair internal.Market) (chan entities.Ticker, error) {
+	result := make(chan entities.Ticker)
+	err := f.subscribe(pair, krakenWsSubscription{Name: "ticker"}, "ticker",
+		func(payload []json.RawMessage) error {
+			ticker, err := parseWsTicker(payload[1], pair)
+			if err != nil {
+				return err
+			}
+			select {
+			case result <- ticker:
+			case <-f.ws.done:
+			}
+			return nil
+		}, func() { close(result) })
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (f *krakenFeed) Close() {
+	f.ws.close()
+}
+
+// registers the handler for the channel and pair and sends
+// the subscription to kraken
+func (f *krakenFeed) subscribe(
+	pair internal.Market,
+	subscription krakenWsSubscription,
+	channel string,
+	handler func(payload []json.RawMessage) error,
+	closer func()) error {
+	key := channelKey(channel, WsPair(pair))
+	f.mu.Lock()
+	if f.ws.isClosed() {
+		f.mu.Unlock()
+		return fmt.Errorf("feed closed")
+	}
+	if _, ok := f.handlers[key]; ok {
+		f.mu.Unlock()
+		return fmt.Errorf("already subscribed to %s %s", channel, pair)
+	}
+	f.handlers[key] = handler
+	f.closers = append(f.closers, closer)
+	f.mu.Unlock()
+	msg := krakenWsSubscribe{Event: "subscribe", Pair: []string{WsPair(pair)}, Subscription: subscription}
+	return f.ws.subscribe(func() interface{} { return msg })
+}
+
+func (f *krakenFeed) dispatch(msg []byte) {
+	if len(msg) > 0 && msg[0] == '{' {
+		handleWsEvent("KRAKEN WS", msg)
+		return
+	}
+	var payload []json.RawMessage
+	if err := json.Unmarshal(msg, &payload); err != nil || len(payload) < 4 {
+		logrus.Warnf("[KRAKEN WS] unexpected message %s", msg)
+		return
+	}
+	var channel, pair string
+	if json.Unmarshal(payload[len(payload)-2], &channel) != nil || json.Unmarshal(payload[len(payload)-1], &pair) != nil {
+		logrus.Warnf("[KRAKEN WS] unexpected message %s", msg)
+		return
+	}
+	f.mu.Lock()
+	handler, ok := f.handlers[channelKey(channel, pair)]
+	f.mu.Unlock()
+	if !ok {
+		logrus.Debugf("[KRAKEN WS] no subscriber for %s %s", channel, pair)
+		return
+	}
+	if err := handler(payload); err != nil {
+		logrus.Warnf("[KRAKEN WS] error %v parsing %s message, skipping...", err, channel)
+	}
+}
+
+func (f *krakenFeed) closeChannels() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, closer := range f.closers {
+		closer()
+	}
+	f.closers = nil
+}
+
+// logs the kraken websocket events (heartbeat, status...)
+func handleWsEvent(name string, msg []byte) {
+	var event krakenWsEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		logrus.Warnf("[%s] unexpected message %s", name, msg)
+		return
+	}
+	switch event.Event {
+	case "heartbeat", "pong":
+		logrus.Tracef("[%s] %s", name, event.Event)
+	case "subscriptionStatus":
+		if event.Status == "error" {
+			logrus.Warnf("[%s] subscription to %s failed: %s", name, event.Pair, event.ErrorMessage)
+		} else {
+			logrus.Infof("[%s] %s %s %s", name, event.Status, event.ChannelName, event.Pair)
+		}
+	default:
+		logrus.Debugf("[%s] event %s", name, msg)
+	}
+}
+
+func channelKey(channel string, pair string) string {
+	return fmt.Sprintf("%s|%s", channel, pair)
+}
+
+// parses an ohlc update [time, etime, open, high, low, close, vwap, volume, count]
+// the candle timestamp is the begin of the interval, as in the rest api
+func parseWsOHLC(data json.RawMessage, interval int) (entities.Candle, error) {
+	var fields []interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return entities.Candle{}, err
+	}
+	if len(fields) < 9 {
+		return entities.Candle{}, fmt.Errorf("unexpected ohlc length %d", len(fields))
+	}
+	values, err := wsStrings(fields[:8])
+	if err != nil {
+		return entities.Candle{}, err
+	}
+	end, err := parseKrakenTime(values[1])
+	if err != nil {
+		return entities.Candle{}, err
+	}
+	prices, err := parseDecimals(values[2:8])
+	if err != nil {
+		return entities.Candle{}, err
+	}
+	count, ok := fields[8].(float64)
+	if !ok {
+		return entities.Candle{}, fmt.Errorf("unexpected ohlc count %v", fields[8])
+	}
+	start := end.Add(-time.Duration(interval) * time.Minute).Truncate(time.Second)
+	return entities.NewCandle(prices[0], prices[1], prices[2], prices[3], start).WithVolume(prices[5], prices[4], int(count)), nil
+}
+
+// parses a list of trades [price, volume, time, side, orderType, misc]
+func parseWsTrades(data json.RawMessage, pair internal.Market) ([]entities.Trade, error) {
+	var trades [][]string
+	if err := json.Unmarshal(data, &trades); err != nil {
+		return nil, err
+	}
+	var res []entities.Trade
+	for _, fields := range trades {
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("unexpected trade length %d", len(fields))
+		}
+		values, err := parseDecimals(fields[0:2])
+		if err != nil {
+			return nil, err
+		}
+		ts, err := parseKrakenTime(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		side := internal.BUY
+		if fields[3] == "s" {
+			side = internal.SELL
+		}
+		priceType := internal.MARKET
+		if fields[4] == "l" {
+			priceType = internal.LIMIT
+		}
+		res = append(res, entities.Trade{
+			Market:    pair,
+			Price:     values[0],
+			Volume:    values[1],
+			Side:      side,
+			PriceType: priceType,
+			Timestamp: ts,
+		})
+	}
+	return res, nil
+}
+
+// parses a ticker update, only ask, bid, last trade and 24h volume are kept
+func parseWsTicker(data json.RawMessage, pair internal.Market) (entities.Ticker, error) {
+	var fields map[string][]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return entities.Ticker{}, err
+	}
+	for _, key := range []string{"a", "b", "c", "v"} {
+		if len(fields[key]) < 2 {
+			return entities.Ticker{}, fmt.Errorf("unexpected ticker field %s", key)
+		}
+	}
+	if len(fields["a"]) < 3 || len(fields["b"]) < 3 {
+		return entities.Ticker{}, fmt.Errorf("unexpected ticker book fields")
+	}
+	values, err := wsStrings([]interface{}{
+		fields["a"][0], fields["a"][2],
+		fields["b"][0], fields["b"][2],
+		fields["c"][0], fields["v"][1],
+	})
+	if err != nil {
+		return entities.Ticker{}, err
+	}
+	prices, err := parseDecimals(values)
+	if err != nil {
+		return entities.Ticker{}, err
+	}
+	return entities.Ticker{
+		Market:    pair,
+		Ask:       prices[0],
+		AskVolume: prices[1],
+		Bid:       prices[2],
+		BidVolume: prices[3],
+		Last:      prices[4],
+		Volume:    prices[5],
+		Timestamp: time.Now(),
+	}, nil
+}
+
+func wsStrings(fields []interface{}) ([]string, error) {
+	res := make([]string, len(fields))
+	for i, field := range fields {
+		value, ok := field.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected value %v", field)
+		}
+		res[i] = value
+	}
+	return res, nil
+}
+
+func parseDecimals(values []string) ([]decimal.Decimal, error) {
+	res := make([]decimal.Decimal, len(values))
+	for i, value := range values {
+		d, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = d
+	}
+	return res, nil
+}
+
+// parses kraken timestamps, expressed in seconds with decimals
+func parseKrakenTime(value string) (time.Time, error) {
+	parts := strings.SplitN(value, ".", 2)
+	seconds, err := decimal.NewFromString(parts[0])
+	if err != nil {
+		return time.Time{}, err
+	}
+	nanos := decimal.Zero
+	if len(parts) == 2 && parts[1] != "" {
+		nanos, err = decimal.NewFromString("0." + parts[1])
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(seconds.IntPart(), nanos.Shift(9).IntPart()), nil
+}
@@ -0,0 +1,230 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+const KRAKEN_WS_AUTH_URL = "wss://ws-auth.kraken.com/v2"
+
+type krakenWsV2Message struct {
+	Method  string          `json:"method"`
+	Success bool            `json:"success"`
+	Error   string          `json:"error"`
+	Channel string          `json:"channel"`
+	Type    string          `json:"type"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type krakenWsV2Subscribe struct {
+	Method string                    `json:"method"`
+	Params krakenWsV2SubscribeParams `json:"params"`
+}
+
+type krakenWsV2SubscribeParams struct {
+	Channel    string `json:"channel"`
+	Token      string `json:"token"`
+	SnapOrders bool   `json:"snap_orders"`
+	SnapTrades bool   `json:"snap_trades"`
+}
+
+type krakenWsExecution struct {
+	OrderId     string          `json:"order_id"`
+	ClientId    string          `json:"cl_ord_id"`
+	ExecType    string          `json:"exec_type"`
+	OrderStatus string          `json:"order_status"`
+	CumQty      decimal.Decimal `json:"cum_qty"`
+	AvgPrice    decimal.Decimal `json:"avg_price"`
+	Fees        []krakenWsFee   `json:"fees"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+type krakenWsFee struct {
+	Asset string          `json:"asset"`
+	Qty   decimal.Decimal `json:"qty"`
+}
+
+// kraken authenticated websocket feed, streaming the
+// executions channel as order updates. implementation of IOrdersFeed
+type krakenOrdersFeed struct {
+	ws      *wsClient
+	token   func() (string, error)
+	mu      sync.Mutex
+	current string
+	updates chan entities.OrderUpdate
+	// the snapshot sent on subscription only lists the open orders,
+	// the orders closed while disconnected are never reported
+	connected chan struct{}
+}
+
+// the token function is called before every connection,
+// as kraken tokens must be used within 15 minutes
+func NewKrakenOrdersFeed(url string, token func() (string, error), heartbeatTimeout time.Duration, reconnectDelay time.Duration) IOrdersFeed {
+	feed := &krakenOrdersFeed{
+		ws:        newWsClient("KRAKEN WS AUTH", url, heartbeatTimeout, reconnectDelay),
+		token:     token,
+		connected: make(chan struct{}, 1),
+	}
+	feed.ws.beforeConnect = feed.refreshToken
+	feed.ws.onMessage = feed.dispatch
+	feed.ws.onClose = feed.closeChannels
+	feed.ws.onConnect = func() {
+		select {
+		case feed.connected <- struct{}{}:
+		default:
+		}
+	}
+	feed.ws.start()
+	return feed
+}
+
+func (f *krakenOrdersFeed) SubscribeOrders() (chan entities.OrderUpdate, error) {
+	f.mu.Lock()
+	if f.ws.isClosed() {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("feed closed")
+	}
+	if f.updates != nil {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("already subscribed to executions")
+	}
+	f.updates = make(chan entities.OrderUpdate)
+	updates := f.updates
+	f.mu.Unlock()
+	err := f.ws.subscribe(func() interface{} {
+		return krakenWsV2Subscribe{
+			Method: "subscribe",
+			Params: krakenWsV2SubscribeParams{
+				Channel:    "executions",
+				Token:      f.getToken(),
+				SnapOrders: true,
+			},
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+func (f *krakenOrdersFeed) Connected() chan struct{} {
+	return f.connected
+}
+
+func (f *krakenOrdersFeed) Close() {
+	f.ws.close()
+}
+
+func (f *krakenOrdersFeed) refreshToken() error {
+	token, err := f.token()
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.current = token
+	return nil
+}
+
+func (f *krakenOrdersFeed) getToken() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.current
+}
+
+func (f *krakenOrdersFeed) dispatch(msg []byte) {
+	var message krakenWsV2Message
+	if err := json.Unmarshal(msg, &message); err != nil {
+		logrus.Warnf("[KRAKEN WS AUTH] unexpected message %s", msg)
+		return
+	}
+	if message.Method != "" {
+		if !message.Success {
+			logrus.Warnf("[KRAKEN WS AUTH] %s failed: %s", message.Method, message.Error)
+		} else {
+			logrus.Infof("[KRAKEN WS AUTH] %s succeeded", message.Method)
+		}
+		return
+	}
+	switch message.Channel {
+	case "heartbeat":
+		logrus.Tracef("[KRAKEN WS AUTH] heartbeat")
+	case "executions":
+		var executions []krakenWsExecution
+		if err := json.Unmarshal(message.Data, &executions); err != nil {
+			logrus.Warnf("[KRAKEN WS AUTH] error %v parsing executions, skipping...", err)
+			return
+		}
+		f.mu.Lock()
+		updates := f.updates
+		f.mu.Unlock()
+		for _, execution := range executions {
+			update, ok := executionUpdate(execution)
+			if !ok || updates == nil {
+				continue
+			}
+			select {
+			case updates <- update:
+			case <-f.ws.done:
+				return
+			}
+		}
+	default:
+		logrus.Debugf("[KRAKEN WS AUTH] message %s", msg)
+	}
+}
+
+func (f *krakenOrdersFeed) closeChannels() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.updates != nil {
+		close(f.updates)
+		f.updates = nil
+	}
+}
+
+// converts an execution into an order update, executions
+// not carrying an order status (e.g. amends) are skipped
+func executionUpdate(execution krakenWsExecution) (entities.OrderUpdate, bool) {
+	status, ok := IExecutionStatus(execution.OrderStatus)
+	if !ok {
+		logrus.Debugf("[KRAKEN WS AUTH] skipping %s execution for order %s", execution.ExecType, execution.OrderId)
+		return entities.OrderUpdate{}, false
+	}
+	fee := decimal.Zero
+	for _, f := range execution.Fees {
+		fee = fee.Add(f.Qty)
+	}
+	return entities.OrderUpdate{
+		Id:             execution.ClientId,
+		RemoteId:       execution.OrderId,
+		Status:         status,
+		ExecutedVolume: execution.CumQty,
+		AveragePrice:   execution.AvgPrice,
+		Fee:            fee,
+		Timestamp:      execution.Timestamp,
+	}, true
+}
+
+// maps the order status of the executions channel
+func IExecutionStatus(status string) (internal.OrderStatus, bool) {
+	switch status {
+	case "pending_new":
+		return internal.CREATED, true
+	case "new", "partially_filled":
+		return internal.OPEN, true
+	case "filled":
+		return internal.FILLED, true
+	case "canceled", "expired":
+		return internal.CANCELLED, true
+	default:
+		return "", false
+	}
+}
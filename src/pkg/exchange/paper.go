@@ -0,0 +1,434 @@
+package exchange
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+)
+
+// size of the buffer of the order updates channel
+const PAPER_UPDATES_BUFFER = 1024
+
+// simulated exchange used to run strategies without real money.
+// market data comes from the live exchange, while orders are filled
+// against the last known prices and tracked on a virtual account
+type IPaperExchange interface {
+	IExchange
+	IOrdersFeed
+	// updates the last price of the market with the closed candle,
+	// filling the resting limit orders crossed by it
+	UpdatePrice(market internal.Market, candle entities.Candle)
+	// returns the realized and unrealized pnl of the account
+	GetPnL() (decimal.Decimal, decimal.Decimal)
+}
+
+type paperPosition struct {
+	size      decimal.Decimal // signed, negative for short positions
+	openPrice decimal.Decimal
+	leverage  decimal.Decimal
+	openedAt  time.Time
+	fees      decimal.Decimal // paid by the fills still open
+}
+
+// a single cash balance is kept, so the simulated markets are
+// expected to share the same reference currency
+type paperExchange struct {
+	live      IMarketData
+	fee       decimal.Decimal
+	mu        sync.Mutex
+	cash      decimal.Decimal // initial balance + realized pnl - fees
+	realized  decimal.Decimal
+	prices    map[internal.Market]decimal.Decimal
+	positions map[internal.Market]*paperPosition
+	orders    map[string]*entities.Order // by remote id
+	sequence  int
+	updates   chan entities.OrderUpdate
+	closed    bool
+}
+
+// creates a paper exchange with the given balance, charging
+// the fee rate (e.g. 0.0026) on the cost of every fill
+func NewPaperExchange(live IMarketData, balance decimal.Decimal, fee decimal.Decimal) IPaperExchange {
+	return &paperExchange{
+		live:      live,
+		fee:       fee,
+		cash:      balance,
+		prices:    map[internal.Market]decimal.Decimal{},
+		positions: map[internal.Market]*paperPosition{},
+		orders:    map[string]*entities.Order{},
+	}
+}
+
+func (p *paperExchange) GetOHLC(pair internal.Market, interval int) ([]entities.Candle, error) {
+	return p.live.GetOHLC(pair, interval)
+}
+
+// the maker and taker fees are replaced by the simulated fee
+func (p *paperExchange) GetMarketsData(markets []internal.Market) (entities.IMarkets, error) {
+	result, err := p.live.GetMarketsData(markets)
+	if err != nil {
+		return result, err
+	}
+	for _, market := range markets {
+		fees := result.GetFees(market)
+		fees.Maker, fees.Taker = p.fee, p.fee
+		result.SetFees(market, fees)
+	}
+	return result, nil
+}
+
+func (p *paperExchange) GetLeverage(market internal.Market) decimal.Decimal {
+	return p.live.GetLeverage(market)
+}
+
+func (p *paperExchange) UpdatePrice(market internal.Market, candle entities.Candle) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prices[market] = candle.Close
+	for _, order := range p.orders {
+		if order.Market != market || order.IsFinal() {
+			continue
+		}
+		crossed := (order.Side == internal.BUY && candle.Low.LessThanOrEqual(order.LimitPrice)) ||
+			(order.Side == internal.SELL && candle.High.GreaterThanOrEqual(order.LimitPrice))
+		if crossed {
+			p.fill(order, order.LimitPrice, candle.Timestamp)
+		}
+	}
+}
+
+func (p *paperExchange) GetPnL() (decimal.Decimal, decimal.Decimal) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.realized, p.unrealized()
+}
+
+func (p *paperExchange) unrealized() decimal.Decimal {
+	res := decimal.Zero
+	for market, position := range p.positions {
+		if price, ok := p.prices[market]; ok {
+			res = res.Add(price.Sub(position.openPrice).Mul(position.size))
+		}
+	}
+	return res
+}
+
+func (p *paperExchange) GetBalance() (*entities.Balance, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	margin := decimal.Zero
+	for _, position := range p.positions {
+		margin = margin.Add(position.size.Abs().Mul(position.openPrice).Div(position.leverage))
+	}
+	equity := p.cash.Add(p.unrealized())
+	balance := &entities.Balance{
+		TradeBalance:  p.cash,
+		InitialMargin: margin,
+		FreeMargin:    equity.Sub(margin),
+		Equity:        equity,
+	}
+	if margin.IsPositive() {
+		balance.MarginLevel = equity.Div(margin).Mul(decimal.NewFromInt(100))
+	}
+	return balance, nil
+}
+
+func (p *paperExchange) GetOpenPositions(market internal.Market) ([]*entities.Position, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	position, ok := p.positions[market]
+	if !ok {
+		return []*entities.Position{}, nil
+	}
+	side := internal.BUY
+	if position.size.IsNegative() {
+		side = internal.SELL
+	}
+	res := &entities.Position{
+		Id:        string(market),
+		Size:      position.size.Abs(),
+		Side:      side,
+		OpenPrice: position.openPrice,
+		Market:    market,
+		Status:    internal.POPEN,
+		CreatedAt: position.openedAt,
+		Cost:      position.size.Abs().Mul(position.openPrice),
+		Leverage:  int(position.leverage.IntPart()),
+		Fee:       position.fees,
+	}
+	if price, ok := p.prices[market]; ok {
+		res.ClosePrice = price
+	}
+	return []*entities.Position{res}, nil
+}
+
+// market orders are filled at the last known price, limit orders
+// when the price crosses the limit. reduce only orders are capped
+// to the size of the opposite position
+func (p *paperExchange) PlaceOrder(order *entities.Order) (*entities.OrderAck, error) {
+	if order.PriceType != internal.MARKET && order.PriceType != internal.LIMIT {
+		return nil, fmt.Errorf("%w: %s orders in paper trading", ErrNotSupported, order.PriceType)
+	}
+	if !order.InitialVolume.IsPositive() {
+		return nil, fmt.Errorf("invalid order volume %s", order.InitialVolume)
+	}
+	price, err := p.price(order)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	volume := order.InitialVolume
+	if order.ReduceOnly {
+		volume = p.reducible(order)
+		if !volume.IsPositive() {
+			return nil, fmt.Errorf("no position of %s to reduce", order.Market)
+		}
+	}
+	p.sequence++
+	now := time.Now()
+	placed := *order
+	placed.RemoteId = fmt.Sprintf("PAPER-%d", p.sequence)
+	placed.InitialVolume = volume
+	placed.Status = internal.OPEN
+	placed.CreatedAt = now
+	placed.UpdatedAt = now
+	p.orders[placed.RemoteId] = &placed
+
+	switch {
+	case placed.PriceType == internal.MARKET:
+		p.fill(&placed, price, now)
+	case (placed.Side == internal.BUY && price.LessThanOrEqual(placed.LimitPrice)) ||
+		(placed.Side == internal.SELL && price.GreaterThanOrEqual(placed.LimitPrice)):
+		// marketable limit orders are filled at the best of the two prices
+		p.fill(&placed, price, now)
+	case placed.Ioc:
+		p.finish(&placed, internal.CANCELLED, now)
+	}
+	logrus.Infof("[PAPER] order %s %s: %s", placed.RemoteId, placed.Status, placed.String())
+	return &entities.OrderAck{
+		Id:          order.Id,
+		RemoteIds:   []string{placed.RemoteId},
+		Description: placed.String(),
+		Status:      placed.Status,
+	}, nil
+}
+
+// returns the last price of the market, falling back to the
+// order market price and then to the last live candle
+func (p *paperExchange) price(order *entities.Order) (decimal.Decimal, error) {
+	p.mu.Lock()
+	price, ok := p.prices[order.Market]
+	p.mu.Unlock()
+	if ok {
+		return price, nil
+	}
+	if order.MarketPrice.IsPositive() {
+		return order.MarketPrice, nil
+	}
+	candles, err := p.live.GetOHLC(order.Market, 1)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if len(candles) == 0 {
+		return decimal.Zero, fmt.Errorf("no price available for %s", order.Market)
+	}
+	return candles[len(candles)-1].Close, nil
+}
+
+func (p *paperExchange) reducible(order *entities.Order) decimal.Decimal {
+	position, ok := p.positions[order.Market]
+	if !ok || (order.Side == internal.BUY) == position.size.IsPositive() {
+		return decimal.Zero
+	}
+	return decimal.Min(order.InitialVolume, position.size.Abs())
+}
+
+// fills the whole order at the given price, updating the position
+// of the market and the cash balance
+func (p *paperExchange) fill(order *entities.Order, price decimal.Decimal, ts time.Time) {
+	signed := order.InitialVolume
+	if order.Side == internal.SELL {
+		signed = signed.Neg()
+	}
+	fee := price.Mul(order.InitialVolume).Mul(p.fee)
+	p.cash = p.cash.Sub(fee)
+	position, ok := p.positions[order.Market]
+	switch {
+	case !ok:
+		p.positions[order.Market] = &paperPosition{size: signed, openPrice: price, leverage: p.leverage(order), openedAt: ts, fees: fee}
+	case position.size.IsPositive() == signed.IsPositive():
+		// increasing the position, the open price is averaged
+		size := position.size.Add(signed)
+		position.openPrice = position.openPrice.Mul(position.size).Add(price.Mul(signed)).Div(size)
+		position.size = size
+		position.fees = position.fees.Add(fee)
+	default:
+		closing := decimal.Min(signed.Abs(), position.size.Abs())
+		pnl := price.Sub(position.openPrice).Mul(closing)
+		if position.size.IsNegative() {
+			pnl = pnl.Neg()
+		}
+		p.realized = p.realized.Add(pnl)
+		p.cash = p.cash.Add(pnl)
+		size := position.size.Add(signed)
+		switch {
+		case size.IsZero():
+			delete(p.positions, order.Market)
+		case size.IsPositive() != position.size.IsPositive():
+			// the position is reversed at the fill price
+			p.positions[order.Market] = &paperPosition{size: size, openPrice: price, leverage: p.leverage(order), openedAt: ts, fees: fee.Mul(size.Abs()).Div(signed.Abs())}
+		default:
+			position.fees = position.fees.Mul(size.Abs()).Div(position.size.Abs())
+			position.size = size
+		}
+	}
+	order.ExecutedVolume = order.InitialVolume
+	order.AveragePrice = price
+	order.Fee = fee
+	p.finish(order, internal.FILLED, ts)
+}
+
+func (p *paperExchange) leverage(order *entities.Order) decimal.Decimal {
+	if order.Leverage > 1 {
+		return decimal.NewFromInt(order.Leverage)
+	}
+	return decimal.NewFromInt(1)
+}
+
+// moves the order into a final state and notifies the subscriber
+func (p *paperExchange) finish(order *entities.Order, status internal.OrderStatus, ts time.Time) {
+	order.Status = status
+	order.UpdatedAt = ts
+	order.ClosedAt = ts
+	if p.updates == nil || p.closed {
+		return
+	}
+	update := entities.OrderUpdate{
+		Id:             order.Id,
+		RemoteId:       order.RemoteId,
+		Status:         status,
+		ExecutedVolume: order.ExecutedVolume,
+		AveragePrice:   order.AveragePrice,
+		Fee:            order.Fee,
+		Timestamp:      ts,
+	}
+	select {
+	case p.updates <- update:
+	default:
+		logrus.Warnf("[PAPER] updates buffer full, dropping update of order %s", order.RemoteId)
+	}
+}
+
+func (p *paperExchange) GetOrder(id string) (*entities.Order, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, order := range p.orders {
+		if order.Id == id {
+			res := *order
+			return &res, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: client id %s", ErrOrderNotFound, id)
+}
+
+func (p *paperExchange) GetRemoteOrder(remoteId string) (*entities.Order, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	order, ok := p.orders[remoteId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrOrderNotFound, remoteId)
+	}
+	res := *order
+	return &res, nil
+}
+
+func (p *paperExchange) GetOpenOrders(market internal.Market) ([]*entities.Order, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var res []*entities.Order
+	for _, order := range p.orders {
+		if order.Market == market && !order.IsFinal() {
+			found := *order
+			res = append(res, &found)
+		}
+	}
+	return res, nil
+}
+
+func (p *paperExchange) GetClosedOrders(market internal.Market, since time.Time) ([]*entities.Order, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var res []*entities.Order
+	for _, order := range p.orders {
+		if order.Market == market && order.IsFinal() && !order.ClosedAt.Before(since) {
+			found := *order
+			res = append(res, &found)
+		}
+	}
+	return res, nil
+}
+
+func (p *paperExchange) CancelOrder(remoteId string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	order, ok := p.orders[remoteId]
+	if !ok || order.IsFinal() {
+		return fmt.Errorf("%w: %s", ErrOrderNotFound, remoteId)
+	}
+	p.finish(order, internal.CANCELLED, time.Now())
+	return nil
+}
+
+func (p *paperExchange) CancelAll() (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	count := 0
+	for _, order := range p.orders {
+		if !order.IsFinal() {
+			p.finish(order, internal.CANCELLED, time.Now())
+			count++
+		}
+	}
+	return count, nil
+}
+
+// resting orders die with the process, there is no switch to arm
+func (p *paperExchange) CancelAllAfter(timeout time.Duration) (time.Time, error) {
+	return time.Time{}, ErrNotSupported
+}
+
+func (p *paperExchange) SubscribeOrders() (chan entities.OrderUpdate, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, fmt.Errorf("feed closed")
+	}
+	if p.updates != nil {
+		return nil, fmt.Errorf("already subscribed to orders")
+	}
+	p.updates = make(chan entities.OrderUpdate, PAPER_UPDATES_BUFFER)
+	return p.updates, nil
+}
+
+// the simulated orders are never disconnected
+func (p *paperExchange) Connected() chan struct{} {
+	return nil
+}
+
+func (p *paperExchange) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	if p.updates != nil {
+		close(p.updates)
+	}
+}
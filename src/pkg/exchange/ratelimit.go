@@ -0,0 +1,115 @@
+package exchange
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type Priority int
+
+const (
+	// order management calls, never starved by data polling
+	PRIORITY_HIGH Priority = iota
+	// market and account data polling
+	PRIORITY_LOW
+)
+
+// shortest sleep of a call waiting for budget
+const MIN_RATE_WAIT = 10 * time.Millisecond
+
+// budget usage of a rate limiter
+type RateUsage struct {
+	Counter float64
+	Max     float64
+	Waiting int // calls queued for budget
+}
+
+// rate limiter modelled on a counter increased by the
+// cost of every call and decaying at a constant rate
+type IRateLimiter interface {
+	// blocks until the cost fits into the budget, then consumes it
+	Wait(priority Priority, cost float64)
+	// adds the cost to the counter without waiting, for calls
+	// the exchange counted anyway (e.g. rejected by rate limit)
+	Consume(cost float64)
+	Usage() RateUsage
+}
+
+// exposes the budget usage of the rate limiters of an exchange
+type IRateMonitor interface {
+	Usage() map[string]RateUsage
+}
+
+// low priority calls can't use the reserved part of the budget
+// and wait while a high priority call is queued
+type decayingLimiter struct {
+	mu          sync.Mutex
+	max         float64
+	decay       float64 // per second
+	reserve     float64
+	counter     float64
+	updated     time.Time
+	waiting     int
+	highWaiting int
+}
+
+func NewDecayingLimiter(max float64, decay float64, reserve float64) IRateLimiter {
+	return &decayingLimiter{max: max, decay: decay, reserve: reserve, updated: time.Now()}
+}
+
+func (l *decayingLimiter) Wait(priority Priority, cost float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.waiting++
+	if priority == PRIORITY_HIGH {
+		l.highWaiting++
+	}
+	defer func() {
+		l.waiting--
+		if priority == PRIORITY_HIGH {
+			l.highWaiting--
+		}
+	}()
+	limit := l.max
+	if priority != PRIORITY_HIGH {
+		limit -= l.reserve
+	}
+	// calls costing more than the limit wait for the counter to be empty
+	needed := math.Min(cost, limit)
+	for {
+		l.update()
+		if l.counter+needed <= limit && (priority == PRIORITY_HIGH || l.highWaiting == 0) {
+			l.counter += cost
+			return
+		}
+		wait := time.Duration((l.counter + needed - limit) / l.decay * float64(time.Second))
+		if wait < MIN_RATE_WAIT {
+			wait = MIN_RATE_WAIT
+		}
+		l.mu.Unlock()
+		time.Sleep(wait)
+		l.mu.Lock()
+	}
+}
+
+func (l *decayingLimiter) Consume(cost float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.update()
+	l.counter += cost
+}
+
+func (l *decayingLimiter) Usage() RateUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.update()
+	return RateUsage{Counter: l.counter, Max: l.max, Waiting: l.waiting}
+}
+
+// decays the counter since the last update
+func (l *decayingLimiter) update() {
+	now := time.Now()
+	l.counter = math.Max(0, l.counter-now.Sub(l.updated).Seconds()*l.decay)
+	l.updated = now
+}
@@ -0,0 +1,235 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/shopspring/decimal"
+)
+
+const binanceSecret = "binance-secret"
+
+// local stand-in of the binance rest api, answering every
+// "METHOD path" with the configured body
+type binanceServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses map[string]string
+	requests  []*http.Request
+}
+
+func newBinanceServer(t *testing.T, responses map[string]string) *binanceServer {
+	s := &binanceServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		body, ok := s.responses[r.Method+" "+r.URL.Path]
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if signature := r.URL.Query().Get("signature"); signature != "" {
+			query := r.URL.RawQuery[:strings.Index(r.URL.RawQuery, "&signature=")]
+			mac := hmac.New(sha256.New, []byte(binanceSecret))
+			mac.Write([]byte(query))
+			if hex.EncodeToString(mac.Sum(nil)) != signature || r.Header.Get("X-MBX-APIKEY") != "binance-key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"code":-1022,"msg":"Signature for this request is not valid."}`))
+				return
+			}
+		}
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":-2013,"msg":"Order does not exist."}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	return s
+}
+
+func (s *binanceServer) client() exchange.IExchange {
+	target, _ := url.Parse(s.URL)
+	return exchange.NewBinanceCliWithClient("binance-key", binanceSecret, &http.Client{Transport: &redirectTransport{target: target}})
+}
+
+// returns a client with the markets of the exchange info loaded
+func (s *binanceServer) loadedClient(t *testing.T) exchange.IExchange {
+	s.mu.Lock()
+	s.responses["GET /api/v3/exchangeInfo"] = readFile(t, "testdata/binance_exchange_info.json")
+	s.mu.Unlock()
+	cli := s.client()
+	_, err := cli.GetMarketsData([]internal.Market{internal.XBTEUR, internal.ETHEUR})
+	requireNoError(t, err)
+	return cli
+}
+
+func (s *binanceServer) lastRequest(method string, path string) *http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.requests) - 1; i >= 0; i-- {
+		if s.requests[i].Method == method && s.requests[i].URL.Path == path {
+			return s.requests[i]
+		}
+	}
+	return nil
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", path, err)
+	}
+	return string(content)
+}
+
+func TestBinanceMarketsData(t *testing.T) {
+	server := newBinanceServer(t, map[string]string{"GET /api/v3/exchangeInfo": readFile(t, "testdata/binance_exchange_info.json")})
+	defer server.Close()
+
+	markets, err := server.client().GetMarketsData([]internal.Market{internal.XBTEUR, internal.ETHEUR})
+	requireNoError(t, err)
+
+	if markets.GetDecimals(internal.XBTEUR) != 5 || !markets.GetOrderMin(internal.XBTEUR).Equal(decimal.RequireFromString("0.00001")) {
+		t.Errorf("unexpected lot size %d %s", markets.GetDecimals(internal.XBTEUR), markets.GetOrderMin(internal.XBTEUR))
+	}
+	if !markets.GetTickSize(internal.XBTEUR).Equal(decimal.RequireFromString("0.01")) || !markets.GetMinCost(internal.XBTEUR).Equal(decimal.NewFromInt(5)) {
+		t.Errorf("unexpected price filters %s %s", markets.GetTickSize(internal.XBTEUR), markets.GetMinCost(internal.XBTEUR))
+	}
+	if !markets.GetMinCost(internal.ETHEUR).Equal(decimal.NewFromInt(10)) || markets.GetTradeCurrency(internal.ETHEUR) != internal.ETH || markets.GetReferenceCurrency(internal.ETHEUR) != internal.EUR {
+		t.Errorf("unexpected ETHEUR metadata %+v", markets.GetMetadata(internal.ETHEUR))
+	}
+	if _, err := server.client().GetMarketsData([]internal.Market{internal.XBTEUR, internal.LTCEUR}); err == nil {
+		t.Errorf("expected an error for markets not listed by binance")
+	}
+}
+
+func TestBinanceKlines(t *testing.T) {
+	server := newBinanceServer(t, map[string]string{"GET /api/v3/klines": readFile(t, "testdata/binance_klines.json")})
+	defer server.Close()
+
+	cli := server.loadedClient(t)
+	candles, err := cli.GetOHLC(internal.XBTEUR, 60)
+	requireNoError(t, err)
+
+	if len(candles) != 2 || !candles[1].Close.Equal(decimal.RequireFromString("34015")) || !candles[0].Timestamp.Equal(time.UnixMilli(1700000040000)) {
+		t.Errorf("unexpected candles %v", candles)
+	}
+	if !candles[0].Volume.Equal(decimal.RequireFromString("1.25")) || !candles[0].Vwap.Equal(decimal.RequireFromString("34005.5")) || candles[0].Count != 12 {
+		t.Errorf("unexpected candle volume %s", candles[0].String())
+	}
+	query := server.lastRequest("GET", "/api/v3/klines").URL.Query()
+	if query.Get("symbol") != "BTCEUR" || query.Get("interval") != "1h" {
+		t.Errorf("unexpected query %v", query)
+	}
+	if _, err := cli.GetOHLC(internal.XBTEUR, 7); err == nil {
+		t.Errorf("expected an error for unsupported intervals")
+	}
+	if _, err := cli.GetOHLC(internal.LTCEUR, 60); err == nil {
+		t.Errorf("expected an error for markets not retrieved")
+	}
+}
+
+func TestBinanceBalance(t *testing.T) {
+	server := newBinanceServer(t, map[string]string{"GET /api/v3/account": readFile(t, "testdata/binance_account.json")})
+	defer server.Close()
+
+	balance, err := server.loadedClient(t).GetBalance()
+	requireNoError(t, err)
+
+	if !balance.Currencies[internal.XBT].Equal(decimal.RequireFromString("0.12345678")) || !balance.Currencies[internal.EUR].Equal(decimal.RequireFromString("1500.25")) {
+		t.Errorf("unexpected balances %v", balance.Currencies)
+	}
+	if len(balance.Currencies) != 2 {
+		t.Errorf("expected unknown assets to be skipped, got %v", balance.Currencies)
+	}
+}
+
+func TestBinancePlaceAndCancelOrder(t *testing.T) {
+	server := newBinanceServer(t, map[string]string{
+		"POST /api/v3/order": `{"symbol":"BTCEUR","orderId":28,"orderListId":-1,"clientOrderId":"6d1b345e-2821-40e2-ad83-4ecb18a06876","transactTime":1700000000000,
+			"price":"34000.00000000","origQty":"0.01000000","executedQty":"0.00000000","cummulativeQuoteQty":"0.00000000","status":"NEW","timeInForce":"GTC","type":"LIMIT","side":"BUY"}`,
+		"GET /api/v3/order": `{"symbol":"BTCEUR","orderId":28,"clientOrderId":"6d1b345e-2821-40e2-ad83-4ecb18a06876","price":"34000.00000000","origQty":"0.01000000",
+			"executedQty":"0.01000000","cummulativeQuoteQty":"339.90000000","status":"FILLED","timeInForce":"GTC","type":"LIMIT","side":"BUY","time":1700000000000,"updateTime":1700000005000}`,
+		"DELETE /api/v3/order": `{"symbol":"BTCEUR","orderId":28,"clientOrderId":"6d1b345e-2821-40e2-ad83-4ecb18a06876","status":"CANCELED","type":"LIMIT","side":"BUY"}`,
+	})
+	defer server.Close()
+	cli := server.loadedClient(t)
+
+	ack, err := cli.PlaceOrder(&entities.Order{
+		Id:            "6d1b345e-2821-40e2-ad83-4ecb18a06876",
+		Market:        internal.XBTEUR,
+		Side:          internal.BUY,
+		PriceType:     internal.LIMIT,
+		InitialVolume: decimal.RequireFromString("0.01"),
+		LimitPrice:    decimal.NewFromInt(34000),
+	})
+	requireNoError(t, err)
+	if ack.RemoteId() != "BTCEUR-28" || ack.Status != internal.OPEN {
+		t.Errorf("unexpected ack %+v", ack)
+	}
+	query := server.lastRequest("POST", "/api/v3/order").URL.Query()
+	if query.Get("side") != "BUY" || query.Get("type") != "LIMIT" || query.Get("price") != "34000" || query.Get("timeInForce") != "GTC" || query.Get("newClientOrderId") == "" {
+		t.Errorf("unexpected query %v", query)
+	}
+
+	order, err := cli.GetRemoteOrder(ack.RemoteId())
+	requireNoError(t, err)
+	if order.Status != internal.FILLED || !order.AveragePrice.Equal(decimal.RequireFromString("33990")) || order.Market != internal.XBTEUR {
+		t.Errorf("unexpected order %+v", order)
+	}
+
+	requireNoError(t, cli.CancelOrder(ack.RemoteId()))
+	if query := server.lastRequest("DELETE", "/api/v3/order").URL.Query(); query.Get("symbol") != "BTCEUR" || query.Get("orderId") != "28" {
+		t.Errorf("unexpected query %v", query)
+	}
+	if _, err := cli.CancelAllAfter(time.Minute); !errors.Is(err, exchange.ErrNotSupported) {
+		t.Errorf("expected the dead man's switch not to be supported, got %v", err)
+	}
+}
+
+func TestBinanceOrderNotFound(t *testing.T) {
+	server := newBinanceServer(t, map[string]string{})
+	defer server.Close()
+	cli := server.loadedClient(t)
+
+	if _, err := cli.GetOrder("unknown"); !errors.Is(err, exchange.ErrOrderNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if _, err := cli.GetRemoteOrder("BTCEUR-1"); !errors.Is(err, exchange.ErrOrderNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestBinanceSkipsUnknownOrders(t *testing.T) {
+	server := newBinanceServer(t, map[string]string{
+		"GET /api/v3/openOrders": `[{"symbol":"BTCEUR","orderId":28,"clientOrderId":"a","price":"34000","origQty":"0.01","executedQty":"0","cummulativeQuoteQty":"0","status":"NEW","type":"LIMIT_MAKER","side":"BUY","time":1700000000000,"updateTime":1700000000000},
+			{"symbol":"BTCEUR","orderId":29,"clientOrderId":"b","price":"35000","origQty":"0.01","executedQty":"0","cummulativeQuoteQty":"0","status":"PENDING_NEW","type":"LIMIT","side":"SELL","time":1700000000000,"updateTime":1700000000000}]`,
+		"GET /api/v3/allOrders": `[{"symbol":"BTCEUR","orderId":30,"clientOrderId":"c","price":"34000","origQty":"0.01","executedQty":"0","cummulativeQuoteQty":"0","status":"EXPIRED_IN_MATCH","type":"LIMIT","side":"BUY","time":1700000000000,"updateTime":1700000005000},
+			{"symbol":"BTCEUR","orderId":31,"clientOrderId":"d","price":"34000","origQty":"0.01","executedQty":"0","cummulativeQuoteQty":"0","status":"CANCELED","type":"TRAILING_STOP","side":"BUY","time":1700000000000,"updateTime":1700000005000}]`,
+	})
+	defer server.Close()
+	cli := server.loadedClient(t)
+
+	open, err := cli.GetOpenOrders(internal.XBTEUR)
+	requireNoError(t, err)
+	if len(open) != 1 || open[0].RemoteId != "BTCEUR-28" || open[0].PriceType != internal.LIMIT {
+		t.Errorf("expected only the known open order, got %v", open)
+	}
+	closed, err := cli.GetClosedOrders(internal.XBTEUR, time.UnixMilli(1700000000000))
+	requireNoError(t, err)
+	if len(closed) != 1 || closed[0].RemoteId != "BTCEUR-30" || closed[0].Status != internal.CANCELLED {
+		t.Errorf("expected only the order expired in match, got %v", closed)
+	}
+}
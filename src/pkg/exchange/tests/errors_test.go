@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+)
+
+// retries without waiting, restoring the default policy at the end of the test
+func fastRetries(t *testing.T) {
+	policy := exchange.KrakenRetryPolicy
+	exchange.KrakenRetryPolicy = exchange.RetryPolicy{Attempts: 3, Delay: time.Millisecond, MaxDelay: time.Millisecond}
+	t.Cleanup(func() { exchange.KrakenRetryPolicy = policy })
+}
+
+// kraken client sending every request to the handler
+func krakenClient(handler http.HandlerFunc) (exchange.IExchange, func()) {
+	server := httptest.NewServer(handler)
+	target, _ := url.Parse(server.URL)
+	cli := exchange.NewKrakenCliWithClient("key", "c2VjcmV0", &http.Client{Transport: &redirectTransport{target: target}}, exchange.NewKrakenLimits(exchange.KRAKEN_TIER_PRO))
+	return cli, server.Close
+}
+
+func TestKrakenErrorClassification(t *testing.T) {
+	fastRetries(t)
+	server := newKrakenServer(map[string]string{})
+	defer server.Close()
+	cases := map[string]exchange.ErrorKind{
+		"EOrder:Insufficient funds":           exchange.ERROR_REJECTED,
+		"EAPI:Rate limit exceeded":            exchange.ERROR_RATE_LIMITED,
+		"EService:Busy":                       exchange.ERROR_RETRYABLE,
+		"EAPI:Invalid key":                    exchange.ERROR_AUTH,
+		"EService:Market in cancel_only mode": exchange.ERROR_UNAVAILABLE,
+		"EQuery:Unknown whatever":             exchange.ERROR_REJECTED,
+	}
+	for code, kind := range cases {
+		server.setError("TradeBalance", code)
+		// a new client for each case, the rate limit errors drain the budget
+		_, err := server.client().GetBalance()
+		if got := exchange.Kind(err); got != kind {
+			t.Errorf("%s: expected %s, got %s (%v)", code, kind, got, err)
+		}
+	}
+	server.setError("TradeBalance", "EOrder:Insufficient funds")
+	_, err := server.client().GetBalance()
+	if !errors.Is(err, exchange.ErrRejected) || errors.Is(err, exchange.ErrRetryable) {
+		t.Errorf("unexpected sentinel matching of %v", err)
+	}
+	if exchange.Kind(errors.New("unrelated")) != "" {
+		t.Errorf("expected unclassified errors to have no kind")
+	}
+}
+
+func TestKrakenConnectionRefusedUnprocessed(t *testing.T) {
+	fastRetries(t)
+	cli, closeServer := krakenClient(func(w http.ResponseWriter, r *http.Request) {})
+	closeServer()
+
+	_, err := cli.GetBalance()
+	var exchangeErr *exchange.ExchangeError
+	if !errors.As(err, &exchangeErr) || exchangeErr.Kind != exchange.ERROR_RETRYABLE || !exchangeErr.Unprocessed {
+		t.Errorf("expected an unprocessed retryable error, got %v", err)
+	}
+}
+
+func TestKrakenTruncatedResponseRetryable(t *testing.T) {
+	fastRetries(t)
+	calls := 0
+	cli, closeServer := krakenClient(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"error":[],"result":{"eb":"10`))
+	})
+	defer closeServer()
+
+	_, err := cli.GetBalance()
+	var exchangeErr *exchange.ExchangeError
+	if !errors.As(err, &exchangeErr) || exchangeErr.Kind != exchange.ERROR_RETRYABLE || exchangeErr.Unprocessed {
+		t.Errorf("expected an ambiguous retryable error, got %v", err)
+	}
+	if calls != exchange.KrakenRetryPolicy.Attempts {
+		t.Errorf("expected the idempotent call to be retried, got %d calls", calls)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := exchange.RetryPolicy{Attempts: 3, Delay: time.Millisecond, MaxDelay: time.Millisecond}
+	count := func(idempotent bool, err error) int {
+		calls := 0
+		policy.Do("test", idempotent, func() error {
+			calls++
+			return err
+		})
+		return calls
+	}
+	retryable := &exchange.ExchangeError{Kind: exchange.ERROR_RETRYABLE}
+	if count(true, retryable) != 3 || count(false, retryable) != 1 {
+		t.Errorf("ambiguous errors must be retried for idempotent calls only")
+	}
+	if count(false, &exchange.ExchangeError{Kind: exchange.ERROR_RETRYABLE, Unprocessed: true}) != 3 {
+		t.Errorf("unprocessed calls must be retried")
+	}
+	if count(false, &exchange.ExchangeError{Kind: exchange.ERROR_RATE_LIMITED}) != 3 {
+		t.Errorf("rate limited calls must be retried")
+	}
+	if count(true, &exchange.ExchangeError{Kind: exchange.ERROR_REJECTED}) != 1 || count(true, errors.New("unclassified")) != 1 {
+		t.Errorf("rejections must not be retried")
+	}
+}
+
+func TestKrakenRetriesIdempotentCalls(t *testing.T) {
+	fastRetries(t)
+	server := newKrakenServer(map[string]string{"CancelAll": `{"count": 2}`})
+	defer server.Close()
+	server.failNext("CancelAll", "EService:Busy")
+
+	_, err := server.client().CancelAll()
+	requireNoError(t, err)
+	if requests := server.getRequests("CancelAll"); len(requests) != 2 {
+		t.Errorf("expected a retry, got %d requests", len(requests))
+	}
+}
+
+func TestKrakenPlaceOrderNotRetriedOnRejection(t *testing.T) {
+	fastRetries(t)
+	server := newKrakenServer(map[string]string{})
+	defer server.Close()
+	server.setError("AddOrder", "EOrder:Insufficient funds")
+
+	_, err := server.client().PlaceOrder(&entities.Order{Id: "id", Market: internal.XBTEUR, Side: internal.SELL, PriceType: internal.MARKET})
+	if !errors.Is(err, exchange.ErrRejected) {
+		t.Errorf("expected a rejection, got %v", err)
+	}
+	if requests := server.getRequests("AddOrder"); len(requests) != 1 {
+		t.Errorf("expected a single AddOrder, got %d", len(requests))
+	}
+}
+
+func TestKrakenPlaceOrderNotRetriedOnAmbiguousError(t *testing.T) {
+	fastRetries(t)
+	server := newKrakenServer(map[string]string{
+		"AddOrder": `{"descr": {"order": "buy 1.25 XBTEUR @ limit 30010.0"}, "txid": ["OQCLML-BW3P3-BUCMWZ"]}`,
+	})
+	defer server.Close()
+	cli := server.client()
+
+	// the order may have reached the book despite the error
+	server.failNext("AddOrder", "EService:Busy")
+	_, err := cli.PlaceOrder(&entities.Order{Id: "6d1b345e-2821-40e2-ad83-4ecb18a06876", Market: internal.XBTEUR, Side: internal.BUY, PriceType: internal.LIMIT})
+	if !errors.Is(err, exchange.ErrRetryable) {
+		t.Errorf("expected the ambiguous error, got %v", err)
+	}
+	if requests := server.getRequests("AddOrder"); len(requests) != 1 {
+		t.Errorf("expected a single AddOrder, got %d", len(requests))
+	}
+	if requests := server.getRequests("OpenOrders"); len(requests) != 0 {
+		t.Errorf("expected the order to be reconciled by the caller")
+	}
+}
+
+func TestKrakenPlaceOrderRetriedWhenUnprocessed(t *testing.T) {
+	fastRetries(t)
+	server := newKrakenServer(map[string]string{
+		"AddOrder": `{"descr": {"order": "buy 1.25 XBTEUR @ limit 30010.0"}, "txid": ["OQCLML-BW3P3-BUCMWZ"]}`,
+	})
+	defer server.Close()
+
+	// rate limited calls never reached the matching engine
+	server.failNext("AddOrder", "EAPI:Rate limit exceeded", "EAPI:Rate limit exceeded", "EAPI:Rate limit exceeded")
+	_, err := server.client().PlaceOrder(&entities.Order{Id: "id", Market: internal.XBTEUR, Side: internal.BUY, PriceType: internal.LIMIT})
+	if !errors.Is(err, exchange.ErrRateLimited) {
+		t.Errorf("expected the rate limit error, got %v", err)
+	}
+	// a single retry layer, not Attempts^2 requests
+	if requests := server.getRequests("AddOrder"); len(requests) != exchange.KrakenRetryPolicy.Attempts {
+		t.Errorf("expected %d AddOrder, got %d", exchange.KrakenRetryPolicy.Attempts, len(requests))
+	}
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/shopspring/decimal"
+)
+
+func receiveBooks(t *testing.T, books chan *entities.OrderBook, n int) []*entities.OrderBook {
+	var res []*entities.OrderBook
+	for len(res) < n {
+		select {
+		case book := <-books:
+			res = append(res, book)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d books, expected %d", len(res), n)
+		}
+	}
+	return res
+}
+
+func TestKrakenOrderBook(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"Depth": `{"XXBTZEUR": {
+			"asks": [["34012.10000", "1.000", 1700000041], ["34012.50000", "0.500", 1700000042]],
+			"bids": [["34011.90000", "2.500", 1700000040], ["34011.00000", "1.000", 1700000039]]}}`,
+	})
+	defer server.Close()
+
+	book, err := server.client().(exchange.IOrderBookData).GetOrderBook(internal.XBTEUR, 2)
+	requireNoError(t, err)
+	if bid, _ := book.BestBid(); !bid.Price.Equal(decimal.RequireFromString("34011.9")) {
+		t.Errorf("unexpected best bid %+v", bid)
+	}
+	if ask, _ := book.BestAsk(); !ask.Price.Equal(decimal.RequireFromString("34012.1")) || !ask.Timestamp.Equal(time.Unix(1700000041, 0)) {
+		t.Errorf("unexpected best ask %+v", ask)
+	}
+	if book.Market != internal.XBTEUR || len(book.Asks) != 2 || len(book.Bids) != 2 {
+		t.Errorf("unexpected book %s", book)
+	}
+	if params := server.getRequests("Depth")[0].Params; params.Get("pair") != "XXBTZEUR" || params.Get("count") != "2" {
+		t.Errorf("unexpected params %v", params)
+	}
+}
+
+func TestKrakenBookChecksum(t *testing.T) {
+	// from the kraken documentation
+	book := entities.NewOrderBook(internal.XBTEUR, 10)
+	for _, level := range [][2]string{
+		{"0.05005", "0.00000500"}, {"0.05010", "0.00000500"}, {"0.05015", "0.00000500"}, {"0.05020", "0.00000500"}, {"0.05025", "0.00000500"},
+		{"0.05030", "0.00000500"}, {"0.05035", "0.00000500"}, {"0.05040", "0.00000500"}, {"0.05045", "0.00000500"}, {"0.05050", "0.00000500"},
+	} {
+		book.UpdateAsk(entities.BookLevel{Price: decimal.RequireFromString(level[0]), Volume: decimal.RequireFromString(level[1])})
+	}
+	for _, level := range [][2]string{
+		{"0.05000", "0.00000500"}, {"0.04995", "0.00000500"}, {"0.04990", "0.00000500"}, {"0.04980", "0.00000500"}, {"0.04975", "0.00000500"},
+		{"0.04970", "0.00000500"}, {"0.04965", "0.00000500"}, {"0.04960", "0.00000500"}, {"0.04955", "0.00000500"}, {"0.04950", "0.00000500"},
+	} {
+		book.UpdateBid(entities.BookLevel{Price: decimal.RequireFromString(level[0]), Volume: decimal.RequireFromString(level[1])})
+	}
+	if checksum := exchange.KrakenBookChecksum(book, 5, 8); checksum != 974947235 {
+		t.Errorf("unexpected checksum %d", checksum)
+	}
+}
+
+func TestKrakenFeedBook(t *testing.T) {
+	server := newReplayServer(loadFrames(t, "testdata/kraken_ws_book.jsonl"), 1)
+	defer server.Close()
+	feed := exchange.NewKrakenFeed(server.wsUrl(), time.Second, 10*time.Millisecond)
+	defer feed.Close()
+
+	books, err := feed.SubscribeBook(internal.XBTEUR, 10)
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+	// snapshot, two valid updates and the snapshot following the
+	// checksum mismatch, the updates in between are skipped
+	received := receiveBooks(t, books, 4)
+
+	if ask, _ := received[0].BestAsk(); !ask.Price.Equal(decimal.RequireFromString("34012.1")) || len(received[0].Bids) != 3 {
+		t.Errorf("unexpected snapshot %s", received[0])
+	}
+	if ask, _ := received[1].BestAsk(); !ask.Price.Equal(decimal.RequireFromString("34012.3")) || len(received[1].Asks) != 3 {
+		t.Errorf("unexpected update %s", received[1])
+	}
+	if bid, _ := received[2].BestBid(); !bid.Price.Equal(decimal.RequireFromString("34011.9")) || len(received[2].Bids) != 4 ||
+		!received[2].Asks[0].Volume.Equal(decimal.RequireFromString("0.8")) {
+		t.Errorf("unexpected update %s", received[2])
+	}
+	if bid, _ := received[3].BestBid(); !bid.Price.Equal(decimal.RequireFromString("34019")) || len(received[3].Bids) != 1 {
+		t.Errorf("unexpected snapshot after the resync %s", received[3])
+	}
+	// earlier copies are not affected by the following updates
+	if len(received[0].Asks) != 3 || !received[0].Asks[0].Price.Equal(decimal.RequireFromString("34012.1")) {
+		t.Errorf("snapshot modified by the updates %s", received[0])
+	}
+
+	subscriptions := server.getSubscriptions()
+	if len(subscriptions) != 1 || !strings.Contains(subscriptions[0], `"depth":10`) || !strings.Contains(subscriptions[0], `"name":"book"`) {
+		t.Errorf("unexpected subscriptions %v", subscriptions)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(server.getMessages()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	messages := server.getMessages()
+	if len(messages) != 2 || !strings.Contains(messages[0], `"event":"unsubscribe"`) || !strings.Contains(messages[1], `"event":"subscribe"`) {
+		t.Errorf("expected a single resubscription, got %v", messages)
+	}
+}
@@ -0,0 +1,354 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/shopspring/decimal"
+)
+
+var krakenFuturesSecret = base64.StdEncoding.EncodeToString([]byte("kraken-futures-secret"))
+
+type futuresRequest struct {
+	Method string
+	Path   string
+	Params url.Values
+}
+
+// local stand-in of the kraken futures api, answering every
+// "METHOD path" with the configured body and checking the
+// signature of private requests
+type krakenFuturesServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses map[string]string
+	requests  []futuresRequest
+}
+
+func newKrakenFuturesServer(t *testing.T, responses map[string]string) *krakenFuturesServer {
+	s := &krakenFuturesServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		data := r.URL.RawQuery
+		if r.Method != http.MethodGet {
+			data = string(body)
+		}
+		params, _ := url.ParseQuery(data)
+		s.mu.Lock()
+		s.requests = append(s.requests, futuresRequest{Method: r.Method, Path: r.URL.Path, Params: params})
+		response, ok := s.responses[r.Method+" "+r.URL.Path]
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if authent := r.Header.Get("Authent"); authent != "" {
+			secret, _ := base64.StdEncoding.DecodeString(krakenFuturesSecret)
+			digest := sha256.Sum256([]byte(data + r.Header.Get("Nonce") + strings.TrimPrefix(r.URL.Path, "/derivatives")))
+			mac := hmac.New(sha512.New, secret)
+			mac.Write(digest[:])
+			if base64.StdEncoding.EncodeToString(mac.Sum(nil)) != authent || r.Header.Get("APIKey") != "futures-key" {
+				w.Write([]byte(`{"result":"error","error":"authenticationError"}`))
+				return
+			}
+		}
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"result":"error","error":"not found"}`))
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	return s
+}
+
+func (s *krakenFuturesServer) client() exchange.IFuturesExchange {
+	target, _ := url.Parse(s.URL)
+	return exchange.NewKrakenFuturesCliWithClient("futures-key", krakenFuturesSecret, &http.Client{Transport: &redirectTransport{target: target}})
+}
+
+func (s *krakenFuturesServer) lastRequest(method string, path string) *futuresRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.requests) - 1; i >= 0; i-- {
+		if s.requests[i].Method == method && s.requests[i].Path == path {
+			return &s.requests[i]
+		}
+	}
+	return nil
+}
+
+func futuresResponses(t *testing.T, responses map[string]string) map[string]string {
+	responses["GET /derivatives/api/v3/instruments"] = readFile(t, "testdata/kraken_futures_instruments.json")
+	responses["GET /derivatives/api/v3/tickers"] = readFile(t, "testdata/kraken_futures_tickers.json")
+	return responses
+}
+
+func TestKrakenFuturesSymbols(t *testing.T) {
+	if symbol := exchange.FuturesSymbol(internal.XBTUSDPERP); symbol != "PF_XBTUSD" {
+		t.Errorf("unexpected perpetual symbol %s", symbol)
+	}
+	if symbol := exchange.FuturesSymbol("XBTUSD241227"); symbol != "FF_XBTUSD_241227" {
+		t.Errorf("unexpected fixed maturity symbol %s", symbol)
+	}
+	if market, ok := exchange.IFuturesSymbol("FF_ETHUSD_250328"); !ok || market != "ETHUSD250328" {
+		t.Errorf("unexpected market %s", market)
+	}
+	if _, ok := exchange.IFuturesSymbol("PI_XBTUSD"); ok {
+		t.Errorf("expected inverse contracts to be skipped")
+	}
+	if internal.XBTEUR.IsFuture() || !internal.IMarket("XBTUSD241227").IsFuture() {
+		t.Errorf("unexpected futures markets detection")
+	}
+}
+
+func TestKrakenFuturesContracts(t *testing.T) {
+	server := newKrakenFuturesServer(t, futuresResponses(t, map[string]string{}))
+	defer server.Close()
+	cli := server.client()
+
+	contracts, err := cli.GetContracts()
+	requireNoError(t, err)
+	if len(contracts) != 2 {
+		t.Fatalf("expected the tradeable multi-collateral contracts only, got %v", contracts)
+	}
+
+	perp, err := cli.GetContract(internal.XBTUSDPERP)
+	requireNoError(t, err)
+	if !perp.Perpetual || !perp.MarkPrice.Equal(decimal.RequireFromString("90005.5")) || !perp.FundingRate.Equal(decimal.RequireFromString("0.91")) {
+		t.Errorf("unexpected perpetual contract %s", perp.String())
+	}
+	if !perp.MaxLeverage.Equal(decimal.NewFromInt(50)) || !cli.GetLeverage(internal.XBTUSDPERP).Equal(decimal.NewFromInt(50)) {
+		t.Errorf("unexpected leverage %s", perp.MaxLeverage)
+	}
+
+	fixed, err := cli.GetContract("XBTUSD241227")
+	requireNoError(t, err)
+	if fixed.Perpetual || !fixed.Maturity.Equal(time.Date(2024, 12, 27, 16, 0, 0, 0, time.UTC)) || !fixed.FundingRate.IsZero() {
+		t.Errorf("unexpected fixed maturity contract %s", fixed.String())
+	}
+
+	markets, err := cli.GetMarketsData([]internal.Market{internal.XBTUSDPERP})
+	requireNoError(t, err)
+	if markets.GetDecimals(internal.XBTUSDPERP) != 4 || !markets.GetOrderMin(internal.XBTUSDPERP).Equal(decimal.RequireFromString("0.0001")) ||
+		!markets.GetTickSize(internal.XBTUSDPERP).Equal(decimal.RequireFromString("0.5")) || markets.GetTradeCurrency(internal.XBTUSDPERP) != internal.XBT {
+		t.Errorf("unexpected metadata %+v", markets.GetMetadata(internal.XBTUSDPERP))
+	}
+	if _, err := cli.GetContract(internal.ETHUSDPERP); err == nil {
+		t.Errorf("expected an error for non tradeable contracts")
+	}
+}
+
+func TestKrakenFuturesContractVolumes(t *testing.T) {
+	contract := &entities.Contract{ContractSize: decimal.RequireFromString("0.01"), Precision: 0}
+	if contracts := contract.Contracts(decimal.RequireFromString("0.257")); !contracts.Equal(decimal.NewFromInt(25)) {
+		t.Errorf("unexpected contracts %s", contracts)
+	}
+	if volume := contract.Volume(decimal.NewFromInt(25)); !volume.Equal(decimal.RequireFromString("0.25")) {
+		t.Errorf("unexpected volume %s", volume)
+	}
+}
+
+func TestKrakenFuturesPlaceOrder(t *testing.T) {
+	server := newKrakenFuturesServer(t, futuresResponses(t, map[string]string{
+		"POST /derivatives/api/v3/sendorder": `{"result":"success","sendStatus":{"order_id":"179f9af8-e45e-469d-b3e9-2fd4675cb7d0","status":"placed",
+			"receivedTime":"2024-11-14T22:13:20.000Z","orderEvents":[{"type":"EXECUTION","amount":0.0125,"price":90010}]}}`,
+	}))
+	defer server.Close()
+	cli := server.client()
+
+	ack, err := cli.PlaceOrder(&entities.Order{
+		Id:            "6d1b345e-2821-40e2-ad83-4ecb18a06876",
+		Type:          internal.FUTURE,
+		PriceType:     internal.MARKET,
+		Market:        internal.XBTUSDPERP,
+		Side:          internal.SELL,
+		InitialVolume: decimal.RequireFromString("0.01256"),
+		ReduceOnly:    true,
+	})
+	requireNoError(t, err)
+	if ack.RemoteId() != "179f9af8-e45e-469d-b3e9-2fd4675cb7d0" || ack.Status != internal.FILLED {
+		t.Errorf("unexpected ack %+v", ack)
+	}
+	params := server.lastRequest("POST", "/derivatives/api/v3/sendorder").Params
+	if params.Get("symbol") != "PF_XBTUSD" || params.Get("size") != "0.0125" || params.Get("orderType") != "mkt" ||
+		params.Get("side") != "sell" || params.Get("reduceOnly") != "true" || params.Get("cliOrdId") != "6d1b345e-2821-40e2-ad83-4ecb18a06876" {
+		t.Errorf("unexpected params %v", params)
+	}
+
+	_, err = cli.PlaceOrder(&entities.Order{Type: internal.MARGIN, PriceType: internal.MARKET, Market: internal.XBTUSDPERP, InitialVolume: decimal.NewFromInt(1)})
+	if !errors.Is(err, exchange.ErrNotSupported) {
+		t.Errorf("expected margin orders to be refused, got %v", err)
+	}
+	_, err = cli.PlaceOrder(&entities.Order{Type: internal.FUTURE, PriceType: internal.STOP_LOSS_LIMIT, Market: internal.XBTUSDPERP, InitialVolume: decimal.NewFromInt(1)})
+	if !errors.Is(err, exchange.ErrNotSupported) {
+		t.Errorf("expected stop loss orders to be refused, got %v", err)
+	}
+}
+
+func TestKrakenFuturesRejectedOrder(t *testing.T) {
+	server := newKrakenFuturesServer(t, futuresResponses(t, map[string]string{
+		"POST /derivatives/api/v3/sendorder": `{"result":"success","sendStatus":{"status":"insufficientAvailableFunds"}}`,
+	}))
+	defer server.Close()
+
+	_, err := server.client().PlaceOrder(&entities.Order{
+		Type:          internal.FUTURE,
+		PriceType:     internal.LIMIT,
+		Market:        internal.XBTUSDPERP,
+		Side:          internal.BUY,
+		InitialVolume: decimal.NewFromInt(1),
+		LimitPrice:    decimal.NewFromInt(90000),
+	})
+	if err == nil || !strings.Contains(err.Error(), "insufficientAvailableFunds") {
+		t.Errorf("expected the rejection reason, got %v", err)
+	}
+}
+
+func TestKrakenFuturesAccount(t *testing.T) {
+	server := newKrakenFuturesServer(t, futuresResponses(t, map[string]string{
+		"GET /derivatives/api/v3/accounts": `{"result":"success","accounts":{"flex":{"type":"multiCollateralMarginAccount","balanceValue":10000.5,
+			"initialMargin":500,"availableMargin":9400,"marginEquity":10100,"currencies":{"USD":{"quantity":8000,"available":7900},"XBT":{"quantity":0.025,"available":0.025}}}}}`,
+		"GET /derivatives/api/v3/openpositions": `{"result":"success","openPositions":[
+			{"side":"short","symbol":"PF_XBTUSD","price":90100,"fillTime":"2024-11-14T22:13:20.000Z","size":0.05,"unrealizedFunding":0.1},
+			{"side":"long","symbol":"PF_ETHUSD","price":3100,"fillTime":"2024-11-14T22:13:20.000Z","size":1}]}`,
+	}))
+	defer server.Close()
+	cli := server.client()
+
+	balance, err := cli.GetBalance()
+	requireNoError(t, err)
+	if !balance.FreeMargin.Equal(decimal.NewFromInt(9400)) || !balance.MarginLevel.Equal(decimal.NewFromInt(2020)) || !balance.Currencies[internal.USD].Equal(decimal.NewFromInt(7900)) {
+		t.Errorf("unexpected balance %+v", balance)
+	}
+
+	positions, err := cli.GetOpenPositions(internal.XBTUSDPERP)
+	requireNoError(t, err)
+	if len(positions) != 1 || positions[0].Side != internal.SELL || !positions[0].Size.Equal(decimal.RequireFromString("0.05")) || positions[0].Market != internal.XBTUSDPERP {
+		t.Errorf("unexpected positions %v", positions)
+	}
+}
+
+func TestKrakenFuturesOrders(t *testing.T) {
+	server := newKrakenFuturesServer(t, futuresResponses(t, map[string]string{
+		"POST /derivatives/api/v3/orders/status": `{"result":"success","orders":[{"order":{"type":"ORDER","orderId":"179f9af8","cliOrdId":"my-order","symbol":"PF_XBTUSD",
+			"side":"buy","quantity":0.1,"filled":0.04,"limitPrice":90000,"reduceOnly":false,"timestamp":"2024-11-14T22:13:20.000Z","lastUpdateTimestamp":"2024-11-14T22:14:00.000Z"},"status":"ENTERED_BOOK"}]}`,
+		"GET /derivatives/api/v3/openorders": `{"result":"success","openOrders":[{"order_id":"179f9af8","cliOrdId":"my-order","symbol":"PF_XBTUSD","side":"buy","orderType":"lmt",
+			"limitPrice":90000,"filledSize":0.04,"unfilledSize":0.06,"receivedTime":"2024-11-14T22:13:20.000Z","lastUpdateTime":"2024-11-14T22:14:00.000Z","status":"partiallyFilled"}]}`,
+		"GET /derivatives/api/v3/fills": `{"result":"success","fills":[
+			{"fill_id":"f1","symbol":"PF_XBTUSD","side":"sell","order_id":"a1","cliOrdId":"closed-order","size":0.01,"price":91000,"fillTime":"2024-11-14T22:20:00.000Z","fillType":"taker"},
+			{"fill_id":"f2","symbol":"PF_XBTUSD","side":"sell","order_id":"a1","cliOrdId":"closed-order","size":0.03,"price":90000,"fillTime":"2024-11-14T22:19:00.000Z","fillType":"taker"},
+			{"fill_id":"f3","symbol":"PF_XBTUSD","side":"buy","order_id":"179f9af8","cliOrdId":"my-order","size":0.04,"price":90000,"fillTime":"2024-11-14T22:14:00.000Z","fillType":"maker"},
+			{"fill_id":"f4","symbol":"PF_XBTUSD","side":"buy","order_id":"old","size":0.04,"price":80000,"fillTime":"2024-11-01T00:00:00.000Z","fillType":"maker"}]}`,
+		"POST /derivatives/api/v3/cancelorder":          `{"result":"success","cancelStatus":{"status":"notFound","order_id":"unknown"}}`,
+		"POST /derivatives/api/v3/cancelallordersafter": `{"result":"success","status":{"currentTime":"2024-11-14T22:13:20Z","triggerTime":"2024-11-14T22:14:20Z"}}`,
+	}))
+	defer server.Close()
+	cli := server.client()
+
+	order, err := cli.GetOrder("my-order")
+	requireNoError(t, err)
+	if order.Status != internal.OPEN || order.PriceType != internal.LIMIT || !order.ExecutedVolume.Equal(decimal.RequireFromString("0.04")) || !order.IsFuture() {
+		t.Errorf("unexpected order %+v", order)
+	}
+	if ids := server.lastRequest("POST", "/derivatives/api/v3/orders/status").Params["cliOrdIds"]; len(ids) != 1 || ids[0] != "my-order" {
+		t.Errorf("unexpected ids %v", ids)
+	}
+
+	open, err := cli.GetOpenOrders(internal.XBTUSDPERP)
+	requireNoError(t, err)
+	if len(open) != 1 || !open[0].InitialVolume.Equal(decimal.RequireFromString("0.1")) {
+		t.Errorf("unexpected open orders %v", open)
+	}
+
+	closed, err := cli.GetClosedOrders(internal.XBTUSDPERP, time.Date(2024, 11, 14, 0, 0, 0, 0, time.UTC))
+	requireNoError(t, err)
+	if len(closed) != 1 || closed[0].Id != "closed-order" || !closed[0].ExecutedVolume.Equal(decimal.RequireFromString("0.04")) ||
+		!closed[0].AveragePrice.Equal(decimal.NewFromInt(90250)) || !closed[0].ClosedAt.Equal(time.Date(2024, 11, 14, 22, 20, 0, 0, time.UTC)) {
+		t.Errorf("unexpected closed orders %+v", closed)
+	}
+
+	if err := cli.CancelOrder("unknown"); !errors.Is(err, exchange.ErrOrderNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	trigger, err := cli.CancelAllAfter(time.Minute)
+	requireNoError(t, err)
+	if !trigger.Equal(time.Date(2024, 11, 14, 22, 14, 20, 0, time.UTC)) {
+		t.Errorf("unexpected trigger time %s", trigger)
+	}
+}
+
+func TestKrakenFuturesOHLC(t *testing.T) {
+	server := newKrakenFuturesServer(t, map[string]string{
+		"GET /api/charts/v1/trade/PF_XBTUSD/1h": `{"candles":[{"time":1731621600000,"open":"90000","high":"90500","low":"89800","close":"90100","volume":"12.5"},
+			{"time":1731625200000,"open":"90100","high":"90200","low":"89900","close":"90000","volume":"3"}],"more_candles":false}`,
+	})
+	defer server.Close()
+
+	candles, err := server.client().GetOHLC(internal.XBTUSDPERP, 60)
+	requireNoError(t, err)
+	if len(candles) != 2 || !candles[0].Close.Equal(decimal.NewFromInt(90100)) || !candles[1].Timestamp.Equal(time.UnixMilli(1731625200000)) {
+		t.Errorf("unexpected candles %v", candles)
+	}
+	if !candles[0].Volume.Equal(decimal.RequireFromString("12.5")) {
+		t.Errorf("unexpected candle volume %s", candles[0].Volume)
+	}
+	if server.lastRequest("GET", "/api/charts/v1/trade/PF_XBTUSD/1h").Params.Get("from") == "" {
+		t.Errorf("expected the from parameter")
+	}
+}
+
+func TestKrakenFuturesUnsupportedOrders(t *testing.T) {
+	server := newKrakenFuturesServer(t, futuresResponses(t, map[string]string{
+		"POST /derivatives/api/v3/orders/status": `{"result":"success","orders":[{"order":{"type":"TRIGGER_ORDER","orderId":"trail","cliOrdId":"trail","symbol":"PF_XBTUSD",
+			"side":"sell","quantity":0.1,"filled":0,"reduceOnly":true,"timestamp":"2024-11-14T22:13:20.000Z","lastUpdateTimestamp":"2024-11-14T22:13:20.000Z"},"status":"TRAILING_PLACED"}]}`,
+		"GET /derivatives/api/v3/openorders": `{"result":"success","openOrders":[{"order_id":"trail","cliOrdId":"trail","symbol":"PF_XBTUSD","side":"sell","orderType":"trailing_stop",
+			"filledSize":0,"unfilledSize":0.1,"receivedTime":"2024-11-14T22:13:20.000Z","lastUpdateTime":"2024-11-14T22:13:20.000Z","status":"untouched"}]}`,
+	}))
+	defer server.Close()
+	cli := server.client()
+
+	if _, err := cli.GetOrder("trail"); err == nil {
+		t.Errorf("expected an error for the unknown status")
+	}
+	open, err := cli.GetOpenOrders(internal.XBTUSDPERP)
+	requireNoError(t, err)
+	if len(open) != 0 {
+		t.Errorf("expected the trailing stop to be skipped, got %v", open)
+	}
+}
+
+func TestKrakenFuturesInvalidResponses(t *testing.T) {
+	fastRetries(t)
+	for contentType, kind := range map[string]exchange.ErrorKind{"text/html": exchange.ERROR_UNAVAILABLE, "application/json": exchange.ERROR_RETRYABLE} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			if contentType == "text/html" {
+				w.WriteHeader(http.StatusBadGateway)
+				w.Write([]byte("<html>bad gateway</html>"))
+				return
+			}
+			w.Write([]byte(`{"result":"success","accounts":{"flex`))
+		}))
+		target, _ := url.Parse(server.URL)
+		cli := exchange.NewKrakenFuturesCliWithClient("futures-key", krakenFuturesSecret, &http.Client{Transport: &redirectTransport{target: target}})
+
+		_, err := cli.GetBalance()
+		var exchangeErr *exchange.ExchangeError
+		if !errors.As(err, &exchangeErr) || exchangeErr.Kind != kind {
+			t.Errorf("%s: expected a %s error, got %v", contentType, kind, err)
+		}
+		server.Close()
+	}
+}
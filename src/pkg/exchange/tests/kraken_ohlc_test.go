@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/shopspring/decimal"
+)
+
+func TestKrakenOHLC(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"OHLC": `{"XXBTZEUR": [
+			[1700000040, "34000.1", "34010.0", "33990.0", "34005.5", "34002.1", "1.25", 12],
+			[1700000100, "34005.5", "34020.0", "34001.0", "34015.0", "34012.0", "2.5", 20]], "last": 1700000100}`,
+	})
+	defer server.Close()
+
+	candles, err := server.client().GetOHLC(internal.XBTEUR, 1)
+	requireNoError(t, err)
+	if len(candles) != 2 || !candles[0].Timestamp.Equal(time.Unix(1700000040, 0)) || !candles[1].Close.Equal(decimal.NewFromInt(34015)) {
+		t.Fatalf("unexpected candles %v", candles)
+	}
+	if !candles[0].Volume.Equal(decimal.RequireFromString("1.25")) || !candles[0].Vwap.Equal(decimal.RequireFromString("34002.1")) || candles[0].Count != 12 {
+		t.Errorf("unexpected candle volume %s", candles[0].String())
+	}
+	if params := server.getRequests("OHLC")[0].Params; params.Get("pair") != "XXBTZEUR" || params.Get("interval") != "1" {
+		t.Errorf("unexpected params %v", params)
+	}
+}
+
+func TestKrakenTrades(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"Trades": `{"XXBTZEUR": [
+			["34011.00000", "0.05000000", 1700000041.1234, "s", "m", "", 101],
+			["34012.00000", "0.01000000", 1700000042.2, "b", "l", "", 102]], "last": "1700000042200000000"}`,
+	})
+	defer server.Close()
+	cli := server.client().(exchange.ITradesData)
+
+	trades, next, err := cli.GetTrades(internal.XBTEUR, time.Time{})
+	requireNoError(t, err)
+	if len(trades) != 2 || trades[0].Side != internal.SELL || trades[1].PriceType != internal.LIMIT || !trades[1].Price.Equal(decimal.NewFromInt(34012)) {
+		t.Fatalf("unexpected trades %v", trades)
+	}
+	if !trades[0].Timestamp.Equal(time.Unix(1700000041, 123400000)) || trades[0].Market != internal.XBTEUR {
+		t.Errorf("unexpected trade %s", trades[0].String())
+	}
+	if !next.Equal(time.Unix(1700000042, 200000000)) {
+		t.Errorf("unexpected next page time %s", next)
+	}
+	if params := server.getRequests("Trades")[0].Params; params.Has("since") {
+		t.Errorf("unexpected since %v", params)
+	}
+
+	_, _, err = cli.GetTrades(internal.XBTEUR, next)
+	requireNoError(t, err)
+	if params := server.getRequests("Trades")[1].Params; params.Get("since") != "1700000042200000000" || params.Get("pair") != "XXBTZEUR" {
+		t.Errorf("unexpected params %v", params)
+	}
+}
+
+// the 5m and 15m candles are built from the 1m ones as kraken does,
+// the vwap being rounded to the pair decimals
+func TestKrakenResampledOHLC(t *testing.T) {
+	server := newKrakenServer(map[string]string{"OHLC": readFile(t, "testdata/kraken_ohlc_1m.json")})
+	defer server.Close()
+	cli := server.client()
+	base, err := cli.GetOHLC(internal.XBTEUR, 1)
+	requireNoError(t, err)
+	// the last candle is in progress
+	base = base[:len(base)-1]
+
+	for _, interval := range []int{5, 15} {
+		server.setResult("OHLC", readFile(t, fmt.Sprintf("testdata/kraken_ohlc_%dm.json", interval)))
+		expected, err := cli.GetOHLC(internal.XBTEUR, interval)
+		requireNoError(t, err)
+		expected = expected[:len(expected)-1]
+
+		resampled := entities.Resample(base, time.Minute, time.Duration(interval)*time.Minute)
+		if len(resampled) != len(expected) {
+			t.Fatalf("expected %d %dm candles, got %d", len(expected), interval, len(resampled))
+		}
+		for i, candle := range resampled {
+			want := expected[i]
+			if !candle.Timestamp.Equal(want.Timestamp) || !candle.Open.Equal(want.Open) || !candle.High.Equal(want.High) || !candle.Low.Equal(want.Low) ||
+				!candle.Close.Equal(want.Close) || !candle.Volume.Equal(want.Volume) || candle.Count != want.Count {
+				t.Errorf("unexpected %dm candle %s, expected %s", interval, candle.String(), want.String())
+			}
+			if diff := candle.Vwap.Sub(want.Vwap).Abs(); diff.GreaterThan(decimal.RequireFromString("0.05")) {
+				t.Errorf("unexpected %dm vwap %s, expected %s", interval, candle.Vwap, want.Vwap)
+			}
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/shopspring/decimal"
+)
+
+const openOrder = `"OQCLML-BW3P3-BUCMWZ": {
+	"refid": null, "userref": 0, "cl_ord_id": "6d1b345e-2821-40e2-ad83-4ecb18a06876", "status": "open",
+	"opentm": 1688666559.8974, "starttm": 0, "expiretm": 0,
+	"descr": {"pair": "XBTEUR", "type": "buy", "ordertype": "limit", "price": "30010.0", "price2": "0", "leverage": "5:1", "order": "buy 1.25 XBTEUR @ limit 30010.0", "close": ""},
+	"vol": "1.25000000", "vol_exec": "0.37500000", "cost": "11253.75000", "fee": "18.00600", "price": "30010.0",
+	"stopprice": "0.00000", "limitprice": "0.00000", "misc": "", "oflags": "fciq"}`
+
+const closedOrder = `"OB5VMB-B4U2U-DK2WRW": {
+	"refid": null, "userref": 0, "cl_ord_id": "0b7c2f6a-0f4c-4b2b-8f7e-1d2c3b4a5f60", "status": "closed", "reason": null,
+	"opentm": 1688148493.7708, "closetm": 1688148610.0482, "starttm": 0, "expiretm": 0,
+	"descr": {"pair": "ETHEUR", "type": "sell", "ordertype": "market", "price": "0", "price2": "0", "leverage": "none", "order": "sell 0.50000000 ETHEUR @ market", "close": ""},
+	"vol": "0.50000000", "vol_exec": "0.50000000", "cost": "910.10", "fee": "2.36626", "price": "1820.20",
+	"stopprice": "0.00000", "limitprice": "0.00000", "misc": "", "oflags": "fciq"}`
+
+const canceledOrder = `"OXVPSU-Q726F-L3SDEP": {
+	"refid": null, "userref": 0, "status": "canceled", "reason": "User requested",
+	"opentm": 1688148493.1, "closetm": 1688148500.2, "starttm": 0, "expiretm": 0,
+	"descr": {"pair": "XBTEUR", "type": "sell", "ordertype": "limit", "price": "31000.0", "price2": "0", "leverage": "none", "order": "", "close": ""},
+	"vol": "0.10000000", "vol_exec": "0.00000000", "cost": "0", "fee": "0", "price": "0",
+	"stopprice": "0.00000", "limitprice": "0.00000", "misc": "", "oflags": "fciq"}`
+
+func TestKrakenGetRemoteOrder(t *testing.T) {
+	server := newKrakenServer(map[string]string{"QueryOrders": "{" + openOrder + "}"})
+	defer server.Close()
+
+	order, err := server.client().GetRemoteOrder("OQCLML-BW3P3-BUCMWZ")
+	requireNoError(t, err)
+
+	if order.RemoteId != "OQCLML-BW3P3-BUCMWZ" || order.Id != "6d1b345e-2821-40e2-ad83-4ecb18a06876" {
+		t.Errorf("unexpected ids %s %s", order.RemoteId, order.Id)
+	}
+	if order.Status != internal.OPEN || order.Market != internal.XBTEUR || order.Side != internal.BUY || order.PriceType != internal.LIMIT {
+		t.Errorf("unexpected order %+v", order)
+	}
+	if order.Type != internal.MARGIN || order.Leverage != 5 {
+		t.Errorf("expected a margin order with leverage 5, got %s %d", order.Type, order.Leverage)
+	}
+	if !order.ExecutedVolume.Equal(decimal.RequireFromString("0.375")) || !order.Fee.Equal(decimal.RequireFromString("18.006")) || !order.LimitPrice.Equal(decimal.RequireFromString("30010")) {
+		t.Errorf("unexpected execution %s %s %s", order.ExecutedVolume, order.Fee, order.LimitPrice)
+	}
+	if !order.CreatedAt.Equal(time.UnixMicro(1688666559897400)) {
+		t.Errorf("unexpected creation time %s", order.CreatedAt)
+	}
+	if params := server.getRequests("QueryOrders")[0].Params; params.Get("txid") != "OQCLML-BW3P3-BUCMWZ" {
+		t.Errorf("unexpected params %v", params)
+	}
+
+	_, err = server.client().GetRemoteOrder("OUNKNO-WN000-000000")
+	if !errors.Is(err, exchange.ErrOrderNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestKrakenGetOrderByClientId(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"OpenOrders":   `{"open": {}}`,
+		"ClosedOrders": `{"closed": {` + closedOrder + `}, "count": 1}`,
+	})
+	defer server.Close()
+
+	order, err := server.client().GetOrder("0b7c2f6a-0f4c-4b2b-8f7e-1d2c3b4a5f60")
+	requireNoError(t, err)
+
+	if order.Status != internal.FILLED || order.Type != internal.SPOT || order.Market != internal.ETHEUR || order.Side != internal.SELL {
+		t.Errorf("unexpected order %+v", order)
+	}
+	if !order.AveragePrice.Equal(decimal.RequireFromString("1820.2")) || !order.ExecutedVolume.Equal(decimal.RequireFromString("0.5")) {
+		t.Errorf("unexpected execution %s@%s", order.ExecutedVolume, order.AveragePrice)
+	}
+	if !order.ClosedAt.Equal(time.UnixMicro(1688148610048200)) {
+		t.Errorf("unexpected close time %s", order.ClosedAt)
+	}
+	for _, method := range []string{"OpenOrders", "ClosedOrders"} {
+		if params := server.getRequests(method)[0].Params; params.Get("cl_ord_id") != "0b7c2f6a-0f4c-4b2b-8f7e-1d2c3b4a5f60" {
+			t.Errorf("unexpected %s params %v", method, params)
+		}
+	}
+}
+
+func TestKrakenOpenAndClosedOrders(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"OpenOrders":   `{"open": {` + openOrder + `}}`,
+		"ClosedOrders": `{"closed": {` + closedOrder + `,` + canceledOrder + `}, "count": 2}`,
+	})
+	defer server.Close()
+	cli := server.client()
+
+	open, err := cli.GetOpenOrders(internal.XBTEUR)
+	requireNoError(t, err)
+	if len(open) != 1 || open[0].RemoteId != "OQCLML-BW3P3-BUCMWZ" {
+		t.Errorf("unexpected open orders %v", open)
+	}
+
+	closed, err := cli.GetClosedOrders(internal.XBTEUR, time.Unix(1688148000, 0))
+	requireNoError(t, err)
+	if len(closed) != 1 || closed[0].Status != internal.CANCELLED || closed[0].RemoteId != "OXVPSU-Q726F-L3SDEP" {
+		t.Errorf("unexpected closed orders %v", closed)
+	}
+	if params := server.getRequests("ClosedOrders")[0].Params; params.Get("start") != "1688148000" {
+		t.Errorf("unexpected params %v", params)
+	}
+}
+
+func TestKrakenOrderStatusMapping(t *testing.T) {
+	for status, expected := range map[exchange.KrakenOrderStatus]internal.OrderStatus{
+		exchange.STATUS_PENDING:   internal.CREATED,
+		exchange.STATUS_OPEN:      internal.OPEN,
+		exchange.STATUS_CLOSED:    internal.FILLED,
+		exchange.STATUS_CANCELLED: internal.CANCELLED,
+		exchange.STATUS_EXPIRED:   internal.CANCELLED,
+	} {
+		if mapped, ok := exchange.IStatus(status); !ok || mapped != expected {
+			t.Errorf("status %s mapped to %s, expected %s", status, mapped, expected)
+		}
+	}
+	if _, ok := exchange.IStatus("unknown"); ok {
+		t.Errorf("expected an unknown status not to be mapped")
+	}
+}
+
+const trailingStopOrder = `"OTRAIL-AAAAA-BBBBBB": {
+	"refid": null, "userref": 0, "status": "open",
+	"opentm": 1688666560.1, "starttm": 0, "expiretm": 0,
+	"descr": {"pair": "XBTEUR", "type": "sell", "ordertype": "trailing-stop", "price": "500.0", "price2": "0", "leverage": "none", "order": "", "close": ""},
+	"vol": "0.10000000", "vol_exec": "0.00000000", "cost": "0", "fee": "0", "price": "0",
+	"stopprice": "0.00000", "limitprice": "0.00000", "misc": "", "oflags": "fciq"}`
+
+func TestKrakenUnsupportedOrdersAreSkipped(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"OpenOrders":  `{"open": {` + openOrder + `,` + trailingStopOrder + `}}`,
+		"QueryOrders": "{" + trailingStopOrder + "}",
+	})
+	defer server.Close()
+	cli := server.client()
+
+	// placed from the kraken ui, the order is not listed
+	open, err := cli.GetOpenOrders(internal.XBTEUR)
+	requireNoError(t, err)
+	if len(open) != 1 || open[0].RemoteId != "OQCLML-BW3P3-BUCMWZ" {
+		t.Errorf("unexpected open orders %v", open)
+	}
+	if _, err := cli.GetRemoteOrder("OTRAIL-AAAAA-BBBBBB"); err == nil {
+		t.Errorf("expected an error for the trailing stop order")
+	}
+}
+
+func TestKrakenClosedOrdersPaging(t *testing.T) {
+	// an order closed between the requests shifts the pages,
+	// the second page repeats the order of the first one
+	server := newKrakenServer(map[string]string{
+		"ClosedOrders": `{"closed": {` + closedOrder + `}, "count": 2}`,
+	})
+	defer server.Close()
+
+	closed, err := server.client().GetClosedOrders(internal.ETHEUR, time.Unix(1688148000, 0))
+	requireNoError(t, err)
+	if len(closed) != 1 {
+		t.Errorf("unexpected closed orders %v", closed)
+	}
+	requests := server.getRequests("ClosedOrders")
+	if len(requests) != 2 || requests[0].Params.Get("ofs") != "0" || requests[1].Params.Get("ofs") != "1" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
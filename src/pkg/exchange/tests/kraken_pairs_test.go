@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/shopspring/decimal"
+)
+
+// serves the kraken pairs fixtures, restoring the
+// default registry at the end of the test
+func krakenPairsServer(t *testing.T) *krakenServer {
+	server := newKrakenServer(map[string]string{
+		"Assets":     readFile(t, "testdata/kraken_assets.json"),
+		"AssetPairs": readFile(t, "testdata/kraken_asset_pairs.json"),
+	})
+	t.Cleanup(func() {
+		server.Close()
+		exchange.KrakenPairs = exchange.NewKrakenPairs()
+	})
+	return server
+}
+
+func TestKrakenMarketsData(t *testing.T) {
+	server := krakenPairsServer(t)
+	cli := server.client()
+
+	markets, err := cli.GetMarketsData([]internal.Market{internal.XBTEUR, "SOLEUR", "DOTEUR"})
+	requireNoError(t, err)
+	if markets.GetDecimals("SOLEUR") != 8 || markets.GetTradeCurrency("SOLEUR") != "SOL" || markets.GetReferenceCurrency("SOLEUR") != internal.EUR ||
+		!markets.GetOrderMin("SOLEUR").Equal(decimal.RequireFromString("0.02")) || !markets.GetTickSize("SOLEUR").Equal(decimal.RequireFromString("0.01")) {
+		t.Errorf("unexpected SOLEUR metadata %+v", markets.GetMetadata("SOLEUR"))
+	}
+	if markets.GetTradeCurrency(internal.XBTEUR) != internal.XBT || !markets.GetMinCost(internal.XBTEUR).Equal(decimal.RequireFromString("0.5")) {
+		t.Errorf("unexpected XBTEUR metadata %+v", markets.GetMetadata(internal.XBTEUR))
+	}
+	if markets.GetPriceDecimals("DOTEUR") != 4 || markets.GetStatus("DOTEUR") != internal.MARKET_POST_ONLY || markets.GetStatus(internal.XBTEUR) != internal.MARKET_ONLINE {
+		t.Errorf("unexpected DOTEUR metadata %+v", markets.GetMetadata("DOTEUR"))
+	}
+	if buy, _ := markets.GetLeverages("SOLEUR"); len(buy) != 2 {
+		t.Errorf("unexpected SOLEUR leverages %v", buy)
+	}
+	if !cli.GetLeverage(internal.XBTEUR).Equal(decimal.NewFromInt(5)) || !cli.GetLeverage("SOLEUR").Equal(decimal.NewFromInt(3)) || !cli.GetLeverage("DOTEUR").IsZero() {
+		t.Errorf("unexpected leverages")
+	}
+
+	if _, err := cli.GetMarketsData([]internal.Market{"FOOEUR"}); err == nil {
+		t.Errorf("expected an error for a market not listed by kraken")
+	}
+}
+
+func TestKrakenPairNames(t *testing.T) {
+	server := krakenPairsServer(t)
+	_, err := server.client().GetMarketsData([]internal.Market{})
+	requireNoError(t, err)
+
+	if exchange.Pair("SOLEUR") != "SOLEUR" || exchange.Pair(internal.XBTUSDT) != "XBTUSDT" || exchange.Pair(internal.ETHEUR) != "XETHZEUR" {
+		t.Errorf("unexpected rest names")
+	}
+	if exchange.WsPair("DOTEUR") != "DOT/EUR" || exchange.IWsPair("SOL/EUR") != "SOLEUR" {
+		t.Errorf("unexpected websocket names")
+	}
+	if exchange.IPair("XXBTZEUR") != internal.XBTEUR || exchange.IPair("XBTEUR") != internal.XBTEUR {
+		t.Errorf("unexpected markets")
+	}
+	if _, ok := exchange.IAltPair("XBTEUR.d"); ok {
+		t.Errorf("expected dark pool pairs to be skipped")
+	}
+	if exchange.ICurrency("XXBT") != internal.XBT || exchange.ICurrency("ZEUR") != internal.EUR || exchange.ICurrency("SOL") != "SOL" {
+		t.Errorf("unexpected currencies")
+	}
+}
+
+func TestKrakenFees(t *testing.T) {
+	server := krakenPairsServer(t)
+	cli := server.client()
+
+	// the fees of the lowest tier without the account fees
+	markets, err := cli.GetMarketsData([]internal.Market{internal.XBTEUR, "SOLEUR"})
+	requireNoError(t, err)
+	if fees := markets.GetFees("SOLEUR"); !fees.Taker.Equal(decimal.RequireFromString("0.0026")) || !fees.Maker.Equal(decimal.RequireFromString("0.0016")) || !fees.MarginOpen.IsPositive() {
+		t.Errorf("unexpected base fees %s", fees)
+	}
+
+	server.setResult("TradeVolume", `{"currency": "ZUSD", "volume": "120000.0000",
+		"fees": {"XXBTZEUR": {"fee": "0.2200", "minfee": "0.1000", "maxfee": "0.2600", "nextfee": "0.2000", "nextvolume": "250000.0000", "tiervolume": "100000.0000"}},
+		"fees_maker": {"XXBTZEUR": {"fee": "0.1200", "minfee": "0.0000", "maxfee": "0.1600", "nextfee": "0.1000", "nextvolume": "250000.0000", "tiervolume": "100000.0000"}}}`)
+	markets, err = cli.GetMarketsData([]internal.Market{internal.XBTEUR, "SOLEUR"})
+	requireNoError(t, err)
+	if fees := markets.GetFees(internal.XBTEUR); !fees.Taker.Equal(decimal.RequireFromString("0.0022")) || !fees.Maker.Equal(decimal.RequireFromString("0.0012")) {
+		t.Errorf("unexpected account fees %s", fees)
+	}
+	if params := server.getRequests("TradeVolume")[1].Params; params.Get("pair") != "XXBTZEUR,SOLEUR" {
+		t.Errorf("unexpected params %v", params)
+	}
+}
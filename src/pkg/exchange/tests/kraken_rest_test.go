@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/shopspring/decimal"
+)
+
+func TestKrakenBalanceDecimals(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"TradeBalance": `{"eb":"1000.1234","tb":"999.9999","m":"100.0001","n":"0.3","c":"0","v":"0","e":"1000.3","mf":"900.2999","ml":"1000.29970003"}`,
+	})
+	defer server.Close()
+
+	balance, err := server.client().GetBalance()
+	requireNoError(t, err)
+	if balance.TradeBalance.String() != "999.9999" || balance.InitialMargin.String() != "100.0001" || balance.Equity.String() != "1000.3" ||
+		balance.FreeMargin.String() != "900.2999" || balance.MarginLevel.String() != "1000.29970003" {
+		t.Errorf("unexpected balance %+v", balance)
+	}
+}
+
+func TestKrakenOpenPositions(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"OpenPositions": `{"TF5GVO-T7ZZ2-6NBKBI": {"ordertxid": "OLWNFG-LLH4R-D6SFFP", "posstatus": "open", "pair": "XXBTZEUR", "time": 1605280097.8294,
+			"type": "buy", "ordertype": "limit", "cost": "104610.10000", "fee": "289.06540", "vol": "8.82412861", "vol_closed": "0.20200000",
+			"margin": "20922.02000", "value": "258797.5", "net": "+154186.9728"}}`,
+	})
+	defer server.Close()
+
+	positions, err := server.client().GetOpenPositions(internal.XBTEUR)
+	requireNoError(t, err)
+	if len(positions) != 1 {
+		t.Fatalf("expected 1 position, got %d", len(positions))
+	}
+	position := positions[0]
+	if position.Id != "TF5GVO-T7ZZ2-6NBKBI" || position.Side != internal.BUY || position.Market != internal.XBTEUR || position.Leverage != 5 {
+		t.Errorf("unexpected position %+v", position)
+	}
+	if !position.Size.Equal(decimal.RequireFromString("8.82412861")) || !position.Cost.Equal(decimal.RequireFromString("104610.1")) ||
+		!position.Fee.Equal(decimal.RequireFromString("289.0654")) || !position.Realized.Equal(decimal.RequireFromString("154186.9728")) {
+		t.Errorf("unexpected position amounts %+v", position)
+	}
+	if position.CreatedAt.UnixNano() != 1605280097829400000 {
+		t.Errorf("unexpected creation time %s", position.CreatedAt)
+	}
+	params := server.getRequests("OpenPositions")[0].Params
+	if params.Get("docalcs") != "true" {
+		t.Errorf("unexpected params %v", params)
+	}
+}
+
+func TestKrakenPrivateRequestsSigned(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"TradeBalance": `{"eb":"1000","tb":"1000","m":"0","n":"0","c":"0","v":"0","e":"1000","mf":"1000"}`,
+	})
+	defer server.Close()
+
+	cli := server.client()
+	for i := 0; i < 3; i++ {
+		_, err := cli.GetBalance()
+		requireNoError(t, err)
+	}
+	var last int64
+	for _, req := range server.getRequests("TradeBalance") {
+		if req.Headers.Get("API-Key") != "key" || req.Headers.Get("API-Sign") == "" {
+			t.Errorf("unsigned request %v", req.Headers)
+		}
+		nonce, err := strconv.ParseInt(req.Params.Get("nonce"), 10, 64)
+		requireNoError(t, err)
+		if nonce <= last {
+			t.Errorf("nonce %d not increasing after %d", nonce, last)
+		}
+		last = nonce
+	}
+}
+
+func TestKrakenHtmlResponseUnavailable(t *testing.T) {
+	fastRetries(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("<html>bad gateway</html>"))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	cli := exchange.NewKrakenCliWithClient("key", "c2VjcmV0", &http.Client{Transport: &redirectTransport{target: target}}, exchange.NewKrakenLimits(exchange.KRAKEN_TIER_PRO))
+	_, err := cli.GetBalance()
+	if !errors.Is(err, exchange.ErrUnavailable) {
+		t.Errorf("expected unavailable error, got %v", err)
+	}
+}
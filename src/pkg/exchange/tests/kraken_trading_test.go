@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/shopspring/decimal"
+)
+
+func TestKrakenPlaceOrder(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"AddOrder": `{"descr": {"order": "buy 0.50000000 XBTEUR @ market with 5:1 leverage"}, "txid": ["OUF4EM-FRGI2-MQMWZD"]}`,
+	})
+	defer server.Close()
+
+	order := &entities.Order{
+		Id:            "6d1b345e-2821-40e2-ad83-4ecb18a06876",
+		Market:        internal.XBTEUR,
+		Side:          internal.BUY,
+		PriceType:     internal.MARKET,
+		InitialVolume: decimal.RequireFromString("0.5"),
+		Leverage:      5,
+	}
+	ack, err := server.client().PlaceOrder(order)
+	requireNoError(t, err)
+
+	if ack.RemoteId() != "OUF4EM-FRGI2-MQMWZD" || ack.Id != order.Id || ack.Status != internal.OPEN {
+		t.Errorf("unexpected ack %+v", ack)
+	}
+	if ack.Description != "buy 0.50000000 XBTEUR @ market with 5:1 leverage" {
+		t.Errorf("unexpected description %s", ack.Description)
+	}
+	params := server.getRequests("AddOrder")[0].Params
+	if params.Get("cl_ord_id") != order.Id || params.Get("pair") != "XXBTZEUR" || params.Get("volume") != "0.5" || params.Get("leverage") != "5" {
+		t.Errorf("unexpected params %v", params)
+	}
+}
+
+func TestKrakenPlaceOrderRounded(t *testing.T) {
+	markets := entities.NewMarkets()
+	markets.SetMetadata(internal.XBTEUR, 8, internal.XBT, internal.EUR, decimal.Zero, decimal.Zero)
+	markets.SetTickSize(internal.XBTEUR, decimal.RequireFromString("0.1"))
+	previous := entities.Markets
+	entities.Markets = markets
+	defer func() { entities.Markets = previous }()
+	server := newKrakenServer(map[string]string{"AddOrder": `{"descr": {"order": "buy 0.12345678 XBTEUR @ limit 30010.3"}, "txid": ["OUF4EM-FRGI2-MQMWZD"]}`})
+	defer server.Close()
+
+	_, err := server.client().PlaceOrder(&entities.Order{
+		Id:            "6d1b345e-2821-40e2-ad83-4ecb18a06876",
+		Market:        internal.XBTEUR,
+		Side:          internal.BUY,
+		PriceType:     internal.LIMIT,
+		InitialVolume: decimal.RequireFromString("0.123456789"),
+		LimitPrice:    decimal.RequireFromString("30010.26"),
+	})
+	requireNoError(t, err)
+	if params := server.getRequests("AddOrder")[0].Params; params.Get("volume") != "0.12345678" || params.Get("price") != "30010.3" {
+		t.Errorf("expected the volume and the price to be rounded, got %v", params)
+	}
+}
+
+func TestKrakenPlaceOrderRejected(t *testing.T) {
+	server := newKrakenServer(map[string]string{})
+	server.setError("AddOrder", "EOrder:Insufficient funds")
+	defer server.Close()
+
+	ack, err := server.client().PlaceOrder(&entities.Order{Id: "id", Market: internal.XBTEUR, Side: internal.SELL, PriceType: internal.MARKET})
+	if err == nil || ack != nil {
+		t.Errorf("expected an error, got %+v", ack)
+	}
+}
+
+func TestKrakenCancelOrder(t *testing.T) {
+	server := newKrakenServer(map[string]string{"CancelOrder": `{"count": 1}`})
+	defer server.Close()
+
+	requireNoError(t, server.client().CancelOrder("OYVGEW-VYV5B-UUEXSK"))
+	if params := server.getRequests("CancelOrder")[0].Params; params.Get("txid") != "OYVGEW-VYV5B-UUEXSK" {
+		t.Errorf("unexpected params %v", params)
+	}
+
+	server.setResult("CancelOrder", `{"count": 0}`)
+	if err := server.client().CancelOrder("OYVGEW-VYV5B-UUEXSK"); !errors.Is(err, exchange.ErrOrderNotFound) {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestKrakenCancelAll(t *testing.T) {
+	server := newKrakenServer(map[string]string{"CancelAll": `{"count": 4}`})
+	defer server.Close()
+
+	count, err := server.client().CancelAll()
+	requireNoError(t, err)
+	if count != 4 {
+		t.Errorf("expected 4 cancelled orders, got %d", count)
+	}
+	request := server.getRequests("CancelAll")[0]
+	if request.Params.Get("nonce") == "" || request.Headers.Get("API-Key") != "key" || request.Headers.Get("API-Sign") == "" {
+		t.Errorf("expected a signed request, got %v %v", request.Params, request.Headers)
+	}
+}
+
+func TestKrakenCancelAllAfter(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"CancelAllOrdersAfter": `{"currentTime": "2023-03-24T17:41:56Z", "triggerTime": "2023-03-24T17:42:56Z"}`,
+	})
+	defer server.Close()
+	cli := server.client()
+
+	trigger, err := cli.CancelAllAfter(time.Minute)
+	requireNoError(t, err)
+	if !trigger.Equal(time.Date(2023, 3, 24, 17, 42, 56, 0, time.UTC)) {
+		t.Errorf("unexpected trigger time %s", trigger)
+	}
+	if params := server.getRequests("CancelAllOrdersAfter")[0].Params; params.Get("timeout") != "60" {
+		t.Errorf("unexpected params %v", params)
+	}
+
+	server.setResult("CancelAllOrdersAfter", `{"currentTime": "2023-03-24T17:41:56Z", "triggerTime": "0"}`)
+	trigger, err = cli.CancelAllAfter(0)
+	requireNoError(t, err)
+	if !trigger.IsZero() {
+		t.Errorf("expected a disarmed switch, got %s", trigger)
+	}
+
+	server.setError("CancelAllOrdersAfter", "EGeneral:Permission denied")
+	if _, err := cli.CancelAllAfter(time.Minute); err == nil {
+		t.Errorf("expected an error")
+	}
+}
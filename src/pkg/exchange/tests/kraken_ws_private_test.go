@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/shopspring/decimal"
+)
+
+const executionFrames = "testdata/kraken_ws_executions.jsonl"
+
+// returns a token function counting the issued tokens
+func tokens() (func() (string, error), func() int) {
+	var mu sync.Mutex
+	issued := 0
+	return func() (string, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			issued++
+			return "token-" + string(rune('0'+issued)), nil
+		}, func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return issued
+		}
+}
+
+func TestKrakenOrdersFeedExecutions(t *testing.T) {
+	server := newReplayServer(loadFrames(t, executionFrames), 1)
+	defer server.Close()
+	token, _ := tokens()
+	feed := exchange.NewKrakenOrdersFeed(server.wsUrl(), token, time.Second, 10*time.Millisecond)
+	defer feed.Close()
+
+	updates, err := feed.SubscribeOrders()
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+	var received []entities.OrderUpdate
+	for len(received) < 4 {
+		select {
+		case update := <-updates:
+			received = append(received, update)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d updates, expected 4", len(received))
+		}
+	}
+
+	expected := []internal.OrderStatus{internal.CREATED, internal.OPEN, internal.OPEN, internal.FILLED}
+	for i, status := range expected {
+		if received[i].Status != status || received[i].RemoteId != "OABCDE-12345-XYZAB" {
+			t.Errorf("unexpected update %d: %+v", i, received[i])
+		}
+	}
+	if received[0].Id != "2c8e1c2d-6b3a-4c55-8f1a-2a6f1b0c9d11" {
+		t.Errorf("expected the client order id, got %s", received[0].Id)
+	}
+	filled := received[3]
+	if !filled.ExecutedVolume.Equal(decimal.RequireFromString("0.5")) || !filled.AveragePrice.Equal(decimal.RequireFromString("34000.7")) || !filled.Fee.Equal(decimal.RequireFromString("2.75")) {
+		t.Errorf("unexpected fill %+v", filled)
+	}
+	subscriptions := server.getSubscriptions()
+	if len(subscriptions) != 1 || !strings.Contains(subscriptions[0], `"channel":"executions"`) || !strings.Contains(subscriptions[0], `"token":"token-1"`) {
+		t.Errorf("unexpected subscriptions %v", subscriptions)
+	}
+}
+
+func TestKrakenOrdersFeedRefreshesToken(t *testing.T) {
+	server := newReplayServer(loadFrames(t, executionFrames), 1)
+	server.dropFirst = true
+	defer server.Close()
+	token, issued := tokens()
+	feed := exchange.NewKrakenOrdersFeed(server.wsUrl(), token, time.Second, 10*time.Millisecond)
+	defer feed.Close()
+
+	updates, err := feed.SubscribeOrders()
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		select {
+		case <-updates:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d updates, expected 8", i)
+		}
+	}
+	if issued() < 2 {
+		t.Errorf("expected a new token for the reconnection")
+	}
+	subscriptions := server.getSubscriptions()
+	if len(subscriptions) != 2 || !strings.Contains(subscriptions[1], `"token":"token-2"`) {
+		t.Errorf("unexpected subscriptions %v", subscriptions)
+	}
+}
+
+func TestKrakenOrdersFeedNotifiesConnections(t *testing.T) {
+	server := newReplayServer(loadFrames(t, executionFrames), 1)
+	server.dropFirst = true
+	defer server.Close()
+	token, _ := tokens()
+	feed := exchange.NewKrakenOrdersFeed(server.wsUrl(), token, time.Second, 10*time.Millisecond)
+	defer feed.Close()
+
+	updates, err := feed.SubscribeOrders()
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+	go func() {
+		for range updates {
+		}
+	}()
+	// the first connection and the reconnection are both notified
+	for i := 0; i < 2; i++ {
+		select {
+		case <-feed.Connected():
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d connections, expected 2", i)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/shopspring/decimal"
+)
+
+const publicFrames = "testdata/kraken_ws_public.jsonl"
+
+func receiveCandles(t *testing.T, candles chan entities.Candle, n int) []entities.Candle {
+	var res []entities.Candle
+	for len(res) < n {
+		select {
+		case candle := <-candles:
+			res = append(res, candle)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d candles, expected %d", len(res), n)
+		}
+	}
+	return res
+}
+
+func TestKrakenFeedOHLC(t *testing.T) {
+	server := newReplayServer(loadFrames(t, publicFrames), 1)
+	defer server.Close()
+	feed := exchange.NewKrakenFeed(server.wsUrl(), time.Second, 10*time.Millisecond)
+	defer feed.Close()
+
+	candles, err := feed.SubscribeOHLC(internal.XBTEUR, 1)
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+	received := receiveCandles(t, candles, 3)
+
+	if !received[0].Timestamp.Equal(time.Unix(1699999980, 0)) {
+		t.Errorf("unexpected candle timestamp %s", received[0].Timestamp)
+	}
+	if !received[1].Close.Equal(decimal.RequireFromString("34015")) || !received[1].High.Equal(decimal.RequireFromString("34020")) {
+		t.Errorf("unexpected candle update %s", received[1].String())
+	}
+	if !received[1].Volume.Equal(decimal.RequireFromString("2.5")) || !received[1].Vwap.Equal(decimal.RequireFromString("34006")) || received[1].Count != 20 {
+		t.Errorf("unexpected candle volume %s", received[1].String())
+	}
+	if !received[2].Timestamp.Equal(time.Unix(1700000040, 0)) {
+		t.Errorf("unexpected timestamp for the new interval %s", received[2].Timestamp)
+	}
+	subscriptions := server.getSubscriptions()
+	if len(subscriptions) != 1 || !strings.Contains(subscriptions[0], `"pair":["XBT/EUR"]`) || !strings.Contains(subscriptions[0], `"interval":1`) {
+		t.Errorf("unexpected subscriptions %v", subscriptions)
+	}
+}
+
+func TestKrakenFeedTradesAndTicker(t *testing.T) {
+	server := newReplayServer(loadFrames(t, publicFrames), 2)
+	defer server.Close()
+	feed := exchange.NewKrakenFeed(server.wsUrl(), time.Second, 10*time.Millisecond)
+	defer feed.Close()
+
+	trades, err := feed.SubscribeTrades(internal.XBTEUR)
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+	tickers, err := feed.SubscribeTicker(internal.XBTEUR)
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+
+	for _, expected := range []entities.Trade{
+		{Price: decimal.RequireFromString("34011"), Side: internal.SELL, PriceType: internal.MARKET},
+		{Price: decimal.RequireFromString("34012"), Side: internal.BUY, PriceType: internal.LIMIT},
+	} {
+		select {
+		case trade := <-trades:
+			if !trade.Price.Equal(expected.Price) || trade.Side != expected.Side || trade.PriceType != expected.PriceType || trade.Market != internal.XBTEUR {
+				t.Errorf("unexpected trade %s", trade.String())
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no trade received")
+		}
+	}
+	select {
+	case ticker := <-tickers:
+		if !ticker.Ask.Equal(decimal.RequireFromString("34012.1")) || !ticker.Bid.Equal(decimal.RequireFromString("34011.9")) || !ticker.Volume.Equal(decimal.RequireFromString("980.25")) {
+			t.Errorf("unexpected ticker %s", ticker.String())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no ticker received")
+	}
+}
+
+func TestKrakenFeedResubscribesAfterReconnect(t *testing.T) {
+	server := newReplayServer(loadFrames(t, publicFrames), 1)
+	server.dropFirst = true
+	defer server.Close()
+	feed := exchange.NewKrakenFeed(server.wsUrl(), time.Second, 10*time.Millisecond)
+	defer feed.Close()
+
+	candles, err := feed.SubscribeOHLC(internal.XBTEUR, 1)
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+	// frames are replayed on both connections
+	receiveCandles(t, candles, 6)
+	if server.getConnections() != 2 {
+		t.Errorf("expected 2 connections, got %d", server.getConnections())
+	}
+	if len(server.getSubscriptions()) != 2 {
+		t.Errorf("expected the subscription to be sent again, got %v", server.getSubscriptions())
+	}
+}
+
+func TestKrakenFeedReconnectsOnMissingHeartbeat(t *testing.T) {
+	server := newReplayServer(loadFrames(t, publicFrames), 1)
+	server.silent = true
+	defer server.Close()
+	feed := exchange.NewKrakenFeed(server.wsUrl(), 50*time.Millisecond, 10*time.Millisecond)
+	defer feed.Close()
+
+	if _, err := feed.SubscribeOHLC(internal.XBTEUR, 1); err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for server.getConnections() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if server.getConnections() < 2 {
+		t.Errorf("expected a reconnection after the heartbeat timeout")
+	}
+}
+
+func TestKrakenFeedClosesChannels(t *testing.T) {
+	server := newReplayServer(loadFrames(t, publicFrames), 1)
+	defer server.Close()
+	feed := exchange.NewKrakenFeed(server.wsUrl(), time.Second, 10*time.Millisecond)
+
+	candles, err := feed.SubscribeOHLC(internal.XBTEUR, 1)
+	if err != nil {
+		t.Fatalf("error subscribing: %v", err)
+	}
+	if _, err := feed.SubscribeOHLC(internal.XBTEUR, 1); err == nil {
+		t.Errorf("expected an error subscribing twice")
+	}
+	feed.Close()
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-candles:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("channel not closed")
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/shopspring/decimal"
+)
+
+// live market data returning a single candle
+type fakeMarketData struct {
+	close decimal.Decimal
+}
+
+func (f *fakeMarketData) GetOHLC(pair internal.Market, interval int) ([]entities.Candle, error) {
+	return []entities.Candle{{Close: f.close, Timestamp: time.Now()}}, nil
+}
+
+func (f *fakeMarketData) GetMarketsData(markets []internal.Market) (entities.IMarkets, error) {
+	return entities.NewMarkets(), nil
+}
+
+func (f *fakeMarketData) GetLeverage(market internal.Market) decimal.Decimal {
+	return decimal.NewFromInt(2)
+}
+
+func paperCandle(low, high, close int64) entities.Candle {
+	return entities.Candle{
+		Open:      decimal.NewFromInt(close),
+		High:      decimal.NewFromInt(high),
+		Low:       decimal.NewFromInt(low),
+		Close:     decimal.NewFromInt(close),
+		Timestamp: time.Now(),
+	}
+}
+
+func TestPaperMarketOrders(t *testing.T) {
+	paper := exchange.NewPaperExchange(&fakeMarketData{close: decimal.NewFromInt(100)}, decimal.NewFromInt(1000), decimal.RequireFromString("0.01"))
+
+	// no price received yet, the last live candle is used
+	ack, err := paper.PlaceOrder(&entities.Order{Id: "open", Market: internal.XBTEUR, Side: internal.BUY, PriceType: internal.MARKET, InitialVolume: decimal.NewFromInt(2), Leverage: 2})
+	requireNoError(t, err)
+	if ack.Status != internal.FILLED {
+		t.Errorf("expected the market order to be filled, got %s", ack.Status)
+	}
+
+	paper.UpdatePrice(internal.XBTEUR, paperCandle(100, 120, 110))
+	positions, err := paper.GetOpenPositions(internal.XBTEUR)
+	requireNoError(t, err)
+	if len(positions) != 1 || positions[0].Side != internal.BUY || !positions[0].Size.Equal(decimal.NewFromInt(2)) || !positions[0].OpenPrice.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("unexpected positions %v", positions)
+	}
+	balance, err := paper.GetBalance()
+	requireNoError(t, err)
+	// 1000 - 2 fee, +20 unrealized, 100 margin with leverage 2
+	if !balance.TradeBalance.Equal(decimal.NewFromInt(998)) || !balance.Equity.Equal(decimal.NewFromInt(1018)) || !balance.FreeMargin.Equal(decimal.NewFromInt(918)) {
+		t.Errorf("unexpected balance %+v", balance)
+	}
+
+	// reduce only orders are capped to the position size
+	ack, err = paper.PlaceOrder(&entities.Order{Id: "close", Market: internal.XBTEUR, Side: internal.SELL, PriceType: internal.MARKET, InitialVolume: decimal.NewFromInt(5), ReduceOnly: true})
+	requireNoError(t, err)
+	order, err := paper.GetRemoteOrder(ack.RemoteId())
+	requireNoError(t, err)
+	if !order.ExecutedVolume.Equal(decimal.NewFromInt(2)) || !order.AveragePrice.Equal(decimal.NewFromInt(110)) || !order.Fee.Equal(decimal.RequireFromString("2.2")) {
+		t.Errorf("unexpected closing order %+v", order)
+	}
+	realized, unrealized := paper.GetPnL()
+	if !realized.Equal(decimal.NewFromInt(20)) || !unrealized.IsZero() {
+		t.Errorf("unexpected pnl %s %s", realized, unrealized)
+	}
+	if positions, _ := paper.GetOpenPositions(internal.XBTEUR); len(positions) != 0 {
+		t.Errorf("expected the position to be closed, got %v", positions)
+	}
+	if _, err := paper.PlaceOrder(&entities.Order{Market: internal.XBTEUR, Side: internal.SELL, PriceType: internal.MARKET, InitialVolume: decimal.NewFromInt(1), ReduceOnly: true}); err == nil {
+		t.Errorf("expected an error reducing a missing position")
+	}
+}
+
+func TestPaperLimitOrders(t *testing.T) {
+	paper := exchange.NewPaperExchange(&fakeMarketData{}, decimal.NewFromInt(1000), decimal.Zero)
+	updates, err := paper.SubscribeOrders()
+	requireNoError(t, err)
+	paper.UpdatePrice(internal.XBTEUR, paperCandle(100, 100, 100))
+
+	ack, err := paper.PlaceOrder(&entities.Order{Id: "limit", Market: internal.XBTEUR, Side: internal.SELL, PriceType: internal.LIMIT, InitialVolume: decimal.NewFromInt(1), LimitPrice: decimal.NewFromInt(105)})
+	requireNoError(t, err)
+	if ack.Status != internal.OPEN {
+		t.Fatalf("expected the limit order to rest, got %s", ack.Status)
+	}
+	if open, _ := paper.GetOpenOrders(internal.XBTEUR); len(open) != 1 {
+		t.Errorf("unexpected open orders %v", open)
+	}
+
+	paper.UpdatePrice(internal.XBTEUR, paperCandle(98, 104, 103))
+	if order, _ := paper.GetOrder("limit"); order.Status != internal.OPEN {
+		t.Errorf("expected the order to rest below the limit, got %s", order.Status)
+	}
+	paper.UpdatePrice(internal.XBTEUR, paperCandle(102, 106, 104))
+	select {
+	case update := <-updates:
+		if update.Id != "limit" || update.Status != internal.FILLED || !update.AveragePrice.Equal(decimal.NewFromInt(105)) {
+			t.Errorf("unexpected update %+v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no update received")
+	}
+	positions, _ := paper.GetOpenPositions(internal.XBTEUR)
+	if len(positions) != 1 || positions[0].Side != internal.SELL {
+		t.Errorf("expected a short position, got %v", positions)
+	}
+	if closed, _ := paper.GetClosedOrders(internal.XBTEUR, time.Now().Add(-time.Minute)); len(closed) != 1 {
+		t.Errorf("unexpected closed orders %v", closed)
+	}
+
+	ack, err = paper.PlaceOrder(&entities.Order{Id: "ioc", Market: internal.XBTEUR, Side: internal.BUY, PriceType: internal.LIMIT, InitialVolume: decimal.NewFromInt(1), LimitPrice: decimal.NewFromInt(90), Ioc: true})
+	requireNoError(t, err)
+	if ack.Status != internal.CANCELLED {
+		t.Errorf("expected the ioc order to be cancelled, got %s", ack.Status)
+	}
+	ack, err = paper.PlaceOrder(&entities.Order{Id: "resting", Market: internal.XBTEUR, Side: internal.BUY, PriceType: internal.LIMIT, InitialVolume: decimal.NewFromInt(1), LimitPrice: decimal.NewFromInt(90)})
+	requireNoError(t, err)
+	requireNoError(t, paper.CancelOrder(ack.RemoteId()))
+	if err := paper.CancelOrder(ack.RemoteId()); !errors.Is(err, exchange.ErrOrderNotFound) {
+		t.Errorf("expected not found cancelling twice, got %v", err)
+	}
+	paper.Close()
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+)
+
+func TestRateLimiterDecay(t *testing.T) {
+	limiter := exchange.NewDecayingLimiter(2, 20, 0)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		limiter.Wait(exchange.PRIORITY_HIGH, 1)
+	}
+	// two calls fit the budget, the other two wait 50ms each
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond || elapsed > time.Second {
+		t.Errorf("unexpected wait %s", elapsed)
+	}
+	if usage := limiter.Usage(); usage.Counter > 2 || usage.Max != 2 || usage.Waiting != 0 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter := exchange.NewDecayingLimiter(4, 10, 2)
+	limiter.Wait(exchange.PRIORITY_LOW, 2)
+
+	// the reserved budget is available to high priority calls only
+	start := time.Now()
+	limiter.Wait(exchange.PRIORITY_HIGH, 2)
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("high priority call waited %s", elapsed)
+	}
+	start = time.Now()
+	limiter.Wait(exchange.PRIORITY_LOW, 1)
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("low priority call waited only %s", elapsed)
+	}
+}
+
+func TestRateLimiterPriority(t *testing.T) {
+	limiter := exchange.NewDecayingLimiter(1, 10, 0)
+	limiter.Wait(exchange.PRIORITY_HIGH, 1)
+
+	var mu sync.Mutex
+	var order []exchange.Priority
+	var wg sync.WaitGroup
+	acquire := func(priority exchange.Priority) {
+		defer wg.Done()
+		limiter.Wait(priority, 1)
+		mu.Lock()
+		order = append(order, priority)
+		mu.Unlock()
+	}
+	wg.Add(2)
+	go acquire(exchange.PRIORITY_LOW)
+	time.Sleep(20 * time.Millisecond)
+	go acquire(exchange.PRIORITY_HIGH)
+	time.Sleep(20 * time.Millisecond)
+	if usage := limiter.Usage(); usage.Waiting != 2 {
+		t.Errorf("expected two waiting calls, got %+v", usage)
+	}
+	wg.Wait()
+	if len(order) != 2 || order[0] != exchange.PRIORITY_HIGH {
+		t.Errorf("expected the high priority call first, got %v", order)
+	}
+}
+
+func TestKrakenCancelCost(t *testing.T) {
+	if exchange.KrakenCancelCost(time.Second) != 8 || exchange.KrakenCancelCost(time.Minute) != 2 || exchange.KrakenCancelCost(10*time.Minute) != 0 {
+		t.Errorf("unexpected cancellation costs")
+	}
+}
+
+func TestKrakenLimitsUsage(t *testing.T) {
+	server := newKrakenServer(map[string]string{
+		"TradeBalance": `{"eb":"1000","tb":"1000","m":"0","n":"0","c":"0","v":"0","e":"1000","mf":"1000"}`,
+	})
+	defer server.Close()
+	limits := exchange.NewKrakenLimits(exchange.KRAKEN_TIER_STARTER)
+	cli := server.clientWithLimits(limits)
+
+	_, err := cli.GetBalance()
+	requireNoError(t, err)
+	if usage := limits.Usage()["api"]; usage.Counter < 0.9 || usage.Max != 15 {
+		t.Errorf("unexpected api usage %+v", usage)
+	}
+
+	// not retried, the limiter would wait for the full counter to decay
+	policy := exchange.KrakenRetryPolicy
+	exchange.KrakenRetryPolicy = exchange.RetryPolicy{Attempts: 1}
+	defer func() { exchange.KrakenRetryPolicy = policy }()
+	server.setError("TradeBalance", "EAPI:Rate limit exceeded")
+	if _, err := cli.GetBalance(); !errors.Is(err, exchange.ErrRateLimited) {
+		t.Fatalf("expected the rate limit error")
+	}
+	if usage := limits.Usage()["api"]; usage.Counter < 14 {
+		t.Errorf("expected a full counter after a rate limit error, got %+v", usage)
+	}
+}
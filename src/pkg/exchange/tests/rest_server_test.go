@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+)
+
+// local stand-in of the kraken rest api, answering every
+// method with the configured result and recording the requests
+type krakenServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	results  map[string]string
+	errors   map[string]string
+	failures map[string][]string // returned once each, before the result
+	requests []krakenRequest
+}
+
+type krakenRequest struct {
+	Method  string
+	Params  url.Values
+	Headers http.Header
+}
+
+func newKrakenServer(results map[string]string) *krakenServer {
+	s := &krakenServer{results: results, errors: map[string]string{}, failures: map[string][]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the private requests send their params as a form
+		body, _ := io.ReadAll(r.Body)
+		params, _ := url.ParseQuery(string(body))
+		for key, values := range r.URL.Query() {
+			params[key] = values
+		}
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		s.mu.Lock()
+		s.requests = append(s.requests, krakenRequest{Method: method, Params: params, Headers: r.Header})
+		result, ok := s.results[method]
+		krakenErr, failing := s.errors[method]
+		if failures := s.failures[method]; len(failures) > 0 {
+			krakenErr, failing = failures[0], true
+			s.failures[method] = failures[1:]
+		}
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case failing:
+			w.Write([]byte(`{"error":["` + krakenErr + `"]}`))
+		case ok:
+			w.Write([]byte(`{"error":[],"result":` + result + `}`))
+		default:
+			w.Write([]byte(`{"error":["EGeneral:Unknown method"]}`))
+		}
+	}))
+	return s
+}
+
+// returns a kraken client sending every request to the server
+func (s *krakenServer) client() exchange.IExchange {
+	return s.clientWithLimits(exchange.NewKrakenLimits(exchange.KRAKEN_TIER_PRO))
+}
+
+func (s *krakenServer) clientWithLimits(limits *exchange.KrakenLimits) exchange.IExchange {
+	target, _ := url.Parse(s.URL)
+	return exchange.NewKrakenCliWithClient("key", "c2VjcmV0", &http.Client{Transport: &redirectTransport{target: target}}, limits)
+}
+
+func (s *krakenServer) setResult(method string, result string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[method] = result
+}
+
+func (s *krakenServer) setError(method string, err string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[method] = err
+}
+
+// fails the next calls of the method with the errors, in order
+func (s *krakenServer) failNext(method string, errs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[method] = append(s.failures[method], errs...)
+}
+
+func (s *krakenServer) getRequests(method string) []krakenRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []krakenRequest
+	for _, req := range s.requests {
+		if req.Method == method {
+			res = append(res, req)
+		}
+	}
+	return res
+}
+
+// rewrites the requests to the local server
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func requireNoError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
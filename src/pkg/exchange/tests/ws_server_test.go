@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// local websocket stand-in which replays recorded frames
+// once the expected subscriptions are received
+type replayServer struct {
+	*httptest.Server
+	frames        [][]byte
+	expected      int
+	dropFirst     bool // close the first connection once the frames are replayed
+	silent        bool // never send any frame
+	mu            sync.Mutex
+	connections   int
+	subscriptions []map[string]interface{}
+	messages      []map[string]interface{}
+}
+
+func loadFrames(t *testing.T, path string) [][]byte {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("error opening %s: %v", path, err)
+	}
+	defer file.Close()
+	var frames [][]byte
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			frames = append(frames, []byte(line))
+		}
+	}
+	return frames
+}
+
+func newReplayServer(frames [][]byte, expected int) *replayServer {
+	s := &replayServer{frames: frames, expected: expected}
+	upgrader := websocket.Upgrader{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s.mu.Lock()
+		s.connections++
+		connection := s.connections
+		s.mu.Unlock()
+		for received := 0; received < s.expected; received++ {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.subscriptions = append(s.subscriptions, msg)
+			s.mu.Unlock()
+		}
+		if !s.silent {
+			for _, frame := range s.frames {
+				if err := conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+					return
+				}
+			}
+		}
+		if s.dropFirst && connection == 1 {
+			return
+		}
+		// keep the connection open until the client leaves,
+		// recording the messages sent after the frames
+		for {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+		}
+	}))
+	return s
+}
+
+func (s *replayServer) wsUrl() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+func (s *replayServer) getConnections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+func (s *replayServer) getSubscriptions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []string
+	for _, msg := range s.subscriptions {
+		raw, _ := json.Marshal(msg)
+		res = append(res, string(raw))
+	}
+	return res
+}
+
+func (s *replayServer) getMessages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []string
+	for _, msg := range s.messages {
+		raw, _ := json.Marshal(msg)
+		res = append(res, string(raw))
+	}
+	return res
+}
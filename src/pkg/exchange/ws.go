@@ -0,0 +1,177 @@
+package exchange
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const MAX_RECONNECT_DELAY = time.Minute
+
+var ErrNotConnected = errors.New("websocket not connected")
+
+// websocket connection which reconnects automatically when the
+// connection drops or when nothing (not even a heartbeat) is received
+// within the heartbeat timeout. every registered subscription is sent
+// again after a reconnection
+type wsClient struct {
+	name             string
+	url              string
+	heartbeatTimeout time.Duration
+	reconnectDelay   time.Duration
+	// called before each connection attempt, an error delays the attempt
+	beforeConnect func() error
+	// called with every message received
+	onMessage func(msg []byte)
+	// called once the client is closed and the read loop has exited
+	onClose func()
+	// called after every connection, once the subscriptions are sent
+	onConnect func()
+
+	mu            sync.Mutex
+	conn          *websocket.Conn
+	subscriptions []func() interface{}
+	done          chan struct{}
+	closeOnce     sync.Once
+}
+
+func newWsClient(name string, url string, heartbeatTimeout time.Duration, reconnectDelay time.Duration) *wsClient {
+	return &wsClient{
+		name:             name,
+		url:              url,
+		heartbeatTimeout: heartbeatTimeout,
+		reconnectDelay:   reconnectDelay,
+		beforeConnect:    func() error { return nil },
+		onMessage:        func(msg []byte) {},
+		onClose:          func() {},
+		onConnect:        func() {},
+		done:             make(chan struct{}),
+	}
+}
+
+func (c *wsClient) start() {
+	go c.run()
+}
+
+func (c *wsClient) run() {
+	defer c.onClose()
+	delay := c.reconnectDelay
+	for !c.isClosed() {
+		conn, err := c.connect()
+		if err != nil {
+			logrus.Warnf("[%s] error connecting to %s: %v, retrying in %s", c.name, c.url, err, delay)
+			if !c.wait(delay) {
+				return
+			}
+			delay *= 2
+			if delay > MAX_RECONNECT_DELAY {
+				delay = MAX_RECONNECT_DELAY
+			}
+			continue
+		}
+		delay = c.reconnectDelay
+		c.onConnect()
+		c.read(conn)
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+		conn.Close()
+		if !c.wait(delay) {
+			return
+		}
+	}
+}
+
+// dials the server and sends every subscription
+func (c *wsClient) connect() (*websocket.Conn, error) {
+	if err := c.beforeConnect(); err != nil {
+		return nil, err
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.isClosed() {
+		conn.Close()
+		return nil, ErrNotConnected
+	}
+	for _, subscription := range c.subscriptions {
+		if err := conn.WriteJSON(subscription()); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	c.conn = conn
+	logrus.Infof("[%s] connected to %s", c.name, c.url)
+	return conn, nil
+}
+
+func (c *wsClient) read(conn *websocket.Conn) {
+	for {
+		conn.SetReadDeadline(time.Now().Add(c.heartbeatTimeout))
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if !c.isClosed() {
+				logrus.Warnf("[%s] connection lost: %v", c.name, err)
+			}
+			return
+		}
+		c.onMessage(msg)
+	}
+}
+
+// registers a subscription, which is sent right away if connected
+// and again after every reconnection
+func (c *wsClient) subscribe(subscription func() interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscriptions = append(c.subscriptions, subscription)
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.WriteJSON(subscription())
+}
+
+// sends a message on the current connection
+func (c *wsClient) send(msg interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *wsClient) wait(d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
+func (c *wsClient) isClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.conn != nil {
+			c.conn.Close()
+		}
+	})
+}
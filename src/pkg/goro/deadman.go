@@ -0,0 +1,43 @@
+package goro
+
+import (
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/sirupsen/logrus"
+)
+
+// goroutine which re-arms the exchange dead man's switch every quarter
+// of the timeout, so that resting orders are cancelled if the process
+// dies. the switch is disarmed once done is closed
+func DeadManSwitch(ex exchange.ITrading, timeout time.Duration, done chan struct{}, wg *sync.WaitGroup) {
+	logrus.Infof("[DEADMAN] arming dead man's switch (timeout %s)", timeout)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		arm(ex, timeout)
+		tick := time.NewTicker(timeout / 4)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				arm(ex, timeout)
+			case <-done:
+				if _, err := ex.CancelAllAfter(0); err != nil {
+					logrus.Warnf("[DEADMAN] error %v disarming dead man's switch", err)
+				}
+				return
+			}
+		}
+	}()
+}
+
+func arm(ex exchange.ITrading, timeout time.Duration) {
+	trigger, err := ex.CancelAllAfter(timeout)
+	if err != nil {
+		logrus.Warnf("[DEADMAN] error %v re-arming dead man's switch", err)
+		return
+	}
+	logrus.Debugf("[DEADMAN] orders will be cancelled at %s", trigger)
+}
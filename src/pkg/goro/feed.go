@@ -0,0 +1,50 @@
+package goro
+
+import (
+	"sync"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/sirupsen/logrus"
+)
+
+// streams ohlc data from the market feed and updates the given
+// trend instance with each candle once its interval is closed.
+// every update of the candle in progress is sent as well, after
+// the candle closed by it
+func StreamOHLC(feed exchange.IMarketFeed, pair internal.Market, trend entities.ITrend, timeframe int, wg *sync.WaitGroup) (chan entities.Candle, error) {
+	logrus.Infof("[%s] streaming ohlc data (interval %d)", pair, timeframe)
+	updates, err := feed.SubscribeOHLC(pair, timeframe)
+	if err != nil {
+		return nil, err
+	}
+	candles := make(chan entities.Candle)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(candles)
+		var last *entities.Candle
+		for update := range updates {
+			if last != nil && update.Timestamp.Before(last.Timestamp) {
+				// stale update replayed after a reconnection
+				continue
+			}
+			if last != nil && update.Timestamp.After(last.Timestamp) {
+				// a new interval started, the previous candle is closed
+				closed := *last
+				frameCandles := *trend.GetCandles(timeframe)
+				// skip candles already loaded in the trend
+				if len(frameCandles) == 0 || frameCandles[len(frameCandles)-1].Timestamp.Before(closed.Timestamp) {
+					trend.Update(closed, timeframe)
+					candles <- closed.WithForming(false)
+				}
+			}
+			next := update
+			last = &next
+			trend.SetForming(update, timeframe)
+			candles <- update.WithForming(true)
+		}
+	}()
+	return candles, nil
+}
@@ -0,0 +1,125 @@
+package goro
+
+import (
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/history"
+	"github.com/sirupsen/logrus"
+)
+
+// longest wait between two attempts to repair a gap
+const MAX_GAP_BACKOFF = time.Hour
+
+// goroutine which checks the candles of the trend timeframes every
+// interval, reloading the missing ones through the backfill, until
+// done is closed. gaps which can't be reloaded (e.g. intervals
+// without trades) are retried less and less often. when flat is set
+// the gaps left, the intervals without any trade, are filled with
+// flat candles. a feed not sending candles anymore is reported
+func RepairGaps(backfill history.IBackfill, pair internal.Market, trend entities.ITrend, interval time.Duration, flat bool, done chan struct{}, wg *sync.WaitGroup) {
+	retries := map[int]map[entities.Gap]*gapRetry{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				for _, timeframe := range trend.Timeframes() {
+					if retries[timeframe] == nil {
+						retries[timeframe] = map[entities.Gap]*gapRetry{}
+					}
+					repairGaps(backfill, pair, trend, timeframe, flat, interval, retries[timeframe])
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// attempts to repair a gap, the wait doubling after each one
+type gapRetry struct {
+	attempts int
+	next     time.Time
+}
+
+func repairGaps(backfill history.IBackfill, pair internal.Market, trend entities.ITrend, timeframe int, flat bool, every time.Duration, retries map[entities.Gap]*gapRetry) {
+	interval := entities.Timeframe(timeframe).Duration()
+	// sub-minute candles are only built from the trades, quiet markets have none
+	if gap, ok := entities.TrailingGap(*trend.GetCandles(timeframe), interval, time.Now()); ok && timeframe > 0 {
+		logrus.Warnf("[%s] no %s candle received since %s, feed stalled", pair, entities.Timeframe(timeframe), gap.From)
+	}
+	quality := trend.GetQuality(timeframe)
+	now := time.Now()
+	var due []entities.Gap
+	for _, gap := range quality.Gaps {
+		if retry, ok := retries[gap]; !ok || !now.Before(retry.next) {
+			due = append(due, gap)
+		}
+	}
+	if len(due) == 0 {
+		forgetRepaired(retries, quality.Gaps)
+		return
+	}
+	logrus.Warnf("[%s] %s candles gapped: %s", pair, entities.Timeframe(timeframe), quality)
+	// sub-minute candles are only built live
+	if timeframe > 0 {
+		size := int(time.Since(due[0].From)/interval) + 1
+		candles, err := backfill.GetCandles(pair, timeframe, size)
+		if err != nil {
+			logrus.Warnf("[%s] error %v reloading the missing %s candles", pair, err, entities.Timeframe(timeframe))
+		} else {
+			// the following candles are left to the ohlc goroutines,
+			// which send them to the strategy once added
+			last := due[len(due)-1].Until
+			var missing []entities.Candle
+			for _, candle := range candles {
+				if candle.Timestamp.Before(last) {
+					missing = append(missing, candle)
+				}
+			}
+			trend.Merge(missing, timeframe)
+		}
+	}
+	if flat {
+		trend.Merge(entities.FillGaps(*trend.GetCandles(timeframe), interval), timeframe)
+	}
+	repaired := trend.GetQuality(timeframe)
+	forgetRepaired(retries, repaired.Gaps)
+	// the gaps left are retried later, the new ones
+	// (e.g. partially repaired) after the first wait
+	for _, gap := range repaired.Gaps {
+		retry, ok := retries[gap]
+		if !ok {
+			retry = &gapRetry{}
+			retries[gap] = retry
+		} else if now.Before(retry.next) {
+			continue
+		}
+		retry.attempts++
+		backoff := every << retry.attempts
+		if backoff <= 0 || backoff > MAX_GAP_BACKOFF {
+			backoff = MAX_GAP_BACKOFF
+		}
+		retry.next = now.Add(backoff)
+	}
+	logrus.Infof("[%s] %s candles repaired: %s", pair, entities.Timeframe(timeframe), repaired)
+}
+
+// forgets the attempts of the gaps not in the series anymore
+func forgetRepaired(retries map[entities.Gap]*gapRetry, gaps []entities.Gap) {
+	left := map[entities.Gap]bool{}
+	for _, gap := range gaps {
+		left[gap] = true
+	}
+	for gap := range retries {
+		if !left[gap] {
+			delete(retries, gap)
+		}
+	}
+}
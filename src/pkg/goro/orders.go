@@ -1,19 +1,140 @@
 package goro
 
 import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
 	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
 	"github.com/d0ze/golang-hft/src/pkg/exchange"
 	"github.com/sirupsen/logrus"
 )
 
-func HandleOrders(orders chan *entities.Order) {
+// goroutine which places every received order, tracking
+// it until the exchange reports a final state
+func HandleOrders(ex exchange.IExchange, tracked entities.IOrders, orders chan *entities.Order) {
 	go func() {
 		for order := range orders {
 			logrus.Infof("handling order %v", order)
-			err := exchange.KrakenCli.PlaceOrder(order)
+			tracked.Add(order)
+			ack, err := ex.PlaceOrder(order)
 			if err != nil {
-				logrus.Warnf("error %v placing order", err)
+				placeFailed(ex, tracked, order, err)
+				continue
 			}
+			logrus.Infof("order %s acknowledged as %s: %s", order.Id, ack.RemoteId(), ack.Description)
+			// the executions feed may already have reported a final state,
+			// in which case the order is not tracked anymore
+			tracked.Update(entities.OrderUpdate{Id: order.Id, RemoteId: ack.RemoteId(), Status: ack.Status, Timestamp: time.Now()})
 		}
 	}()
 }
+
+// goroutine which applies the order updates streamed
+// by the exchange to the tracked orders
+func TrackOrders(feed exchange.IOrdersFeed, tracked entities.IOrders, wg *sync.WaitGroup) error {
+	updates, err := feed.SubscribeOrders()
+	if err != nil {
+		return err
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for update := range updates {
+			order := tracked.Update(update)
+			if order == nil {
+				logrus.Debugf("update for untracked order %s (%s), skipping...", update.RemoteId, update.Id)
+				continue
+			}
+			logrus.Infof("[%s] order %s %s: executed %s@%s", order.Market, order.Id, order.Status, order.ExecutedVolume, order.AveragePrice)
+		}
+	}()
+	return nil
+}
+
+// handles an order which could not be placed. rejected orders
+// are discarded, while after transport errors the order may
+// have reached the exchange anyway, so it's looked up first and
+// kept in flight, to be reconciled later, if the lookup fails too
+func placeFailed(ex exchange.IExchange, tracked entities.IOrders, order *entities.Order, err error) {
+	switch {
+	case errors.Is(err, exchange.ErrRejected):
+		logrus.Warnf("order %s rejected: %v", order.Id, err)
+	case errors.Is(err, exchange.ErrAuth):
+		logrus.Errorf("order %s not placed, check the api credentials: %v", order.Id, err)
+	case errors.Is(err, exchange.ErrRetryable), errors.Is(err, exchange.ErrUnavailable):
+		placed, lookupErr := ex.GetOrder(order.Id)
+		if lookupErr == nil {
+			logrus.Warnf("order %s placed despite error %v", order.Id, err)
+			tracked.Update(entities.OrderUpdate{Id: order.Id, RemoteId: placed.RemoteId, Status: placed.Status, Timestamp: time.Now()})
+			return
+		}
+		if !errors.Is(lookupErr, exchange.ErrOrderNotFound) {
+			logrus.Warnf("error %v placing order %s, lookup failed (%v), keeping it in flight", err, order.Id, lookupErr)
+			return
+		}
+		logrus.Warnf("error %v placing order %s, not found on the exchange", err, order.Id)
+	default:
+		logrus.Warnf("error %v placing order", err)
+	}
+	tracked.Update(entities.OrderUpdate{Id: order.Id, Status: internal.ERROR, Timestamp: time.Now()})
+}
+
+// goroutine which looks up the tracked orders on the exchange every
+// interval and after every reconnection of the orders feed, since the
+// updates sent while disconnected are lost. without an orders feed
+// (connected is nil) it's the only source of updates. orders not found
+// on the exchange are dropped once older than maxAge, while orders
+// which cannot be looked up are kept in flight until the next attempt
+func ReconcileOrders(ex exchange.ITrading, tracked entities.IOrders, every time.Duration, maxAge time.Duration, connected chan struct{}, done chan struct{}, wg *sync.WaitGroup) {
+	logrus.Infof("reconciling tracked orders every %s", every)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tick := time.NewTicker(every)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+			case <-connected:
+				logrus.Infof("orders feed connected, reconciling tracked orders")
+			case <-done:
+				return
+			}
+			for _, order := range tracked.Tracked() {
+				reconcile(ex, tracked, order, maxAge)
+			}
+		}
+	}()
+}
+
+func reconcile(ex exchange.ITrading, tracked entities.IOrders, order entities.Order, maxAge time.Duration) {
+	var remote *entities.Order
+	var err error
+	if order.RemoteId != "" {
+		remote, err = ex.GetRemoteOrder(order.RemoteId)
+	} else {
+		remote, err = ex.GetOrder(order.Id)
+	}
+	switch {
+	case err == nil:
+		updated := tracked.Update(entities.OrderUpdate{
+			Id:             order.Id,
+			RemoteId:       remote.RemoteId,
+			Status:         remote.Status,
+			ExecutedVolume: remote.ExecutedVolume,
+			AveragePrice:   remote.AveragePrice,
+			Fee:            remote.Fee,
+			Timestamp:      time.Now(),
+		})
+		if updated != nil && updated.Status != order.Status {
+			logrus.Infof("[%s] order %s reconciled as %s: executed %s@%s", updated.Market, updated.Id, updated.Status, updated.ExecutedVolume, updated.AveragePrice)
+		}
+	case !errors.Is(err, exchange.ErrOrderNotFound):
+		logrus.Warnf("error %v looking up order %s, keeping it in flight", err, order.Id)
+	case time.Since(order.CreatedAt) > maxAge:
+		logrus.Warnf("order %s not found on the exchange after %s, dropping it", order.Id, maxAge)
+		tracked.Update(entities.OrderUpdate{Id: order.Id, Status: internal.ERROR, Timestamp: time.Now()})
+	}
+}
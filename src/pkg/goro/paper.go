@@ -0,0 +1,26 @@
+package goro
+
+import (
+	"sync"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+)
+
+// goroutine which forwards every candle after updating the paper
+// exchange prices, so that orders fired by the strategy on a candle
+// are filled against it
+func SimulatePrices(paper exchange.IPaperExchange, market internal.Market, candles chan entities.Candle, wg *sync.WaitGroup) chan entities.Candle {
+	result := make(chan entities.Candle)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(result)
+		for candle := range candles {
+			paper.UpdatePrice(market, candle)
+			result <- candle
+		}
+	}()
+	return result
+}
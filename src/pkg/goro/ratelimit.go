@@ -0,0 +1,48 @@
+package goro
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/sirupsen/logrus"
+)
+
+// usage ratio above which the budget usage is logged as a warning
+const RATE_USAGE_WARNING = 0.8
+
+// goroutine which logs the budget usage of the exchange
+// rate limiters every interval, until done is closed
+func MonitorRateLimits(monitor exchange.IRateMonitor, interval time.Duration, done chan struct{}, wg *sync.WaitGroup) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-tick.C:
+				logRateUsage(monitor.Usage())
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+func logRateUsage(usage map[string]exchange.RateUsage) {
+	var names []string
+	for name := range usage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		limiter := usage[name]
+		if limiter.Counter >= limiter.Max*RATE_USAGE_WARNING || limiter.Waiting > 0 {
+			logrus.Warnf("[RATE LIMIT] %s budget %.2f/%.0f, %d calls waiting", name, limiter.Counter, limiter.Max, limiter.Waiting)
+		} else {
+			logrus.Debugf("[RATE LIMIT] %s budget %.2f/%.0f", name, limiter.Counter, limiter.Max)
+		}
+	}
+}
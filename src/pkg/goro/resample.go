@@ -0,0 +1,66 @@
+package goro
+
+import (
+	"sync"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/sirupsen/logrus"
+)
+
+// goroutine which builds the candles of the timeframes from the candles,
+// closed and in progress, of the base timeframe, so that a single feed
+// is needed for every timeframe of the market. the trend is updated with
+// the resampled candles, and the candles of the output timeframe, base
+// or resampled, are sent to the returned channel. the resampling starts
+// from the base candles of the trend: an interval whose base candles
+// are not all there is skipped, and reloaded by RepairGaps
+func ResampleOHLC(candles chan entities.Candle, pair internal.Market, trend entities.ITrend, base int, timeframes []int, output int, wg *sync.WaitGroup) chan entities.Candle {
+	logrus.Infof("[%s] resampling %v candles from %s candles", pair, timeframes, entities.Timeframe(base))
+	resamplers := map[int]*entities.CandleResampler{}
+	for _, timeframe := range timeframes {
+		resamplers[timeframe] = entities.NewCandleResampler(entities.Timeframe(base).Duration(), entities.Timeframe(timeframe).Duration())
+		for _, candle := range *trend.GetCandles(base) {
+			if closed, ok := resamplers[timeframe].Add(candle); ok {
+				updateResampled(trend, closed, timeframe)
+			}
+		}
+	}
+	result := make(chan entities.Candle)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(result)
+		for candle := range candles {
+			if output == base {
+				result <- candle
+			}
+			for _, timeframe := range timeframes {
+				if candle.Forming {
+					if forming, ok := resamplers[timeframe].Forming(candle); ok {
+						trend.SetForming(forming, timeframe)
+						if timeframe == output {
+							result <- forming
+						}
+					}
+					continue
+				}
+				closed, ok := resamplers[timeframe].Add(candle)
+				if ok && updateResampled(trend, closed, timeframe) && timeframe == output {
+					result <- closed
+				}
+			}
+		}
+	}()
+	return result
+}
+
+// adds the candle to the trend unless already loaded
+func updateResampled(trend entities.ITrend, candle entities.Candle, timeframe int) bool {
+	loaded := *trend.GetCandles(timeframe)
+	if len(loaded) > 0 && !loaded[len(loaded)-1].Timestamp.Before(candle.Timestamp) {
+		return false
+	}
+	trend.Update(candle, timeframe)
+	return true
+}
@@ -11,33 +11,64 @@ import (
 )
 
 // goroutine which applies the strategy on each new candle and fires every
-// order to be open into the returned channel
-func Check(strategy strategy.IStrategy, market internal.Market, trend entities.ITrend, candles chan entities.Candle, wg *sync.WaitGroup) chan *entities.Order {
+// order to be open into the returned channel. the strategy is not
+// applied while an order of the market is still in flight, and sees
+// a snapshot of the trend not changed by the candles received meanwhile.
+// the candles in progress are skipped unless the strategy is intrabar
+func Check(ex exchange.IExchange, tracked entities.IOrders, strat strategy.IStrategy, market internal.Market, trend entities.ITrend, candles chan entities.Candle, wg *sync.WaitGroup) chan *entities.Order {
 	result := make(chan *entities.Order)
 	go func() {
 		for candle := range candles {
-			balance, err := exchange.KrakenCli.GetBalance()
+			if !strategy.ChecksCandle(strat, candle) {
+				continue
+			}
+			if inFlight := tracked.InFlight(market); len(inFlight) > 0 {
+				logrus.Infof("[%s] %d orders in flight, skipping...", market, len(inFlight))
+				continue
+			}
+			balance, err := ex.GetBalance()
 			if err != nil {
 				logrus.Warnf("[%s] error %v retrieving balance, skipping...", err, market)
 				continue
 			}
-			positions, err := exchange.KrakenCli.GetOpenPositions(market)
+			positions, err := ex.GetOpenPositions(market)
 			if err != nil {
 				logrus.Warnf("[%s] error %v retrieving positions, skipping...", market, err)
 				continue
 			}
-			open := strategy.Open(trend, candle, balance, positions)
+			snapshot := trend.Snapshot()
+			open := strat.Open(snapshot, candle, balance, positions)
 			logrus.Infof("[%s] selected open order: %v", market, open)
 
-			close := strategy.Close(trend, candle, positions)
+			close := strat.Close(snapshot, candle, positions)
 			logrus.Infof("[%s] selected closing order: %v", market, close)
 			if close != nil {
-				result <- close
+				if err := checkStatus(close); err != nil {
+					logrus.Warnf("[%s] closing order %s refused: %v", market, close.Id, err)
+				} else {
+					result <- close
+				}
 			}
 			if open != nil {
+				if err := checkStatus(open); err != nil {
+					logrus.Warnf("[%s] order %s refused: %v", market, open.Id, err)
+					continue
+				}
+				if err := strategy.Leverage.Validate(ex, open); err != nil {
+					logrus.Warnf("[%s] order %s refused: %v", market, open.Id, err)
+					continue
+				}
 				result <- open
 			}
 		}
 	}()
 	return result
 }
+
+// refuses the orders not accepted by the trading status of the market
+func checkStatus(order *entities.Order) error {
+	if entities.Markets == nil {
+		return nil
+	}
+	return entities.Markets.CheckStatus(order)
+}
@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/d0ze/golang-hft/src/pkg/goro"
+	"github.com/shopspring/decimal"
+)
+
+// market in cancel only mode, registered before any test since
+// the goroutines of the tests keep reading the markets
+const RESTRICTED_MARKET internal.Market = "DOTEUR"
+
+func TestMain(m *testing.M) {
+	markets := entities.NewMarkets()
+	markets.SetMetadata(RESTRICTED_MARKET, 8, "DOT", internal.EUR, decimal.Zero, decimal.Zero)
+	markets.SetStatus(RESTRICTED_MARKET, internal.MARKET_CANCEL_ONLY)
+	entities.Markets = markets
+	os.Exit(m.Run())
+}
+
+// in memory exchange used to run the goroutines
+// without a live account
+type fakeExchange struct {
+	mu         sync.Mutex
+	balanceErr error
+	placeErr   error
+	existing   *entities.Order // returned by GetOrder and GetRemoteOrder
+	lookupErr  error           // returned by GetOrder if set
+	placed     []*entities.Order
+	positions  []*entities.Position
+	timeouts   []time.Duration
+}
+
+func (f *fakeExchange) GetOHLC(pair internal.Market, interval int) ([]entities.Candle, error) {
+	return []entities.Candle{}, nil
+}
+
+func (f *fakeExchange) GetMarketsData(markets []internal.Market) (entities.IMarkets, error) {
+	return entities.NewMarkets(), nil
+}
+
+func (f *fakeExchange) GetLeverage(market internal.Market) decimal.Decimal {
+	return decimal.NewFromInt(2)
+}
+
+func (f *fakeExchange) PlaceOrder(order *entities.Order) (*entities.OrderAck, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.placeErr != nil {
+		return nil, f.placeErr
+	}
+	f.placed = append(f.placed, order)
+	return &entities.OrderAck{Id: order.Id, RemoteIds: []string{"O" + order.Id}, Status: internal.OPEN}, nil
+}
+
+func (f *fakeExchange) GetOrder(id string) (*entities.Order, error) {
+	if f.lookupErr != nil {
+		return nil, f.lookupErr
+	}
+	if f.existing == nil {
+		return nil, exchange.ErrOrderNotFound
+	}
+	return f.existing, nil
+}
+
+func (f *fakeExchange) GetRemoteOrder(remoteId string) (*entities.Order, error) {
+	return f.GetOrder(remoteId)
+}
+
+func (f *fakeExchange) GetOpenOrders(market internal.Market) ([]*entities.Order, error) {
+	return []*entities.Order{}, nil
+}
+
+func (f *fakeExchange) GetClosedOrders(market internal.Market, since time.Time) ([]*entities.Order, error) {
+	return []*entities.Order{}, nil
+}
+
+func (f *fakeExchange) CancelOrder(remoteId string) error {
+	return nil
+}
+
+func (f *fakeExchange) CancelAll() (int, error) {
+	return 0, nil
+}
+
+func (f *fakeExchange) CancelAllAfter(timeout time.Duration) (time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.timeouts = append(f.timeouts, timeout)
+	return time.Now().Add(timeout), nil
+}
+
+func (f *fakeExchange) getTimeouts() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]time.Duration{}, f.timeouts...)
+}
+
+func (f *fakeExchange) GetBalance() (*entities.Balance, error) {
+	if f.balanceErr != nil {
+		return nil, f.balanceErr
+	}
+	return &entities.Balance{FreeMargin: decimal.NewFromInt(1000)}, nil
+}
+
+func (f *fakeExchange) GetOpenPositions(market internal.Market) ([]*entities.Position, error) {
+	return f.positions, nil
+}
+
+// strategy opening a buy order on every candle
+type buyStrategy struct{}
+
+func (s *buyStrategy) Open(trend entities.ITrend, candle entities.Candle, balance *entities.Balance, positions []*entities.Position) *entities.Order {
+	return &entities.Order{Market: trend.GetMarket(), Side: internal.BUY, MarketPrice: candle.Close}
+}
+
+func (s *buyStrategy) Close(trend entities.ITrend, candle entities.Candle, positions []*entities.Position) *entities.Order {
+	return nil
+}
+
+func TestCheckEmitsStrategyOrders(t *testing.T) {
+	ex := &fakeExchange{}
+	candles := make(chan entities.Candle)
+	var wg sync.WaitGroup
+	orders := goro.Check(ex, entities.NewOrders(), &buyStrategy{}, internal.XBTEUR, entities.InitTrend(internal.XBTEUR), candles, &wg)
+
+	candles <- entities.Candle{Close: decimal.NewFromInt(100), Timestamp: time.Now()}
+	select {
+	case order := <-orders:
+		if order.Side != internal.BUY || !order.MarketPrice.Equal(decimal.NewFromInt(100)) {
+			t.Errorf("unexpected order %v", order)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no order emitted")
+	}
+}
+
+// buy strategy checked on the candles in progress as well
+type intrabarStrategy struct {
+	buyStrategy
+}
+
+func (s *intrabarStrategy) Intrabar() bool {
+	return true
+}
+
+func TestCheckSkipsFormingCandles(t *testing.T) {
+	ex := &fakeExchange{}
+	candles := make(chan entities.Candle)
+	var wg sync.WaitGroup
+	orders := goro.Check(ex, entities.NewOrders(), &buyStrategy{}, internal.XBTEUR, entities.InitTrend(internal.XBTEUR), candles, &wg)
+
+	candles <- entities.Candle{Close: decimal.NewFromInt(100), Timestamp: time.Now(), Forming: true}
+	select {
+	case order := <-orders:
+		t.Fatalf("unexpected order %v", order)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	updates := make(chan entities.Candle)
+	intrabar := goro.Check(ex, entities.NewOrders(), &intrabarStrategy{}, internal.XBTEUR, entities.InitTrend(internal.XBTEUR), updates, &wg)
+	updates <- entities.Candle{Close: decimal.NewFromInt(100), Timestamp: time.Now(), Forming: true}
+	select {
+	case <-intrabar:
+	case <-time.After(time.Second):
+		t.Fatalf("expected an order on the candle in progress")
+	}
+}
+
+func TestCheckSkipsOnBalanceError(t *testing.T) {
+	ex := &fakeExchange{balanceErr: errors.New("unavailable")}
+	candles := make(chan entities.Candle)
+	var wg sync.WaitGroup
+	orders := goro.Check(ex, entities.NewOrders(), &buyStrategy{}, internal.XBTEUR, entities.InitTrend(internal.XBTEUR), candles, &wg)
+
+	candles <- entities.Candle{Close: decimal.NewFromInt(100), Timestamp: time.Now()}
+	select {
+	case order := <-orders:
+		t.Errorf("unexpected order %v", order)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestCheckSkipsWhileOrdersInFlight(t *testing.T) {
+	ex := &fakeExchange{}
+	tracked := entities.NewOrders()
+	tracked.Add(&entities.Order{Id: "in-flight", Market: internal.XBTEUR, Status: internal.CREATED})
+	candles := make(chan entities.Candle)
+	var wg sync.WaitGroup
+	orders := goro.Check(ex, tracked, &buyStrategy{}, internal.XBTEUR, entities.InitTrend(internal.XBTEUR), candles, &wg)
+
+	candles <- entities.Candle{Close: decimal.NewFromInt(100), Timestamp: time.Now()}
+	select {
+	case order := <-orders:
+		t.Fatalf("unexpected order %v", order)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	tracked.Update(entities.OrderUpdate{Id: "in-flight", Status: internal.FILLED})
+	candles <- entities.Candle{Close: decimal.NewFromInt(100), Timestamp: time.Now()}
+	select {
+	case <-orders:
+	case <-time.After(time.Second):
+		t.Fatalf("expected an order once the previous one is filled")
+	}
+}
+
+func TestCheckRefusesOrdersOfRestrictedMarkets(t *testing.T) {
+	ex := &fakeExchange{}
+	candles := make(chan entities.Candle)
+	var wg sync.WaitGroup
+	orders := goro.Check(ex, entities.NewOrders(), &buyStrategy{}, RESTRICTED_MARKET, entities.InitTrend(RESTRICTED_MARKET), candles, &wg)
+
+	candles <- entities.Candle{Close: decimal.NewFromInt(100), Timestamp: time.Now()}
+	select {
+	case order := <-orders:
+		t.Fatalf("unexpected order %v", order)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
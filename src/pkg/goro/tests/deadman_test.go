@@ -0,0 +1,33 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/pkg/goro"
+)
+
+func TestDeadManSwitchRearmsAndDisarms(t *testing.T) {
+	ex := &fakeExchange{}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	goro.DeadManSwitch(ex, 40*time.Millisecond, done, &wg)
+
+	time.Sleep(100 * time.Millisecond)
+	close(done)
+	wg.Wait()
+
+	timeouts := ex.getTimeouts()
+	if len(timeouts) < 3 {
+		t.Fatalf("expected the switch to be re-armed, got %v", timeouts)
+	}
+	for _, timeout := range timeouts[:len(timeouts)-1] {
+		if timeout != 40*time.Millisecond {
+			t.Errorf("unexpected timeout %s", timeout)
+		}
+	}
+	if timeouts[len(timeouts)-1] != 0 {
+		t.Errorf("expected the switch to be disarmed, got %s", timeouts[len(timeouts)-1])
+	}
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/goro"
+	"github.com/shopspring/decimal"
+)
+
+// market feed streaming the candles pushed by the test
+type fakeFeed struct {
+	ohlc chan entities.Candle
+}
+
+func (f *fakeFeed) SubscribeOHLC(pair internal.Market, interval int) (chan entities.Candle, error) {
+	return f.ohlc, nil
+}
+
+func (f *fakeFeed) SubscribeTrades(pair internal.Market) (chan entities.Trade, error) {
+	return make(chan entities.Trade), nil
+}
+
+func (f *fakeFeed) SubscribeTicker(pair internal.Market) (chan entities.Ticker, error) {
+	return make(chan entities.Ticker), nil
+}
+
+func (f *fakeFeed) SubscribeBook(pair internal.Market, depth int) (chan *entities.OrderBook, error) {
+	return make(chan *entities.OrderBook), nil
+}
+
+func (f *fakeFeed) Close() {
+	close(f.ohlc)
+}
+
+func TestStreamOHLCEmitsClosedCandles(t *testing.T) {
+	internal.InitConfig()
+	feed := &fakeFeed{ohlc: make(chan entities.Candle)}
+	trend := entities.InitTrend(internal.XBTEUR)
+	var wg sync.WaitGroup
+	candles, err := goro.StreamOHLC(feed, internal.XBTEUR, trend, 1, &wg)
+	if err != nil {
+		t.Fatalf("error streaming: %v", err)
+	}
+
+	start := time.Unix(1700000000, 0)
+	go func() {
+		feed.ohlc <- entities.Candle{Close: decimal.NewFromInt(1), Timestamp: start}
+		feed.ohlc <- entities.Candle{Close: decimal.NewFromInt(2), Timestamp: start}
+		feed.ohlc <- entities.Candle{Close: decimal.NewFromInt(3), Timestamp: start.Add(time.Minute)}
+		feed.Close()
+	}()
+
+	var closed, forming []entities.Candle
+	for candle := range candles {
+		if candle.Forming {
+			forming = append(forming, candle)
+		} else {
+			closed = append(closed, candle)
+		}
+	}
+	wg.Wait()
+	if len(closed) != 1 || !closed[0].Close.Equal(decimal.NewFromInt(2)) {
+		t.Fatalf("expected only the last update of the closed candle, got %v", closed)
+	}
+	if len(forming) != 3 || !forming[2].Close.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("expected every update of the candles in progress, got %v", forming)
+	}
+	if len(*trend.GetCandles(1)) != 1 || (*trend.GetCandles(1))[0].Forming {
+		t.Errorf("expected the closed candle in the trend")
+	}
+	if current, ok := trend.GetForming(1); !ok || !current.Close.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("expected the candle in progress in the trend, got %v", current)
+	}
+}
+
+// market data returning the configured candles
+type fakeOHLC struct {
+	fakeExchange
+	mu      sync.Mutex
+	candles []entities.Candle
+}
+
+func (f *fakeOHLC) GetOHLC(pair internal.Market, interval int) ([]entities.Candle, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]entities.Candle{}, f.candles...), nil
+}
+
+func TestPollOHLCSeparatesFormingCandle(t *testing.T) {
+	internal.InitConfig()
+	start := time.Unix(1700000000, 0)
+	ex := &fakeOHLC{candles: []entities.Candle{
+		{Close: decimal.NewFromInt(1), Timestamp: start},
+		{Close: decimal.NewFromInt(2), Timestamp: start.Add(time.Minute)},
+		{Close: decimal.NewFromInt(3), Timestamp: start.Add(2 * time.Minute)},
+	}}
+	trend := entities.InitTrend(internal.XBTEUR)
+	trend.Update(ex.candles[0], 1)
+	var wg sync.WaitGroup
+	candles := goro.PollOHLC(ex, internal.XBTEUR, trend, 1, &wg)
+
+	closed := <-candles
+	forming := <-candles
+	if closed.Forming || !closed.Close.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("unexpected closed candle %v", closed)
+	}
+	if !forming.Forming || !forming.Close.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("unexpected candle in progress %v", forming)
+	}
+	if frame := *trend.GetCandles(1); len(frame) != 2 || !frame[1].Close.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("the candle in progress was added to the trend: %v", frame)
+	}
+	if current, ok := trend.GetForming(1); !ok || !current.Close.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("unexpected candle in progress in the trend %v", current)
+	}
+}
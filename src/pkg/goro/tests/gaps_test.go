@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/goro"
+	"github.com/shopspring/decimal"
+)
+
+// backfill returning the configured candles
+type fakeBackfill struct {
+	mu      sync.Mutex
+	candles []entities.Candle
+	calls   int
+}
+
+func (f *fakeBackfill) GetCandles(market internal.Market, interval int, size int) ([]entities.Candle, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return f.candles, nil
+}
+
+func (f *fakeBackfill) getCalls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func minuteCandle(start time.Time, minute int) entities.Candle {
+	price := decimal.NewFromInt(int64(100 + minute))
+	return entities.NewCandle(price, price, price, price, start.Add(time.Duration(minute)*time.Minute))
+}
+
+func waitComplete(t *testing.T, trend entities.ITrend, timeframe int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !trend.GetQuality(timeframe).Complete() {
+		if time.Now().After(deadline) {
+			t.Fatalf("gaps not repaired: %s", trend.GetQuality(timeframe))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRepairGapsFromBackfill(t *testing.T) {
+	internal.InitConfig()
+	start := time.Now().Truncate(time.Minute).Add(-10 * time.Minute)
+	trend := entities.InitTrend(internal.XBTEUR, 1)
+	for _, minute := range []int{0, 1, 4, 5} {
+		trend.Update(minuteCandle(start, minute), 1)
+	}
+	backfill := &fakeBackfill{}
+	for minute := 0; minute < 8; minute++ {
+		backfill.candles = append(backfill.candles, minuteCandle(start, minute))
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	goro.RepairGaps(backfill, internal.XBTEUR, trend, 10*time.Millisecond, false, done, &wg)
+	waitComplete(t, trend, 1)
+	close(done)
+	wg.Wait()
+
+	// the candles after the series are left to the ohlc goroutines
+	candles := *trend.GetCandles(1)
+	if len(candles) != 6 || !candles[2].Close.Equal(decimal.NewFromInt(102)) {
+		t.Errorf("unexpected candles %v", candles)
+	}
+}
+
+func TestRepairGapsWithFlatCandles(t *testing.T) {
+	internal.InitConfig()
+	start := time.Now().Truncate(time.Minute).Add(-10 * time.Minute)
+	trend := entities.InitTrend(internal.XBTEUR, 1)
+	for _, minute := range []int{0, 1, 4} {
+		trend.Update(minuteCandle(start, minute), 1)
+	}
+	// the exchange has no candles for the empty intervals
+	backfill := &fakeBackfill{candles: []entities.Candle{minuteCandle(start, 0), minuteCandle(start, 1), minuteCandle(start, 4)}}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	goro.RepairGaps(backfill, internal.XBTEUR, trend, 10*time.Millisecond, true, done, &wg)
+	waitComplete(t, trend, 1)
+	close(done)
+	wg.Wait()
+
+	candles := *trend.GetCandles(1)
+	if len(candles) != 5 || !candles[3].Close.Equal(decimal.NewFromInt(101)) || !candles[3].Volume.IsZero() {
+		t.Errorf("unexpected candles %v", candles)
+	}
+}
+
+func TestRepairGapsBacksOff(t *testing.T) {
+	internal.InitConfig()
+	start := time.Now().Truncate(time.Minute).Add(-10 * time.Minute)
+	trend := entities.InitTrend(internal.XBTEUR, 1)
+	for _, minute := range []int{0, 1, 4} {
+		trend.Update(minuteCandle(start, minute), 1)
+	}
+	// the exchange has no candles for the empty intervals
+	backfill := &fakeBackfill{candles: []entities.Candle{minuteCandle(start, 0), minuteCandle(start, 1), minuteCandle(start, 4)}}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	goro.RepairGaps(backfill, internal.XBTEUR, trend, 10*time.Millisecond, false, done, &wg)
+	time.Sleep(150 * time.Millisecond)
+	close(done)
+	wg.Wait()
+
+	// attempted after 10, 30 and 70ms, then after 150ms
+	if calls := backfill.getCalls(); calls < 1 || calls > 4 {
+		t.Errorf("expected the unrepairable gap to be retried less often, reloaded %d times", calls)
+	}
+	if trend.GetQuality(1).Complete() {
+		t.Errorf("expected the gap to be left")
+	}
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/d0ze/golang-hft/src/pkg/goro"
+	"github.com/shopspring/decimal"
+)
+
+// waits until the tracked order satisfies the condition
+func waitOrder(t *testing.T, tracked entities.IOrders, id string, condition func(order *entities.Order) bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if condition(tracked.Get(id)) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("order %s not updated", id)
+}
+
+func TestHandleOrdersStoresRemoteId(t *testing.T) {
+	ex := &fakeExchange{}
+	tracked := entities.NewOrders()
+	orders := make(chan *entities.Order)
+	goro.HandleOrders(ex, tracked, orders)
+	defer close(orders)
+
+	orders <- &entities.Order{Id: "local", Market: internal.XBTEUR, Status: internal.CREATED}
+	waitOrder(t, tracked, "local", func(order *entities.Order) bool {
+		return order != nil && order.RemoteId == "Olocal" && order.Status == internal.OPEN
+	})
+}
+
+func TestHandleOrdersReconcilesAmbiguousErrors(t *testing.T) {
+	ex := &fakeExchange{
+		placeErr: &exchange.ExchangeError{Kind: exchange.ERROR_RETRYABLE, Message: "timeout"},
+		existing: &entities.Order{Id: "local", RemoteId: "Oremote", Status: internal.OPEN},
+	}
+	tracked := entities.NewOrders()
+	orders := make(chan *entities.Order)
+	goro.HandleOrders(ex, tracked, orders)
+	defer close(orders)
+
+	// the order reached the exchange despite the timeout
+	orders <- &entities.Order{Id: "local", Market: internal.XBTEUR, Status: internal.CREATED}
+	waitOrder(t, tracked, "local", func(order *entities.Order) bool {
+		return order != nil && order.RemoteId == "Oremote" && order.Status == internal.OPEN
+	})
+}
+
+func TestHandleOrdersKeepsOrdersWhenLookupFails(t *testing.T) {
+	ex := &fakeExchange{
+		placeErr:  &exchange.ExchangeError{Kind: exchange.ERROR_RETRYABLE, Message: "timeout"},
+		lookupErr: &exchange.ExchangeError{Kind: exchange.ERROR_RETRYABLE, Message: "timeout"},
+	}
+	tracked := entities.NewOrders()
+	orders := make(chan *entities.Order)
+	goro.HandleOrders(ex, tracked, orders)
+	defer close(orders)
+
+	// the order may be live, it's not dropped
+	orders <- &entities.Order{Id: "local", Market: internal.XBTEUR, Status: internal.CREATED}
+	time.Sleep(50 * time.Millisecond)
+	if len(tracked.InFlight(internal.XBTEUR)) != 1 {
+		t.Errorf("expected the order to stay in flight")
+	}
+}
+
+func TestHandleOrdersDropsOrdersNotFound(t *testing.T) {
+	ex := &fakeExchange{placeErr: &exchange.ExchangeError{Kind: exchange.ERROR_RETRYABLE, Message: "timeout"}}
+	tracked := entities.NewOrders()
+	orders := make(chan *entities.Order)
+	goro.HandleOrders(ex, tracked, orders)
+	defer close(orders)
+
+	orders <- &entities.Order{Id: "local", Market: internal.XBTEUR, Status: internal.CREATED}
+	waitOrder(t, tracked, "local", func(order *entities.Order) bool { return order == nil })
+}
+
+func TestHandleOrdersMarksRejectedOrders(t *testing.T) {
+	ex := &fakeExchange{placeErr: &exchange.ExchangeError{Kind: exchange.ERROR_REJECTED, Code: "EOrder:Insufficient funds"}}
+	tracked := entities.NewOrders()
+	orders := make(chan *entities.Order)
+	goro.HandleOrders(ex, tracked, orders)
+	defer close(orders)
+
+	order := &entities.Order{Id: "local", Market: internal.XBTEUR, Status: internal.CREATED}
+	orders <- order
+	waitOrder(t, tracked, "local", func(order *entities.Order) bool { return order == nil })
+	if len(tracked.InFlight(internal.XBTEUR)) != 0 {
+		t.Errorf("expected the rejected order not to be in flight")
+	}
+}
+
+func TestReconcileOrdersAppliesRemoteState(t *testing.T) {
+	ex := &fakeExchange{existing: &entities.Order{Id: "local", RemoteId: "Oremote", Status: internal.FILLED, ExecutedVolume: decimal.NewFromInt(1)}}
+	tracked := entities.NewOrders()
+	tracked.Add(&entities.Order{Id: "local", RemoteId: "Oremote", Market: internal.XBTEUR, Status: internal.OPEN})
+	connected := make(chan struct{}, 1)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	goro.ReconcileOrders(ex, tracked, time.Hour, time.Hour, connected, done, &wg)
+	defer wg.Wait()
+	defer close(done)
+
+	// the fill was sent while the feed was disconnected
+	connected <- struct{}{}
+	waitOrder(t, tracked, "local", func(order *entities.Order) bool { return order == nil })
+}
+
+func TestReconcileOrdersKeepsOrdersWhenLookupFails(t *testing.T) {
+	ex := &fakeExchange{lookupErr: &exchange.ExchangeError{Kind: exchange.ERROR_RETRYABLE, Message: "timeout"}}
+	tracked := entities.NewOrders()
+	tracked.Add(&entities.Order{Id: "local", Market: internal.XBTEUR, Status: internal.OPEN, CreatedAt: time.Now().Add(-time.Hour)})
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	goro.ReconcileOrders(ex, tracked, 5*time.Millisecond, time.Minute, nil, done, &wg)
+
+	time.Sleep(50 * time.Millisecond)
+	close(done)
+	wg.Wait()
+	if tracked.Get("local") == nil {
+		t.Errorf("expected the order to stay in flight")
+	}
+}
+
+func TestReconcileOrdersDropsOldOrdersNotFound(t *testing.T) {
+	ex := &fakeExchange{}
+	tracked := entities.NewOrders()
+	tracked.Add(&entities.Order{Id: "recent", Market: internal.XBTEUR, Status: internal.OPEN})
+	tracked.Add(&entities.Order{Id: "old", Market: internal.XBTEUR, Status: internal.OPEN, CreatedAt: time.Now().Add(-time.Hour)})
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	goro.ReconcileOrders(ex, tracked, 5*time.Millisecond, time.Minute, nil, done, &wg)
+	defer wg.Wait()
+	defer close(done)
+
+	waitOrder(t, tracked, "old", func(order *entities.Order) bool { return order == nil })
+	// the recent order may not be listed yet
+	if tracked.Get("recent") == nil {
+		t.Errorf("expected the recent order to stay in flight")
+	}
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/goro"
+	"github.com/shopspring/decimal"
+)
+
+func TestResampleOHLC(t *testing.T) {
+	internal.InitConfig()
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	trend := entities.InitTrend(internal.XBTEUR, 1, 5)
+	// the trend has the base candles of the 5m interval in progress
+	for minute := 0; minute < 2; minute++ {
+		trend.Update(minuteCandle(start, minute), 1)
+	}
+	base := make(chan entities.Candle)
+	var wg sync.WaitGroup
+	candles := goro.ResampleOHLC(base, internal.XBTEUR, trend, 1, []int{5}, 5, &wg)
+
+	go func() {
+		base <- minuteCandle(start, 2).WithForming(true)
+		for minute := 2; minute < 6; minute++ {
+			base <- minuteCandle(start, minute)
+		}
+		close(base)
+	}()
+	var received []entities.Candle
+	for candle := range candles {
+		received = append(received, candle)
+	}
+	wg.Wait()
+	if len(received) != 2 || !received[0].Forming || !received[0].Open.Equal(decimal.NewFromInt(100)) || !received[0].Close.Equal(decimal.NewFromInt(102)) {
+		t.Fatalf("unexpected candles %v", received)
+	}
+	closed := received[1]
+	if closed.Forming || !closed.Timestamp.Equal(start) || !closed.Open.Equal(decimal.NewFromInt(100)) || !closed.Close.Equal(decimal.NewFromInt(104)) {
+		t.Errorf("unexpected closed candle %v", closed)
+	}
+	if candles := *trend.GetCandles(5); len(candles) != 1 || !candles[0].Timestamp.Equal(start) {
+		t.Errorf("unexpected 5m candles in the trend %v", candles)
+	}
+	if _, ok := trend.GetForming(5); ok {
+		t.Errorf("expected no 5m candle in progress once closed")
+	}
+}
+
+func TestResampleOHLCSkipsIncompleteIntervals(t *testing.T) {
+	internal.InitConfig()
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	trend := entities.InitTrend(internal.XBTEUR, 1, 5)
+	base := make(chan entities.Candle)
+	var wg sync.WaitGroup
+	candles := goro.ResampleOHLC(base, internal.XBTEUR, trend, 1, []int{5}, 5, &wg)
+
+	go func() {
+		// the third minute is missing
+		for _, minute := range []int{0, 1, 3, 4, 5, 6, 7, 8, 9} {
+			base <- minuteCandle(start, minute)
+		}
+		close(base)
+	}()
+	var received []entities.Candle
+	for candle := range candles {
+		received = append(received, candle)
+	}
+	wg.Wait()
+	if len(received) != 1 || !received[0].Timestamp.Equal(start.Add(5*time.Minute)) {
+		t.Errorf("expected only the complete interval, got %v", received)
+	}
+	if candles := *trend.GetCandles(5); len(candles) != 1 {
+		t.Errorf("unexpected 5m candles in the trend %v", candles)
+	}
+}
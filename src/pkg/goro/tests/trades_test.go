@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/goro"
+	"github.com/shopspring/decimal"
+)
+
+// trades history returning the pages in order, then no trades
+type fakeTrades struct {
+	mu    sync.Mutex
+	pages [][]entities.Trade
+	since []time.Time
+}
+
+func (f *fakeTrades) GetTrades(market internal.Market, since time.Time) ([]entities.Trade, time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.since = append(f.since, since)
+	if len(f.pages) == 0 {
+		return nil, since, nil
+	}
+	page := f.pages[0]
+	f.pages = f.pages[1:]
+	return page, page[len(page)-1].Timestamp, nil
+}
+
+func TestAggregateTradesUpdatesTrend(t *testing.T) {
+	internal.InitConfig()
+	start := time.Unix(1700000040, 0)
+	history := &fakeTrades{pages: [][]entities.Trade{
+		{{Price: decimal.NewFromInt(100), Volume: decimal.NewFromInt(1), Timestamp: start}},
+		{{Price: decimal.NewFromInt(101), Volume: decimal.NewFromInt(1), Timestamp: start.Add(30 * time.Second)},
+			{Price: decimal.NewFromInt(102), Volume: decimal.NewFromInt(1), Timestamp: start.Add(time.Minute)}},
+	}}
+	trend := entities.InitTrend(internal.XBTEUR)
+	var wg sync.WaitGroup
+	trades := goro.PollTrades(history, internal.XBTEUR, start.Add(-time.Hour), time.Millisecond, &wg)
+	candles := goro.AggregateTrades(trades, internal.XBTEUR, trend, time.Minute, nil, &wg)
+
+	select {
+	case candle := <-candles:
+		if !candle.Timestamp.Equal(start) || !candle.Close.Equal(decimal.NewFromInt(101)) || candle.Count != 2 {
+			t.Errorf("unexpected candle %s", candle.String())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("no candle received")
+	}
+	if candles := *trend.GetCandles(1); len(candles) != 1 {
+		t.Errorf("expected the closed candle in the trend, got %v", candles)
+	}
+	history.mu.Lock()
+	defer history.mu.Unlock()
+	if !history.since[0].Equal(start.Add(-time.Hour)) || !history.since[1].Equal(start) {
+		t.Errorf("unexpected pagination %v", history.since)
+	}
+}
+
+func TestAggregateTradesClosesQuietIntervals(t *testing.T) {
+	trades := make(chan entities.Trade)
+	forming := make(chan entities.Candle)
+	var wg sync.WaitGroup
+	candles := goro.AggregateTrades(trades, internal.XBTEUR, nil, 100*time.Millisecond, forming, &wg)
+
+	start := time.Now().Truncate(100 * time.Millisecond)
+	trades <- entities.Trade{Price: decimal.NewFromInt(100), Volume: decimal.NewFromInt(1), Timestamp: start}
+	if candle := <-forming; !candle.Close.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("unexpected candle in progress %s", candle.String())
+	}
+	// closed without any other trade
+	select {
+	case candle := <-candles:
+		if !candle.Timestamp.Equal(start) {
+			t.Errorf("unexpected candle %s", candle.String())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("candle not closed")
+	}
+	close(trades)
+	for range candles {
+	}
+	wg.Wait()
+}
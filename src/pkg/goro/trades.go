@@ -0,0 +1,100 @@
+package goro
+
+import (
+	"sync"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/sirupsen/logrus"
+)
+
+// polls the public trades executed on the market since the given time,
+// paging through the history as fast as the rate limits allow and then
+// every given interval once the latest trades are reached
+func PollTrades(ex exchange.ITradesData, pair internal.Market, since time.Time, every time.Duration, wg *sync.WaitGroup) chan entities.Trade {
+	logrus.Infof("[%s] polling trades since %s", pair, since)
+	trades := make(chan entities.Trade)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			page, next, err := ex.GetTrades(pair, since)
+			if err != nil {
+				logrus.Warnf("[%s] error retrieving trades : %v", pair, err)
+			}
+			for _, trade := range page {
+				trades <- trade
+			}
+			since = next
+			if err != nil || len(page) == 0 || time.Since(since) < every {
+				time.Sleep(every)
+			}
+		}
+	}()
+	return trades
+}
+
+// builds candles of the given interval, seconds included, from the
+// trades and returns the closed ones. candles are closed by the first
+// trade of a following interval or, in quiet markets, once the interval
+// ended. the time is measured from the last trade, so that recorded
+// trades can be replayed as well. the trend is updated with the candles
+// and the candle in progress if it tracks their timeframe. when forming
+// is not nil every update of the candle in progress is sent to it
+func AggregateTrades(trades chan entities.Trade, pair internal.Market, trend entities.ITrend, interval time.Duration, forming chan entities.Candle, wg *sync.WaitGroup) chan entities.Candle {
+	logrus.Infof("[%s] building %s candles from trades", pair, interval)
+	aggregator := entities.NewCandleAggregator(interval)
+	candles := make(chan entities.Candle)
+	timeframe := int(entities.TimeframeOf(interval))
+	tracked := false
+	if trend != nil {
+		for _, tf := range trend.Timeframes() {
+			tracked = tracked || tf == timeframe
+		}
+	}
+	emit := func(closed []entities.Candle) {
+		for _, candle := range closed {
+			if tracked {
+				trend.Update(candle, timeframe)
+			}
+			candles <- candle
+		}
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(candles)
+		period := time.Second
+		if interval < period {
+			period = interval
+		}
+		tick := time.NewTicker(period)
+		defer tick.Stop()
+		var last time.Time
+		var received time.Time
+		for {
+			select {
+			case trade, ok := <-trades:
+				if !ok {
+					return
+				}
+				last, received = trade.Timestamp, time.Now()
+				emit(aggregator.Add(trade))
+				current, ok := aggregator.Current()
+				if ok && tracked {
+					trend.SetForming(current, timeframe)
+				}
+				if ok && forming != nil {
+					forming <- current.WithForming(true)
+				}
+			case <-tick.C:
+				if !last.IsZero() {
+					emit(aggregator.Flush(last.Add(time.Since(received))))
+				}
+			}
+		}
+	}()
+	return candles
+}
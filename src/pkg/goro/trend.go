@@ -10,36 +10,50 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// polls ohlc and updates the given trend instance
-// with the new candles for the timeframe
-func PollOHLC(pair internal.Market, trend entities.ITrend, timeframe int, wg *sync.WaitGroup) chan entities.Candle {
+// polls ohlc and updates the given trend instance with the new
+// candles for the timeframe. the closed candles are sent in order,
+// followed by the candle in progress whenever it changes
+func PollOHLC(ex exchange.IMarketData, pair internal.Market, trend entities.ITrend, timeframe int, wg *sync.WaitGroup) chan entities.Candle {
 	logrus.Infof("[%s] polling ohlc data (interval %d)", pair, timeframe)
 	candles := make(chan entities.Candle)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		pollNext(pair, trend, candles, timeframe)
+		pollNext(ex, pair, trend, candles, timeframe)
 		tick := time.NewTicker(time.Duration(timeframe*30) * time.Second)
 		for range tick.C {
-			pollNext(pair, trend, candles, timeframe)
+			pollNext(ex, pair, trend, candles, timeframe)
 		}
 	}()
 	return candles
 }
 
-func pollNext(pair internal.Market, trend entities.ITrend, output chan entities.Candle, timeframe int) {
-	candles, err := exchange.KrakenCli.GetOHLC(pair, timeframe)
+func pollNext(ex exchange.IMarketData, pair internal.Market, trend entities.ITrend, output chan entities.Candle, timeframe int) {
+	candles, err := ex.GetOHLC(pair, timeframe)
 	if err != nil {
 		logrus.Warnf("[%s] error retrieving ohlc : %v", pair, err)
 	}
-	if len(candles) > 0 {
-		next := candles[len(candles)-1]
-		// skip updating if the last candle is equal
-		frameCandles := *trend.GetCandles(timeframe)
-		if len(frameCandles) > 0 && frameCandles[len(frameCandles)-1].Timestamp == next.Timestamp {
-			return
+	if len(candles) == 0 {
+		return
+	}
+	// the last candle is still in progress
+	closed, forming := candles[:len(candles)-1], candles[len(candles)-1]
+	frameCandles := *trend.GetCandles(timeframe)
+	if len(frameCandles) == 0 && len(closed) > 0 {
+		// nothing loaded in the trend, only the latest candle is sent
+		closed = closed[len(closed)-1:]
+	}
+	for _, candle := range closed {
+		// skip the candles already loaded in the trend
+		if len(frameCandles) > 0 && !candle.Timestamp.After(frameCandles[len(frameCandles)-1].Timestamp) {
+			continue
 		}
-		trend.Update(next, timeframe)
-		output <- next
+		trend.Update(candle, timeframe)
+		output <- candle.WithForming(false)
+	}
+	if prev, ok := trend.GetForming(timeframe); ok && prev.Equal(forming) {
+		return
 	}
+	trend.SetForming(forming, timeframe)
+	output <- forming.WithForming(true)
 }
@@ -0,0 +1,142 @@
+package history
+
+import (
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+	"github.com/sirupsen/logrus"
+)
+
+// loads the candle history needed to warm the trends up. the exchange
+// ohlc data only covers the latest candles (720 on kraken), so the older
+// ones are rebuilt from the trades history, when the exchange exposes
+// it, and kept in the store for the following runs
+type IBackfill interface {
+	// returns up to size closed candles of the market interval,
+	// the last one being the candle closed most recently
+	GetCandles(market internal.Market, interval int, size int) ([]entities.Candle, error)
+}
+
+type backfill struct {
+	ohlc   exchange.IMarketData
+	trades exchange.ITradesData // nil if the exchange has no trades history
+	store  ICandleStore         // nil if the history is not persisted
+}
+
+func NewBackfill(ohlc exchange.IMarketData, store ICandleStore) IBackfill {
+	b := &backfill{ohlc: ohlc, store: store}
+	if trades, ok := ohlc.(exchange.ITradesData); ok {
+		b.trades = trades
+	}
+	return b
+}
+
+func (b *backfill) GetCandles(market internal.Market, interval int, size int) ([]entities.Candle, error) {
+	duration := time.Duration(interval) * time.Minute
+	// the candle in progress is excluded
+	end := time.Now().Truncate(duration)
+	from := end.Add(-time.Duration(size) * duration)
+
+	var stored []entities.Candle
+	if b.store != nil {
+		var err error
+		if stored, err = b.store.Load(market, interval); err != nil {
+			return nil, err
+		}
+	}
+	recent, err := b.ohlc.GetOHLC(market, interval)
+	if err != nil {
+		return nil, err
+	}
+	candles := Merge(stored, between(recent, from, end))
+
+	// rebuilds the candles missing before and between the stored and
+	// the recent ones, the intervals without trades stay missing
+	for start := from; b.trades != nil; {
+		missing := firstMissing(candles, start, end, duration)
+		if !missing.Before(end) {
+			break
+		}
+		until := end
+		for _, candle := range candles {
+			if candle.Timestamp.After(missing) {
+				until = candle.Timestamp
+				break
+			}
+		}
+		logrus.Infof("[BACKFILL] rebuilding %s %dm candles from %s to %s from trades", market, interval, missing, until)
+		rebuilt, err := b.fromTrades(market, duration, missing, until)
+		if err != nil {
+			return nil, err
+		}
+		candles = Merge(candles, rebuilt)
+		start = until
+	}
+	if b.store != nil {
+		if err := b.store.Save(market, interval, candles); err != nil {
+			logrus.Warnf("[BACKFILL] error %v storing %s %dm candles", err, market, interval)
+		}
+	}
+	candles = between(candles, from, end)
+	logrus.Infof("[BACKFILL] loaded %d/%d %s %dm candles", len(candles), size, market, interval)
+	return candles, nil
+}
+
+// builds the candles between from and until from the trades history
+func (b *backfill) fromTrades(market internal.Market, duration time.Duration, from time.Time, until time.Time) ([]entities.Candle, error) {
+	aggregator := entities.NewCandleAggregator(duration)
+	var candles []entities.Candle
+	// the trades are requested after since, excluded
+	since := from.Add(-time.Nanosecond)
+	for since.Before(until) {
+		trades, next, err := b.trades.GetTrades(market, since)
+		if err != nil {
+			return nil, err
+		}
+		for _, trade := range trades {
+			if !trade.Timestamp.Before(until) {
+				break
+			}
+			candles = append(candles, aggregator.Add(trade)...)
+		}
+		logrus.Debugf("[BACKFILL] received %d %s trades since %s", len(trades), market, since)
+		if len(trades) == 0 || !next.After(since) {
+			break
+		}
+		since = next
+	}
+	candles = append(candles, aggregator.Flush(until)...)
+	return between(candles, from, until), nil
+}
+
+// candles with a timestamp in [from, until)
+func between(candles []entities.Candle, from time.Time, until time.Time) []entities.Candle {
+	var res []entities.Candle
+	for _, candle := range candles {
+		if !candle.Timestamp.Before(from) && candle.Timestamp.Before(until) {
+			res = append(res, candle)
+		}
+	}
+	return res
+}
+
+// start of the first interval after from missing from the sorted
+// candles, until if every interval is covered
+func firstMissing(candles []entities.Candle, from time.Time, until time.Time, duration time.Duration) time.Time {
+	next := from
+	for _, candle := range candles {
+		if candle.Timestamp.Before(next) {
+			continue
+		}
+		if candle.Timestamp.After(next) {
+			return next
+		}
+		next = next.Add(duration)
+	}
+	if next.Before(until) {
+		return next
+	}
+	return until
+}
@@ -0,0 +1,117 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+)
+
+// persistent storage of the closed candles of the markets
+type ICandleStore interface {
+	// returns the stored candles of the market and interval, sorted by time
+	Load(market internal.Market, interval int) ([]entities.Candle, error)
+	// merges the candles with the stored ones, replacing
+	// the stored candles with the same timestamp
+	Save(market internal.Market, interval int, candles []entities.Candle) error
+}
+
+// stores the candles of each market and interval
+// in a json lines file of the given directory
+type fileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewFileStore(dir string) ICandleStore {
+	return &fileStore{dir: dir}
+}
+
+func (s *fileStore) Load(market internal.Market, interval int) ([]entities.Candle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(market, interval)
+}
+
+func (s *fileStore) Save(market internal.Market, interval int, candles []entities.Candle) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.load(market, interval)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	// written aside and renamed, so a crash never corrupts the history
+	path := s.path(market, interval)
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, candle := range Merge(stored, candles) {
+		if err := encoder.Encode(candle); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (s *fileStore) load(market internal.Market, interval int) ([]entities.Candle, error) {
+	file, err := os.Open(s.path(market, interval))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var candles []entities.Candle
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var candle entities.Candle
+		if err := json.Unmarshal(scanner.Bytes(), &candle); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", file.Name(), err)
+		}
+		candles = append(candles, candle)
+	}
+	return candles, scanner.Err()
+}
+
+func (s *fileStore) path(market internal.Market, interval int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s-%d.jsonl", market, interval))
+}
+
+// merges the candles sorted by time, the candles of
+// next replacing the ones of prev with the same timestamp
+func Merge(prev []entities.Candle, next []entities.Candle) []entities.Candle {
+	byTime := map[int64]entities.Candle{}
+	for _, candles := range [][]entities.Candle{prev, next} {
+		for _, candle := range candles {
+			byTime[candle.Timestamp.UnixNano()] = candle
+		}
+	}
+	res := make([]entities.Candle, 0, len(byTime))
+	for _, candle := range byTime {
+		res = append(res, candle)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Timestamp.Before(res[j].Timestamp) })
+	return res
+}
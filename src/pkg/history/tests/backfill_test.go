@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/history"
+	"github.com/shopspring/decimal"
+)
+
+// market data returning the configured latest candles
+type fakeOHLC struct {
+	candles []entities.Candle
+}
+
+func (f *fakeOHLC) GetOHLC(pair internal.Market, interval int) ([]entities.Candle, error) {
+	return f.candles, nil
+}
+
+func (f *fakeOHLC) GetMarketsData(markets []internal.Market) (entities.IMarkets, error) {
+	return entities.NewMarkets(), nil
+}
+
+func (f *fakeOHLC) GetLeverage(market internal.Market) decimal.Decimal {
+	return decimal.Zero
+}
+
+// market data with a trades history, paged by two trades
+type fakeHistory struct {
+	fakeOHLC
+	trades []entities.Trade
+	calls  int
+}
+
+func (f *fakeHistory) GetTrades(market internal.Market, since time.Time) ([]entities.Trade, time.Time, error) {
+	f.calls++
+	var page []entities.Trade
+	for _, trade := range f.trades {
+		if trade.Timestamp.After(since) && len(page) < 2 {
+			page = append(page, trade)
+		}
+	}
+	if len(page) == 0 {
+		return nil, since, nil
+	}
+	return page, page[len(page)-1].Timestamp, nil
+}
+
+func candle(ts time.Time, price int64) entities.Candle {
+	p := decimal.NewFromInt(price)
+	return entities.NewCandle(p, p, p, p, ts)
+}
+
+// the last 3 closed hours and the one in progress from the ohlc data,
+// the 4 hours before from the trades, the first of which has none
+func newFakeHistory(end time.Time) *fakeHistory {
+	ex := &fakeHistory{}
+	for i := 3; i >= 0; i-- {
+		ex.candles = append(ex.candles, candle(end.Add(-time.Duration(i)*time.Hour), 100+int64(i)))
+	}
+	for _, i := range []int{6, 5, 5, 4} {
+		ex.trades = append(ex.trades, entities.Trade{Price: decimal.NewFromInt(int64(10 * i)), Volume: decimal.NewFromInt(1), Timestamp: end.Add(-time.Duration(i)*time.Hour + time.Duration(len(ex.trades))*time.Minute)})
+	}
+	return ex
+}
+
+func TestBackfillFromTrades(t *testing.T) {
+	end := time.Now().Truncate(time.Hour)
+	ex := newFakeHistory(end)
+
+	candles, err := history.NewBackfill(ex, nil).GetCandles(internal.XBTEUR, 60, 7)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(candles) != 6 {
+		t.Fatalf("expected 6 candles, got %v", candles)
+	}
+	for i, candle := range candles {
+		if !candle.Timestamp.Equal(end.Add(-time.Duration(6-i) * time.Hour)) {
+			t.Errorf("unexpected timestamp of candle %d: %s", i, candle.String())
+		}
+	}
+	if candles[1].Count != 2 || !candles[1].Close.Equal(decimal.NewFromInt(50)) || !candles[3].Close.Equal(decimal.NewFromInt(103)) {
+		t.Errorf("unexpected candles %v", candles)
+	}
+	if !candles[5].Close.Equal(decimal.NewFromInt(101)) {
+		t.Errorf("expected the candle in progress to be excluded, got %s", candles[5].String())
+	}
+}
+
+func TestBackfillStoresHistory(t *testing.T) {
+	end := time.Now().Truncate(time.Hour)
+	ex := newFakeHistory(end)
+	store := history.NewFileStore(t.TempDir())
+
+	first, err := history.NewBackfill(ex, store).GetCandles(internal.XBTEUR, 60, 6)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	calls := ex.calls
+	second, err := history.NewBackfill(ex, store).GetCandles(internal.XBTEUR, 60, 6)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if ex.calls != calls {
+		t.Errorf("expected the stored candles to be used")
+	}
+	if len(first) != 6 || len(second) != 6 || !second[0].Vwap.Equal(first[0].Vwap) || !second[0].Timestamp.Equal(first[0].Timestamp) {
+		t.Errorf("unexpected stored candles %v", second)
+	}
+}
+
+func TestBackfillWithoutTrades(t *testing.T) {
+	end := time.Now().Truncate(time.Hour)
+	ex := &newFakeHistory(end).fakeOHLC
+
+	candles, err := history.NewBackfill(ex, nil).GetCandles(internal.XBTEUR, 60, 10)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(candles) != 3 {
+		t.Errorf("expected the closed ohlc candles only, got %v", candles)
+	}
+}
+
+func TestMergeCandles(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	merged := history.Merge(
+		[]entities.Candle{candle(start.Add(time.Minute), 1), candle(start, 2)},
+		[]entities.Candle{candle(start.Add(time.Minute), 3), candle(start.Add(2*time.Minute), 4)})
+	if len(merged) != 3 || !merged[0].Close.Equal(decimal.NewFromInt(2)) || !merged[1].Close.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("unexpected merge %v", merged)
+	}
+}
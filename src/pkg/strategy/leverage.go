@@ -0,0 +1,188 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/exchange"
+)
+
+type LeveragePolicy string
+
+const (
+	// the configured leverage, or the highest level below it
+	LEVERAGE_FIXED LeveragePolicy = "fixed"
+	// the highest level allowed by the market
+	LEVERAGE_MAX LeveragePolicy = "max"
+	// the highest level scaled by the ratio between the target
+	// volatility and the volatility of the market
+	LEVERAGE_VOLATILITY LeveragePolicy = "volatility"
+)
+
+// picks the leverage of the orders among the levels
+// allowed by the market, within the configured caps
+type ILeveragePolicy interface {
+	// returns the leverage of a new order, 0 when the market
+	// can't be traded on margin
+	Leverage(ex exchange.IMarketData, market internal.Market, side internal.OrderSide, trend entities.ITrend) int64
+	// checks the order leverage before it is placed
+	Validate(ex exchange.IMarketData, order *entities.Order) error
+}
+
+type LeverageConfig struct {
+	Policy           LeveragePolicy
+	Fixed            int64
+	Cap              int64 // 0 if not capped
+	MarketCaps       map[internal.Market]int64
+	TargetVolatility float64 // standard deviation of the candle returns
+	Timeframe        int     // candles used to measure the volatility
+}
+
+type leveragePolicy struct {
+	config LeverageConfig
+}
+
+// leverage policy used by the strategies, configured at startup
+var Leverage ILeveragePolicy = NewLeveragePolicy(LeverageConfig{Policy: LEVERAGE_MAX})
+
+func NewLeveragePolicy(config LeverageConfig) ILeveragePolicy {
+	return &leveragePolicy{config: config}
+}
+
+func (p *leveragePolicy) Leverage(ex exchange.IMarketData, market internal.Market, side internal.OrderSide, trend entities.ITrend) int64 {
+	levels := p.levels(ex, market, side)
+	if len(levels) == 0 {
+		return 0
+	}
+	var target float64
+	switch p.config.Policy {
+	case LEVERAGE_FIXED:
+		target = float64(p.config.Fixed)
+	case LEVERAGE_MAX:
+		target = float64(levels[len(levels)-1])
+	case LEVERAGE_VOLATILITY:
+		target = float64(levels[len(levels)-1])
+		if volatility := Volatility(trend, p.config.Timeframe); volatility > 0 {
+			target = math.Min(target, target*p.config.TargetVolatility/volatility)
+		}
+	default:
+		panic("unknown leverage policy")
+	}
+	// the order is not leveraged (spot) when the
+	// target is below every level
+	leverage := int64(0)
+	for _, level := range levels {
+		if float64(level) <= target {
+			leverage = level
+		}
+	}
+	return leverage
+}
+
+func (p *leveragePolicy) Validate(ex exchange.IMarketData, order *entities.Order) error {
+	if order.Leverage <= 1 {
+		return nil
+	}
+	if limit := p.cap(order.Market); limit > 0 && order.Leverage > limit {
+		return fmt.Errorf("leverage %d above the cap %d of %s", order.Leverage, limit, order.Market)
+	}
+	for _, level := range p.levels(ex, order.Market, order.Side) {
+		if level == order.Leverage {
+			return nil
+		}
+	}
+	return fmt.Errorf("leverage %d not allowed for %s %s", order.Leverage, order.Side, order.Market)
+}
+
+// allowed levels of the market side in ascending order, within the caps.
+// exchanges not listing the levels allow every leverage up to the max
+func (p *leveragePolicy) levels(ex exchange.IMarketData, market internal.Market, side internal.OrderSide) []int64 {
+	var listed []int
+	if entities.Markets != nil {
+		buy, sell := entities.Markets.GetLeverages(market)
+		listed = buy
+		if side == internal.SELL {
+			listed = sell
+		}
+	}
+	var levels []int64
+	if len(listed) > 0 {
+		for _, level := range listed {
+			levels = append(levels, int64(level))
+		}
+	} else {
+		for level := int64(2); level <= ex.GetLeverage(market).IntPart(); level++ {
+			levels = append(levels, level)
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+	limit := p.cap(market)
+	if limit == 0 {
+		return levels
+	}
+	var res []int64
+	for _, level := range levels {
+		if level <= limit {
+			res = append(res, level)
+		}
+	}
+	return res
+}
+
+// lowest between the global and the market cap, 0 if not capped
+func (p *leveragePolicy) cap(market internal.Market) int64 {
+	limit := p.config.Cap
+	if marketCap, ok := p.config.MarketCaps[market]; ok && (limit == 0 || marketCap < limit) {
+		limit = marketCap
+	}
+	return limit
+}
+
+// standard deviation of the close to close returns of the
+// candles of the timeframe, 0 if there are not enough candles
+func Volatility(trend entities.ITrend, timeframe int) float64 {
+	if trend == nil {
+		return 0
+	}
+	candles := trend.GetCandles(timeframe)
+	if candles == nil || len(*candles) < 3 {
+		return 0
+	}
+	var returns []float64
+	for i := 1; i < len(*candles); i++ {
+		prev := (*candles)[i-1].Close.InexactFloat64()
+		if prev == 0 {
+			continue
+		}
+		returns = append(returns, (*candles)[i].Close.InexactFloat64()/prev-1)
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(variance / float64(len(returns)-1))
+}
+
+func ILeverage(policy string) LeveragePolicy {
+	switch policy {
+	case string(LEVERAGE_FIXED):
+		return LEVERAGE_FIXED
+	case string(LEVERAGE_MAX):
+		return LEVERAGE_MAX
+	case string(LEVERAGE_VOLATILITY):
+		return LEVERAGE_VOLATILITY
+	default:
+		panic("unknown leverage policy")
+	}
+}
@@ -21,6 +21,50 @@ type IStrategy interface {
 	Close(trend entities.ITrend, candle entities.Candle, positions []*entities.Position) *entities.Order
 }
 
+// implemented by the strategies whose indicators need more
+// candles than OHLC_SIZE, e.g. a 200 periods sma
+type IWarmup interface {
+	// returns the number of closed candles needed by timeframe
+	Warmup() map[int]int
+}
+
+// candles to load in the trend timeframe before the strategy
+// is checked, at least OHLC_SIZE
+func WarmupSize(strat IStrategy, timeframe int) int {
+	size := internal.Config.OHLCSize
+	if warmup, ok := strat.(IWarmup); ok && warmup.Warmup()[timeframe] > size {
+		size = warmup.Warmup()[timeframe]
+	}
+	return size
+}
+
+// implemented by the strategies acting on every update of the candle
+// in progress. the other strategies are only checked on closed candles
+type IIntrabar interface {
+	// whether the strategy is checked on the candles in progress
+	Intrabar() bool
+}
+
+// whether the strategy is checked on the given candle
+func ChecksCandle(strat IStrategy, candle entities.Candle) bool {
+	if !candle.Forming {
+		return true
+	}
+	intrabar, ok := strat.(IIntrabar)
+	return ok && intrabar.Intrabar()
+}
+
+// whether the candles of every timeframe have no missing interval,
+// the strategies shouldn't trade on indicators computed on gaps
+func Gapless(trend entities.ITrend, timeframes ...int) bool {
+	for _, timeframe := range timeframes {
+		if !trend.GetQuality(timeframe).Complete() {
+			return false
+		}
+	}
+	return true
+}
+
 func CheckCost(order *entities.Order) bool {
 	return order.GetMarketCost().GreaterThanOrEqual(entities.Markets.GetMinCost(order.Market))
 }
@@ -29,14 +73,31 @@ func CheckVolume(order *entities.Order) bool {
 	return order.InitialVolume.GreaterThanOrEqual(entities.Markets.GetOrderMin(order.Market))
 }
 
+// whether closing the position at the given price is profitable after
+// the fees paid to open it and the fee of the closing order
+func CheckProfit(position *entities.Position, price decimal.Decimal) bool {
+	return position.IsProfitable(price)
+}
+
+// the leverage is picked by the leverage policy, orders
+// on markets not tradeable on margin are spot orders
 func buildOpenOrder(
+	ex exchange.IMarketData,
+	trend entities.ITrend,
 	market internal.Market,
 	side internal.OrderSide,
 	volume decimal.Decimal,
 	price decimal.Decimal) *entities.Order {
+	leverage := Leverage.Leverage(ex, market, side, trend)
+	orderType := internal.MARGIN
+	if market.IsFuture() {
+		orderType = internal.FUTURE
+	} else if leverage == 0 {
+		orderType = internal.SPOT
+	}
 	return &entities.Order{
 		Id:            uuid.New().String(),
-		Type:          internal.MARGIN,
+		Type:          orderType,
 		PriceType:     internal.MARKET,
 		InitialVolume: volume,
 		Side:          side,
@@ -45,9 +106,12 @@ func buildOpenOrder(
 		MarketPrice:   price,
 		CreatedAt:     time.Now(),
 		ReduceOnly:    false,
-		Leverage:      exchange.KrakenCli.GetLeverage(market).BigInt().Int64(),
+		Leverage:      leverage,
 	}
 }
+
+// the closing order uses the leverage of the position,
+// margin positions can't be closed with another leverage
 func buildClosingOrder(position *entities.Position) *entities.Order {
 	var side internal.OrderSide
 	if position.Side == internal.BUY {
@@ -55,9 +119,15 @@ func buildClosingOrder(position *entities.Position) *entities.Order {
 	} else {
 		side = internal.BUY
 	}
+	orderType := internal.SPOT
+	if position.Market.IsFuture() {
+		orderType = internal.FUTURE
+	} else if position.Leverage > 1 {
+		orderType = internal.MARGIN
+	}
 	return &entities.Order{
 		Id:            uuid.New().String(),
-		Type:          internal.SPOT,
+		Type:          orderType,
 		PriceType:     internal.MARKET,
 		InitialVolume: position.Size,
 		Side:          side,
@@ -65,6 +135,6 @@ func buildClosingOrder(position *entities.Position) *entities.Order {
 		Market:        position.Market,
 		CreatedAt:     time.Now(),
 		ReduceOnly:    true,
-		Leverage:      exchange.KrakenCli.GetLeverage(position.Market).BigInt().Int64(),
+		Leverage:      int64(position.Leverage),
 	}
 }
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/d0ze/golang-hft/src/internal"
+	"github.com/d0ze/golang-hft/src/pkg/domain/entities"
+	"github.com/d0ze/golang-hft/src/pkg/strategy"
+	"github.com/shopspring/decimal"
+)
+
+// market data of an exchange not listing the leverage levels
+type fakeMarketData struct {
+	leverage int64
+}
+
+func (f *fakeMarketData) GetOHLC(pair internal.Market, interval int) ([]entities.Candle, error) {
+	return []entities.Candle{}, nil
+}
+
+func (f *fakeMarketData) GetMarketsData(markets []internal.Market) (entities.IMarkets, error) {
+	return entities.NewMarkets(), nil
+}
+
+func (f *fakeMarketData) GetLeverage(market internal.Market) decimal.Decimal {
+	return decimal.NewFromInt(f.leverage)
+}
+
+func leverageMarkets() {
+	markets := entities.NewMarkets()
+	markets.SetMetadata(internal.XBTEUR, 8, internal.XBT, internal.EUR, decimal.Zero, decimal.Zero)
+	markets.SetLeverages(internal.XBTEUR, []int{2, 3, 4, 5}, []int{2, 3})
+	markets.SetMetadata("DOTEUR", 8, "DOT", internal.EUR, decimal.Zero, decimal.Zero)
+	entities.Markets = markets
+}
+
+func TestLeveragePolicies(t *testing.T) {
+	leverageMarkets()
+	ex := &fakeMarketData{}
+
+	max := strategy.NewLeveragePolicy(strategy.LeverageConfig{Policy: strategy.LEVERAGE_MAX})
+	if max.Leverage(ex, internal.XBTEUR, internal.BUY, nil) != 5 || max.Leverage(ex, internal.XBTEUR, internal.SELL, nil) != 3 {
+		t.Errorf("expected the highest level of each side")
+	}
+	if max.Leverage(ex, "DOTEUR", internal.BUY, nil) != 0 {
+		t.Errorf("expected no leverage on spot only markets")
+	}
+
+	// the highest level below the fixed leverage
+	fixed := strategy.NewLeveragePolicy(strategy.LeverageConfig{Policy: strategy.LEVERAGE_FIXED, Fixed: 4})
+	if fixed.Leverage(ex, internal.XBTEUR, internal.BUY, nil) != 4 || fixed.Leverage(ex, internal.XBTEUR, internal.SELL, nil) != 3 {
+		t.Errorf("unexpected fixed leverage")
+	}
+	spot := strategy.NewLeveragePolicy(strategy.LeverageConfig{Policy: strategy.LEVERAGE_FIXED, Fixed: 1})
+	if leverage := spot.Leverage(ex, internal.XBTEUR, internal.BUY, nil); leverage != 0 {
+		t.Errorf("expected no leverage below the lowest level, got %d", leverage)
+	}
+
+	capped := strategy.NewLeveragePolicy(strategy.LeverageConfig{Policy: strategy.LEVERAGE_MAX, Cap: 4, MarketCaps: map[internal.Market]int64{internal.XBTEUR: 2}})
+	if capped.Leverage(ex, internal.XBTEUR, internal.BUY, nil) != 2 {
+		t.Errorf("expected the market cap to apply")
+	}
+	if capped.Leverage(&fakeMarketData{leverage: 50}, internal.XBTUSDPERP, internal.BUY, nil) != 4 {
+		t.Errorf("expected the global cap to apply to the exchange max leverage")
+	}
+}
+
+func TestVolatilityScaledLeverage(t *testing.T) {
+	internal.InitConfig()
+	leverageMarkets()
+	ex := &fakeMarketData{}
+	trend := entities.InitTrend(internal.XBTEUR)
+	// returns alternating +2% and -2%
+	price := decimal.NewFromInt(100)
+	for i := 0; i < 20; i++ {
+		trend.Update(entities.Candle{Close: price, Timestamp: time.Now()}, 60)
+		if i%2 == 0 {
+			price = price.Mul(decimal.RequireFromString("1.02"))
+		} else {
+			price = price.Div(decimal.RequireFromString("1.02"))
+		}
+	}
+	policy := strategy.NewLeveragePolicy(strategy.LeverageConfig{Policy: strategy.LEVERAGE_VOLATILITY, TargetVolatility: 0.01, Timeframe: 60})
+	// about 2% volatility halves the highest level
+	if leverage := policy.Leverage(ex, internal.XBTEUR, internal.BUY, trend); leverage != 2 {
+		t.Errorf("unexpected leverage %d", leverage)
+	}
+	calm := strategy.NewLeveragePolicy(strategy.LeverageConfig{Policy: strategy.LEVERAGE_VOLATILITY, TargetVolatility: 0.1, Timeframe: 60})
+	if leverage := calm.Leverage(ex, internal.XBTEUR, internal.BUY, trend); leverage != 5 {
+		t.Errorf("unexpected leverage %d", leverage)
+	}
+	// the scaled leverage (0.25) is below the lowest level
+	cautious := strategy.NewLeveragePolicy(strategy.LeverageConfig{Policy: strategy.LEVERAGE_VOLATILITY, TargetVolatility: 0.001, Timeframe: 60})
+	if leverage := cautious.Leverage(ex, internal.XBTEUR, internal.BUY, trend); leverage != 0 {
+		t.Errorf("expected no leverage, got %d", leverage)
+	}
+}
+
+func TestLeverageValidation(t *testing.T) {
+	leverageMarkets()
+	ex := &fakeMarketData{}
+	policy := strategy.NewLeveragePolicy(strategy.LeverageConfig{Policy: strategy.LEVERAGE_MAX, Cap: 4})
+
+	if err := policy.Validate(ex, &entities.Order{Market: internal.XBTEUR, Side: internal.BUY, Leverage: 3}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := policy.Validate(ex, &entities.Order{Market: internal.XBTEUR, Side: internal.BUY, Leverage: 5}); err == nil {
+		t.Errorf("expected the leverage above the cap to be refused")
+	}
+	if err := policy.Validate(ex, &entities.Order{Market: internal.XBTEUR, Side: internal.SELL, Leverage: 4}); err == nil {
+		t.Errorf("expected a level not listed for the side to be refused")
+	}
+	if err := policy.Validate(ex, &entities.Order{Market: "DOTEUR", Side: internal.BUY, Leverage: 2}); err == nil {
+		t.Errorf("expected leverage on a spot only market to be refused")
+	}
+}
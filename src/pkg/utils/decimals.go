@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"strings"
+
 	"github.com/shopspring/decimal"
 )
 
@@ -11,3 +13,12 @@ func Percentage(number decimal.Decimal, percent int) decimal.Decimal {
 func MarketPrecision(number decimal.Decimal, decimals int) decimal.Decimal {
 	return decimal.RequireFromString(number.StringFixed(int32(decimals)))
 }
+
+// returns the number of decimals of a step size (e.g. 0.00100000 -> 3)
+func Precision(step decimal.Decimal) int {
+	str := step.String()
+	if i := strings.IndexByte(str, '.'); i >= 0 {
+		return len(str) - i - 1
+	}
+	return 0
+}